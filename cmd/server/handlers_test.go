@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"mime/multipart"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
 	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/server"
@@ -40,7 +42,12 @@ func getProjectRoot() (string, error) {
 	}
 }
 
-func newTestServer(t *testing.T) *server.Server {
+func newTestServer(t *testing.T, opts ...server.Option) *server.Server {
+	return newTestServerWith(t, nil, opts...)
+}
+
+// newTestServerWith is newTestServer with a service configured by svcOpts.
+func newTestServerWith(t *testing.T, svcOpts []service.Option, opts ...server.Option) *server.Server {
 	// Setup in-memory DB
 	db, err := sql.Open("sqlite", ":memory:")
 	if err != nil {
@@ -60,8 +67,97 @@ func newTestServer(t *testing.T) *server.Server {
 	}
 
 	dbQueries := database.New(db)
-	svc := service.New(dbQueries, db)
-	return server.NewServer(svc)
+	svc := service.New(dbQueries, db, svcOpts...)
+	return server.NewServer(svc, opts...)
+}
+
+func TestStaticCaching(t *testing.T) {
+	srv := newTestServer(t)
+
+	req := httptest.NewRequest("GET", "/static/css/style.css", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if cc := w.Header().Get("Cache-Control"); !strings.Contains(cc, "max-age=") {
+		t.Errorf("expected a Cache-Control max-age, got %q", cc)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+
+	req = httptest.NewRequest("GET", "/static/css/style.css", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for a matching ETag, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/favicon.ico", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "image/") {
+		t.Errorf("expected the favicon, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		t.Error("expected the favicon to be cacheable")
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	root, _ := getProjectRoot()
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t, server.WithReadOnly(true))
+	ctx := context.Background()
+	entry, _ := srv.Service.StartTimer(ctx, "Existing", nil)
+
+	form := url.Values{}
+	form.Add("description", "Sneaky")
+	req := httptest.NewRequest("POST", "/start", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for POST, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("DELETE", fmt.Sprintf("/entry/%d", entry.ID), nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for DELETE, got %d", w.Code)
+	}
+
+	active, err := srv.Service.GetActiveTimeEntry(ctx)
+	if err != nil || active.ID != entry.ID {
+		t.Fatalf("expected the existing timer to be untouched, got %+v (%v)", active, err)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for GET, got %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `id="read-only-notice"`) {
+		t.Errorf("expected read-only notice")
+	}
+	if strings.Contains(body, `id="sticky-active-bar"`) {
+		t.Errorf("expected timer controls to be hidden")
+	}
 }
 
 func TestHandleIndex(t *testing.T) {
@@ -182,7 +278,7 @@ func TestHandleEditAndUpdate(t *testing.T) {
 	form.Add("description", "New Description")
 	// Must provide start_time as form requires parsing it back, usually hidden input or preserved.
 	// The handler expects start_time logic.
-	// If I don't provide start_time in form, handler fails "Invalid start time format".
+	// If I don't provide start_time in form, handler fails "Start time is required".
 	// Test needs to simulate full form submission.
 	form.Add("start_time", entry.StartTime.Format("2006-01-02T15:04:05"))
 
@@ -255,6 +351,111 @@ func TestHandleUpdateActiveEntry(t *testing.T) {
 	}
 }
 
+func TestHandleUpdateActiveEntryStartTime(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	running, err := srv.Service.StartTimer(ctx, "Running", nil)
+	if err != nil {
+		t.Fatalf("failed to start timer: %v", err)
+	}
+
+	patch := func(startTime string) int {
+		form := url.Values{}
+		form.Add("entry_id", fmt.Sprintf("%d", running.ID))
+		form.Add("description", "Running")
+		form.Add("start_time", startTime)
+		req := httptest.NewRequest("PATCH", "/entry/active", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	backdated := srv.Service.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	if code := patch(backdated.Format("2006-01-02T15:04:05")); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	active, err := srv.Service.GetActiveTimeEntry(ctx)
+	if err != nil {
+		t.Fatalf("failed to get active entry: %v", err)
+	}
+	if active.ID != running.ID || !active.StartTime.Equal(backdated) || active.EndTime.Valid {
+		t.Errorf("expected the timer to keep running from %s, got %+v", backdated, active)
+	}
+
+	// Blank keeps the start; future and unparseable starts are rejected
+	if code := patch(""); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	future := srv.Service.Now().Add(time.Hour).Format("2006-01-02T15:04:05")
+	for _, v := range []string{future, "yesterday"} {
+		if code := patch(v); code != http.StatusBadRequest {
+			t.Errorf("start_time=%q: expected 400, got %d", v, code)
+		}
+	}
+	active, _ = srv.Service.GetActiveTimeEntry(ctx)
+	if !active.StartTime.Equal(backdated) {
+		t.Errorf("expected the start to stay at %s, got %s", backdated, active.StartTime)
+	}
+}
+
+func TestHandleUpdateActiveEntryAfterSwitch(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	// The sticky bar is rendered for the first timer...
+	first, err := srv.Service.StartTimer(ctx, "First", nil)
+	if err != nil {
+		t.Fatalf("failed to start timer: %v", err)
+	}
+	// ...which another tab stops by starting a second one
+	second, err := srv.Service.StartTimer(ctx, "Second", nil)
+	if err != nil {
+		t.Fatalf("failed to start timer: %v", err)
+	}
+
+	patch := func(entryID int64, description string) int {
+		form := url.Values{}
+		form.Add("entry_id", fmt.Sprintf("%d", entryID))
+		form.Add("description", description)
+		req := httptest.NewRequest("PATCH", "/entry/active", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// The edit typed into the stale bar arrives late
+	if code := patch(first.ID, "Edited first"); code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", code)
+	}
+	active, _ := srv.Service.GetActiveTimeEntry(ctx)
+	if active.ID != second.ID || active.Description != "Second" {
+		t.Errorf("expected the second timer to be untouched, got %+v", active)
+	}
+	stopped, _ := srv.Service.GetTimeEntry(ctx, first.ID)
+	if stopped.Description != "First" || !stopped.EndTime.Valid {
+		t.Errorf("expected the first timer to stay stopped and unchanged, got %+v", stopped)
+	}
+
+	if code := patch(second.ID, "Edited second"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	active, _ = srv.Service.GetActiveTimeEntry(ctx)
+	if active.Description != "Edited second" {
+		t.Errorf("expected the edit to apply, got %q", active.Description)
+	}
+
+	// Once nothing runs, the stale edit is still a conflict
+	if err := srv.Service.StopTimer(ctx, nil); err != nil {
+		t.Fatalf("StopTimer failed: %v", err)
+	}
+	if code := patch(second.ID, "Too late"); code != http.StatusConflict {
+		t.Errorf("expected 409 after stop, got %d", code)
+	}
+}
+
 func TestHandleLists(t *testing.T) {
 	root, _ := getProjectRoot()
 	oldWd, _ := os.Getwd()
@@ -284,6 +485,20 @@ func TestHandleLists(t *testing.T) {
 	if w.Result().StatusCode != http.StatusOK {
 		t.Errorf("GET /categories expected 200, got %d", w.Result().StatusCode)
 	}
+
+	// List Settings
+	if err := srv.Service.SetSetting(context.Background(), "some_key", "some_value"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	req = httptest.NewRequest("GET", "/settings", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("GET /settings expected 200, got %d", w.Result().StatusCode)
+	}
+	if !strings.Contains(w.Body.String(), "some_value") {
+		t.Errorf("expected settings page to contain stored value")
+	}
 }
 
 func TestHandleReports(t *testing.T) {
@@ -301,7 +516,7 @@ func TestHandleReports(t *testing.T) {
 	srv := newTestServer(t)
 
 	// Test various periods
-	periods := []string{"today", "week", "month", "year", "all"}
+	periods := []string{"today", "week", "month", "quarter", "year", "last7", "last30", "last90", "since_last_gap", "all"}
 	for _, p := range periods {
 		req := httptest.NewRequest("GET", "/reports?period="+p, nil)
 		w := httptest.NewRecorder()
@@ -312,6 +527,178 @@ func TestHandleReports(t *testing.T) {
 	}
 }
 
+func TestHandleReportsStrictPeriod(t *testing.T) {
+	root, _ := getProjectRoot()
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"/reports?period=weak", http.StatusOK}, // Lenient by default
+		{"/reports?period=weak&strict=1", http.StatusBadRequest},
+		{"/reports?period=week&strict=1", http.StatusOK},
+		{"/reports?period=since_last_gap&strict=1", http.StatusOK},
+		{"/reports?period=custom&start_date=2024-01-01&strict=1", http.StatusOK},
+		{"/export?period=weak&strict=1", http.StatusBadRequest},
+		{"/export/pivot?period=weak&strict=1", http.StatusBadRequest},
+		{"/export/timeseries.csv?period=weak&strict=1", http.StatusBadRequest},
+		{"/export/timeseries.csv?period=quarter&strict=1", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.url, nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("GET %s: expected %d, got %d", tt.url, tt.want, w.Code)
+		}
+	}
+}
+
+func TestHandleReportsExportURL(t *testing.T) {
+	root, _ := getProjectRoot()
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+	work, _ := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+	for _, e := range []struct {
+		desc string
+		cat  *int64
+	}{
+		{"Shown #api #review", &work.ID},
+		{"Missing a tag #api", &work.ID},
+		{"Other category #api #review", nil},
+	} {
+		if _, err := srv.Service.StartTimer(ctx, e.desc, e.cat); err != nil {
+			t.Fatalf("StartTimer failed: %v", err)
+		}
+	}
+	if err := srv.Service.StopTimer(ctx, nil); err != nil {
+		t.Fatalf("StopTimer failed: %v", err)
+	}
+	tags, _ := srv.Service.ListTags(ctx)
+
+	query := url.Values{"period": {"all"}, "category_id": {fmt.Sprint(work.ID)}}
+	for _, tag := range tags {
+		query.Add("tag_ids", fmt.Sprint(tag.ID))
+	}
+	req := httptest.NewRequest("GET", "/reports?"+query.Encode(), nil)
+	req.Header.Set("HX-Request", "true")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	body := w.Body.String()
+	i := strings.Index(body, `id="export-view" href="`)
+	if i < 0 {
+		t.Fatalf("expected an export link, got %s", body)
+	}
+	href := body[i+len(`id="export-view" href="`):]
+	href = strings.ReplaceAll(href[:strings.Index(href, `"`)], "&amp;", "&")
+	exportURL, err := url.Parse(href)
+	if err != nil {
+		t.Fatalf("invalid export URL %q: %v", href, err)
+	}
+	if exportURL.Path != "/export" || exportURL.Query().Get("category_id") != fmt.Sprint(work.ID) || len(exportURL.Query()["tag_ids"]) != len(tags) {
+		t.Errorf("expected the export URL to carry every filter, got %q", href)
+	}
+
+	req = httptest.NewRequest("GET", href, nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 from export, got %d", w.Code)
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], "Shown #api #review") {
+		t.Errorf("expected the export to hold only the entry shown, got %q", lines)
+	}
+}
+
+func TestHandleExportCSVOptionsOnly(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	entry, err := srv.Service.StartTimer(ctx, "Last month", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	start := time.Now().AddDate(0, -1, 0)
+	end := sql.NullTime{Time: start.Add(time.Hour), Valid: true}
+	if _, err := srv.Service.UpdateTimeEntry(ctx, entry.ID, "Last month", start, end, nil); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+
+	if _, err := srv.Service.StartTimer(ctx, "Still running", nil); err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	// Without a filter the export is not narrowed and keeps the running entry
+	for _, target := range []string{"/export?units=decimal", "/export?x=1"} {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", target, w.Code)
+		}
+		body := w.Body.String()
+		if lines := strings.Split(strings.TrimSpace(body), "\n"); len(lines) != 3 || !strings.Contains(body, "Last month") || !strings.Contains(body, "Still running") {
+			t.Errorf("%s: expected the export to hold every entry, got %q", target, lines)
+		}
+	}
+}
+
+func TestHandleReportsDecimalHours(t *testing.T) {
+	root, _ := getProjectRoot()
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+	entry, _ := srv.Service.StartTimer(ctx, "Payroll", nil)
+	start := srv.Service.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	end := sql.NullTime{Time: start.Add(90 * time.Minute), Valid: true}
+	if _, err := srv.Service.UpdateTimeEntry(ctx, entry.ID, entry.Description, start, end, nil); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/reports?period=all&units=decimal", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `<span class="total-duration">1.50</span>`) || strings.Contains(body, "1h 30m") {
+		t.Errorf("expected durations in decimal hours, got %s", body)
+	}
+}
+
 func TestHandleDataPageAndExport(t *testing.T) {
 	root, _ := getProjectRoot()
 	oldWd, _ := os.Getwd()
@@ -344,6 +731,20 @@ func TestHandleDataPageAndExport(t *testing.T) {
 	if w.Header().Get("Content-Type") != "text/csv" {
 		t.Errorf("expected Content-Type text/csv, got %s", w.Header().Get("Content-Type"))
 	}
+	if cl := w.Header().Get("Content-Length"); cl != fmt.Sprint(w.Body.Len()) {
+		t.Errorf("expected Content-Length %d, got %q", w.Body.Len(), cl)
+	}
+
+	// Pivot export
+	req = httptest.NewRequest("GET", "/export/pivot?period=week", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("GET /export/pivot expected 200, got %d", w.Result().StatusCode)
+	}
+	if !strings.HasPrefix(w.Body.String(), "date,") {
+		t.Errorf("expected pivot CSV header, got %q", w.Body.String())
+	}
 }
 
 func TestHandleImportPreview(t *testing.T) {
@@ -380,3 +781,1949 @@ func TestHandleImportPreview(t *testing.T) {
 		t.Errorf("POST /import/preview expected 200, got %d", rec.Result().StatusCode)
 	}
 }
+
+func TestHandleImportJSON(t *testing.T) {
+	srv := newTestServer(t)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		var b bytes.Buffer
+		w := multipart.NewWriter(&b)
+		fw, _ := w.CreateFormFile("json_file", "entries.json")
+		if _, err := fw.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write to multipart form: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("failed to close multipart writer: %v", err)
+		}
+		req := httptest.NewRequest("POST", "/import/json", &b)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(`[{"description": "Imported", "start_time": "2024-01-01T10:00:00Z", "end_time": "2024-01-01T11:00:00Z", "tags": ["json"]}]`)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", rec.Code, rec.Body.String())
+	}
+	tags, _ := srv.Service.ListTags(context.Background())
+	if len(tags) != 1 || tags[0].Name != "json" {
+		t.Errorf("expected the json tag, got %+v", tags)
+	}
+
+	if rec := post(`not json`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for malformed JSON, got %d", rec.Code)
+	}
+}
+
+func TestHandleImportCSVReplace(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	old, err := srv.Service.StartTimer(ctx, "Old only", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	post := func(fields map[string]string) *httptest.ResponseRecorder {
+		var b bytes.Buffer
+		w := multipart.NewWriter(&b)
+		for k, v := range fields {
+			if err := w.WriteField(k, v); err != nil {
+				t.Fatalf("failed to write field: %v", err)
+			}
+		}
+		fw, _ := w.CreateFormFile("csv_file", "entries.csv")
+		if _, err := fw.Write([]byte("description,start_time,end_time\nImported,2024-01-01T10:00:00Z,2024-01-01T11:00:00Z\n")); err != nil {
+			t.Fatalf("failed to write to multipart form: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("failed to close multipart writer: %v", err)
+		}
+		req := httptest.NewRequest("POST", "/import", &b)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, confirm := range []string{"", "replace"} {
+		if rec := post(map[string]string{"mode": "replace", "confirm": confirm}); rec.Code != http.StatusBadRequest {
+			t.Errorf("confirm=%q: expected 400, got %d", confirm, rec.Code)
+		}
+	}
+	if _, err := srv.Service.GetTimeEntry(ctx, old.ID); err != nil {
+		t.Fatalf("expected an unconfirmed replace to change nothing, got %v", err)
+	}
+
+	if rec := post(map[string]string{"mode": "replace", "confirm": "REPLACE"}); rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := srv.Service.GetTimeEntry(ctx, old.ID); err == nil {
+		t.Error("expected the old entry to be gone")
+	}
+	entries, _ := srv.Service.ListTimeEntries(ctx)
+	if len(entries) != 1 || entries[0].Description != "Imported" {
+		t.Errorf("expected only the imported entry, got %+v", entries)
+	}
+}
+
+func TestHandleResetData(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	work, err := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	entry, err := srv.Service.StartTimer(ctx, "Coding #golang", &work.ID)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/data/reset", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, confirm := range []string{"", "delete", "yes"} {
+		if w := post(url.Values{"confirm": {confirm}}); w.Code != http.StatusBadRequest {
+			t.Errorf("confirm=%q: expected 400, got %d", confirm, w.Code)
+		}
+	}
+	if _, err := srv.Service.GetTimeEntry(ctx, entry.ID); err != nil {
+		t.Fatalf("expected an unconfirmed reset to change nothing, got %v", err)
+	}
+
+	w := post(url.Values{"confirm": {"DELETE"}})
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/data?reset=1" {
+		t.Fatalf("expected redirect to /data?reset=1, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	if _, err := srv.Service.GetTimeEntry(ctx, entry.ID); err == nil {
+		t.Error("expected the entry to be deleted")
+	}
+	if tags, _ := srv.Service.ListTags(ctx); len(tags) != 0 {
+		t.Errorf("expected no tags, got %+v", tags)
+	}
+	if cats, _ := srv.Service.ListCategories(ctx); len(cats) != 1 {
+		t.Errorf("expected categories to be kept, got %d", len(cats))
+	}
+
+	post(url.Values{"confirm": {"DELETE"}, "include_categories": {"1"}})
+	if cats, _ := srv.Service.ListCategories(ctx); len(cats) != 0 {
+		t.Errorf("expected categories to be deleted, got %d", len(cats))
+	}
+}
+
+func TestHandleExportJSON(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.Service.StartTimer(context.Background(), "Exported #json", nil); err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/export.json", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+	var entries []service.EntryJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Description != "Exported #json" || len(entries[0].Tags) != 1 || entries[0].EndTime != nil {
+		t.Errorf("unexpected export: %+v", entries)
+	}
+}
+
+func TestHandleListEntriesJSON(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	for _, desc := range []string{"First", "Second", "Third"} {
+		if _, err := srv.Service.StartTimer(ctx, desc, nil); err != nil {
+			t.Fatalf("StartTimer failed: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/entries?sort=start_asc&limit=2&offset=1", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if total := rec.Header().Get("X-Total-Count"); total != "3" {
+		t.Errorf("expected X-Total-Count 3, got %q", total)
+	}
+	var entries []service.EntryJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Description != "Second" || entries[1].Description != "Third" {
+		t.Errorf("unexpected page: %+v", entries)
+	}
+
+	for _, query := range []string{"sort=description", "limit=ten", "offset=-1"} {
+		req := httptest.NewRequest("GET", "/api/entries?"+query, nil)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestHandleListEntriesJSONNotModified(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	if _, err := srv.Service.StartTimer(ctx, "First", nil); err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/entries", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("expected a weak ETag, got %q", etag)
+	}
+	if rec.Header().Get("Last-Modified") == "" {
+		t.Error("expected a Last-Modified header")
+	}
+
+	rec = get(etag)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected an empty body, got %q", rec.Body.String())
+	}
+
+	if _, err := srv.Service.StartTimer(ctx, "Second", nil); err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	rec = get(etag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after a change, got %d", rec.Code)
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Errorf("expected the ETag to change, still %q", etag)
+	}
+
+	// Entries are listed with their category, so renaming one is a change
+	cat, err := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	// Edits follow each other within the same millisecond of updated_at, still
+	// each one changes the ETag
+	for _, name := range []string{"Client work", "Internal work"} {
+		etag = get("").Header().Get("ETag")
+		if _, err := srv.Service.UpdateCategory(ctx, cat.ID, name, cat.Color, nil); err != nil {
+			t.Fatalf("UpdateCategory failed: %v", err)
+		}
+		if rec = get(etag); rec.Code != http.StatusOK {
+			t.Errorf("expected 200 after renaming a category to %q, got %d", name, rec.Code)
+		}
+	}
+
+	// A bad query is rejected rather than reported unchanged
+	etag = rec.Header().Get("ETag")
+	for _, query := range []string{"?limit=-1", "?offset=x", "?sort=sideways"} {
+		req := httptest.NewRequest("GET", "/api/entries"+query, nil)
+		req.Header.Set("If-None-Match", etag)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET /api/entries%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestHandleGetEntryJSON(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	cat, _ := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+	plain, err := srv.Service.StartTimer(ctx, "Plain", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	tagged, err := srv.Service.StartTimer(ctx, "Review #golang", &cat.ID)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	rec := get(fmt.Sprintf("/entry/%d.json", tagged.ID))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected JSON 200, got %d %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	var entry service.EntryJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if entry.ID != tagged.ID || entry.Category == nil || *entry.Category != "Work" || len(entry.Tags) != 1 || entry.Tags[0] != "golang" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if entry.EndTime != nil {
+		t.Errorf("expected a running entry to have a null end time, got %q", *entry.EndTime)
+	}
+
+	// Missing values are null rather than zero-value structs
+	rec = get(fmt.Sprintf("/entry/%d.json", plain.ID))
+	body := rec.Body.String()
+	if !strings.Contains(body, `"category":null`) || !strings.Contains(body, `"tags":[]`) || strings.Contains(body, "Valid") {
+		t.Errorf("expected null-safe JSON, got %s", body)
+	}
+
+	if rec := get("/entry/9999.json"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+	if rec := get("/entry/abc.json"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestHandleSetDefaultCategory(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	cat, _ := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+
+	form := url.Values{}
+	form.Add("category_id", fmt.Sprintf("%d", cat.ID))
+	req := httptest.NewRequest("POST", "/settings/default-category", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusSeeOther {
+		t.Errorf("expected redirect 303, got %d", w.Result().StatusCode)
+	}
+
+	defaultID, err := srv.Service.DefaultCategoryID(ctx)
+	if err != nil {
+		t.Fatalf("DefaultCategoryID failed: %v", err)
+	}
+	if defaultID == nil || *defaultID != cat.ID {
+		t.Errorf("expected default category %d, got %v", cat.ID, defaultID)
+	}
+}
+
+func TestHandleStartTimerNoCategoryOverridesDefault(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	cat, _ := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+	if err := srv.Service.SetDefaultCategoryID(ctx, &cat.ID); err != nil {
+		t.Fatalf("SetDefaultCategoryID failed: %v", err)
+	}
+
+	start := func(categoryID string) {
+		t.Helper()
+		form := url.Values{"description": {"Errands"}, "category_id": {categoryID}}
+		req := httptest.NewRequest("POST", "/start", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("expected 303, got %d", w.Code)
+		}
+	}
+
+	// Picking "No Category" wins over the default
+	start("-1")
+	active, err := srv.Service.GetActiveTimeEntry(ctx)
+	if err != nil {
+		t.Fatalf("GetActiveTimeEntry failed: %v", err)
+	}
+	if active.CategoryID.Valid {
+		t.Errorf("expected no category, got %d", active.CategoryID.Int64)
+	}
+
+	// Leaving the field out still applies it
+	start("")
+	active, _ = srv.Service.GetActiveTimeEntry(ctx)
+	if !active.CategoryID.Valid || active.CategoryID.Int64 != cat.ID {
+		t.Errorf("expected the default category %d, got %v", cat.ID, active.CategoryID)
+	}
+}
+
+func TestHandleStartTimerWithTagIDs(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	if _, err := srv.Service.StartTimer(ctx, "Seed #picked", nil); err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	if err := srv.Service.StopTimer(ctx, nil); err != nil {
+		t.Fatalf("StopTimer failed: %v", err)
+	}
+	tags, _ := srv.Service.ListTags(ctx)
+	if len(tags) != 1 {
+		t.Fatalf("expected 1 seeded tag, got %d", len(tags))
+	}
+
+	form := url.Values{}
+	form.Add("description", "From picker")
+	form.Add("tag_ids[]", fmt.Sprintf("%d", tags[0].ID))
+	req := httptest.NewRequest("POST", "/start", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusSeeOther {
+		t.Fatalf("expected redirect 303, got %d", w.Result().StatusCode)
+	}
+	if err := srv.Service.StopTimer(ctx, nil); err != nil {
+		t.Fatalf("StopTimer failed: %v", err)
+	}
+
+	// Both entries now carry the picked tag
+	start, end := srv.Service.ReportPeriod("all")
+	report, err := srv.Service.GetReport(ctx, service.ReportFilter{
+		StartDate: start,
+		EndDate:   end,
+		TagIDs:    []int64{tags[0].ID},
+	})
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if len(report.Entries) != 2 {
+		t.Errorf("expected 2 entries tagged via picker, got %d", len(report.Entries))
+	}
+}
+
+func TestHandleStartStopTimerHTMX(t *testing.T) {
+	root, _ := getProjectRoot()
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+
+	form := url.Values{}
+	form.Add("description", "HTMX Task")
+	req := httptest.NewRequest("POST", "/start", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for HTMX start, got %d", w.Result().StatusCode)
+	}
+	if w.Header().Get("HX-Trigger") != "timerStarted" {
+		t.Errorf("expected HX-Trigger timerStarted, got %q", w.Header().Get("HX-Trigger"))
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `id="sticky-active-bar"`) || !strings.Contains(body, `data-state="active"`) {
+		t.Errorf("expected active sticky bar fragment, got: %s", body)
+	}
+	if !strings.Contains(body, `hx-swap-oob="true"`) {
+		t.Errorf("expected out-of-band entry list")
+	}
+
+	req = httptest.NewRequest("POST", "/stop", nil)
+	req.Header.Set("HX-Request", "true")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for HTMX stop, got %d", w.Result().StatusCode)
+	}
+	if w.Header().Get("HX-Trigger") != "timerStopped" {
+		t.Errorf("expected HX-Trigger timerStopped, got %q", w.Header().Get("HX-Trigger"))
+	}
+	body = w.Body.String()
+	if !strings.Contains(body, `data-state="idle"`) || !strings.Contains(body, "HTMX Task") {
+		t.Errorf("expected idle bar and stopped entry in list, got: %s", body)
+	}
+}
+
+func TestHandleStartTimerWithOffset(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	start := func(offset string) int {
+		t.Helper()
+		form := url.Values{"description": {"Backdated"}, "start_offset_minutes": {offset}}
+		req := httptest.NewRequest("POST", "/start", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, bad := range []string{"-5", "soon", "100000"} {
+		if code := start(bad); code != http.StatusBadRequest {
+			t.Errorf("offset %q: expected 400, got %d", bad, code)
+		}
+	}
+
+	before := srv.Service.Now()
+	if code := start("30"); code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d", code)
+	}
+	after := srv.Service.Now()
+
+	active, err := srv.Service.GetActiveTimeEntry(ctx)
+	if err != nil {
+		t.Fatalf("expected a running timer: %v", err)
+	}
+	earliest, latest := before.Add(-30*time.Minute), after.Add(-30*time.Minute)
+	if active.StartTime.Before(earliest) || active.StartTime.After(latest) {
+		t.Errorf("expected start 30 minutes ago (%v-%v), got %v", earliest, latest, active.StartTime)
+	}
+}
+
+func TestHandleMultipleTimers(t *testing.T) {
+	root, err := getProjectRoot()
+	if err != nil {
+		t.Fatalf("failed to find project root: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServerWith(t, []service.Option{service.WithMultipleTimers(true)})
+	ctx := context.Background()
+
+	background, err := srv.Service.StartTimerAt(ctx, srv.Service.Now().Add(-time.Hour), "Long build", nil)
+	if err != nil {
+		t.Fatalf("StartTimerAt failed: %v", err)
+	}
+	foreground, err := srv.Service.StartTimer(ctx, "Code review", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	body := w.Body.String()
+	if !strings.Contains(body, `id="other-active-timers"`) || !strings.Contains(body, "Long build") {
+		t.Errorf("expected the background timer to be listed, got: %s", body)
+	}
+	if strings.Count(body, `class="global-start-form"`) != 1 {
+		t.Errorf("expected the start form to stay available while timers run")
+	}
+	// Each stop form names its timer, so a timer started elsewhere meanwhile
+	// is not the one stopped
+	if !strings.Contains(body, fmt.Sprintf(`name="id" value="%d"`, foreground.ID)) {
+		t.Errorf("expected the primary stop form to carry its entry id")
+	}
+
+	form := url.Values{"id": {fmt.Sprint(background.ID)}}
+	req = httptest.NewRequest("POST", "/stop", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", w.Code, w.Body.String())
+	}
+	if got, _ := srv.Service.GetTimeEntry(ctx, background.ID); !got.EndTime.Valid {
+		t.Error("expected the background timer to be stopped")
+	}
+	if active, err := srv.Service.GetActiveTimeEntry(ctx); err != nil || active.ID != foreground.ID {
+		t.Errorf("expected the foreground timer to keep running, got %+v, %v", active, err)
+	}
+
+	// Stopping it again is a conflict
+	req = httptest.NewRequest("POST", "/stop", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+}
+
+func TestHandleStopTimerAtTime(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	entry, err := srv.Service.StartTimer(ctx, "Late stop", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	start := srv.Service.Now().Add(-3 * time.Hour).Truncate(time.Minute)
+	if _, err := srv.Service.UpdateTimeEntry(ctx, entry.ID, entry.Description, start, sql.NullTime{}, nil); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+
+	stop := func(stopTime string) int {
+		t.Helper()
+		form := url.Values{"stop_time": {stopTime}}
+		req := httptest.NewRequest("POST", "/stop", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := stop("yesterday"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unparseable stop time, got %d", code)
+	}
+	if code := stop(start.Add(-time.Hour).Format("2006-01-02T15:04")); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for stop time before start, got %d", code)
+	}
+
+	end := start.Add(90 * time.Minute)
+	if code := stop(end.Format("2006-01-02T15:04")); code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d", code)
+	}
+	stopped, _ := srv.Service.GetTimeEntry(ctx, entry.ID)
+	if !stopped.EndTime.Valid || !stopped.EndTime.Time.Equal(end) {
+		t.Errorf("expected end time %v, got %v", end, stopped.EndTime)
+	}
+
+	if code := stop(""); code != http.StatusConflict {
+		t.Errorf("expected 409 with no running timer, got %d", code)
+	}
+}
+
+func TestEntryColorOverridesCategoryColor(t *testing.T) {
+	root, _ := getProjectRoot()
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	cat, _ := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+	entry, err := srv.Service.StartTimer(ctx, "Stand out", &cat.ID)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	// Set the override through the edit form
+	form := url.Values{}
+	form.Add("description", "Stand out")
+	form.Add("start_time", entry.StartTime.Format("2006-01-02T15:04:05"))
+	form.Add("category_id", fmt.Sprintf("%d", cat.ID))
+	form.Add("color_override", "1")
+	form.Add("color", "#00aa00")
+	req := httptest.NewRequest("PUT", fmt.Sprintf("/entry/%d", entry.ID), strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/entry/%d", entry.ID), nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	body := w.Body.String()
+	if !strings.Contains(body, "background-color: #00aa00") {
+		t.Errorf("expected badge to use entry color, got: %s", body)
+	}
+	if strings.Contains(body, "#ff0000") {
+		t.Errorf("expected category color to be overridden, got: %s", body)
+	}
+
+	// Unchecking the override inherits the category color again
+	form.Del("color_override")
+	req = httptest.NewRequest("PUT", fmt.Sprintf("/entry/%d", entry.ID), strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), "background-color: #ff0000") {
+		t.Errorf("expected category color after clearing override, got: %s", w.Body.String())
+	}
+}
+
+func TestHandleIndexTodayTotal(t *testing.T) {
+	root, err := getProjectRoot()
+	if err != nil {
+		t.Fatalf("failed to find project root: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	now := srv.Service.Now()
+	if now.Hour() == 0 && now.Minute() < 45 {
+		t.Skip("too close to midnight for entries to fall on today")
+	}
+
+	// 30 minutes stopped, then a timer running for the last 5 minutes
+	stopped, err := srv.Service.StartTimer(ctx, "Stopped", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	if _, err := srv.Service.UpdateTimeEntry(ctx, stopped.ID, stopped.Description, now.Add(-40*time.Minute), sql.NullTime{Time: now.Add(-10 * time.Minute), Valid: true}, nil); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+	running, err := srv.Service.StartTimer(ctx, "Running", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	if _, err := srv.Service.UpdateTimeEntry(ctx, running.ID, running.Description, now.Add(-5*time.Minute), sql.NullTime{}, nil); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `<strong id="today-total">35m `) {
+		t.Errorf("expected today's total of 35m including the running timer")
+	}
+	if !strings.Contains(w.Body.String(), `id="last-7-days"`) || strings.Count(w.Body.String(), "<rect ") != 7 {
+		t.Errorf("expected a 7 day chart")
+	}
+}
+
+func TestHandleIndexUncategorized(t *testing.T) {
+	root, err := getProjectRoot()
+	if err != nil {
+		t.Fatalf("failed to find project root: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	cat, _ := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+	for _, e := range []struct {
+		desc  string
+		catID *int64
+	}{
+		{"Filed report", &cat.ID},
+		{"Forgot category one", nil},
+		{"Forgot category two", nil},
+	} {
+		if _, err := srv.Service.StartTimer(ctx, e.desc, e.catID); err != nil {
+			t.Fatalf("StartTimer failed: %v", err)
+		}
+	}
+	// Running entries are not listed, so stop the last one
+	if err := srv.Service.StopTimer(ctx, nil); err != nil {
+		t.Fatalf("StopTimer failed: %v", err)
+	}
+
+	get := func(target string) string {
+		t.Helper()
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", target, w.Code)
+		}
+		return w.Body.String()
+	}
+
+	body := get("/")
+	if !strings.Contains(body, `<span id="uncategorized-count" class="category-badge">2</span>`) {
+		t.Errorf("expected a badge counting 2 uncategorized entries")
+	}
+
+	body = get("/?uncategorized=1")
+	if !strings.Contains(body, "Forgot category one") || !strings.Contains(body, "Forgot category two") {
+		t.Errorf("expected the uncategorized entries to be listed")
+	}
+	if strings.Contains(body, "Filed report") {
+		t.Errorf("expected categorized entries to be filtered out")
+	}
+}
+
+func TestHandleUpdateEntryClearsCategory(t *testing.T) {
+	root, err := getProjectRoot()
+	if err != nil {
+		t.Fatalf("failed to find project root: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	cat, _ := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+	entry, err := srv.Service.StartTimer(ctx, "Categorized", &cat.ID)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	put := func(form url.Values) {
+		t.Helper()
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/entry/%d", entry.ID), strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+	}
+	form := url.Values{}
+	form.Add("description", "Categorized")
+	form.Add("start_time", entry.StartTime.Format("2006-01-02T15:04:05"))
+
+	// Omitting category_id keeps the category
+	put(form)
+	updated, _ := srv.Service.GetTimeEntry(ctx, entry.ID)
+	if !updated.CategoryID.Valid || updated.CategoryID.Int64 != cat.ID {
+		t.Fatalf("expected category %d to be kept, got %v", cat.ID, updated.CategoryID)
+	}
+
+	// The "No Category" sentinel clears it
+	form.Set("category_id", "-1")
+	put(form)
+	updated, _ = srv.Service.GetTimeEntry(ctx, entry.ID)
+	if updated.CategoryID.Valid {
+		t.Errorf("expected category to be cleared, got %v", updated.CategoryID)
+	}
+}
+
+func TestHandleUpdateEntryUnixTime(t *testing.T) {
+	root, err := getProjectRoot()
+	if err != nil {
+		t.Fatalf("failed to find project root: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	entry, err := srv.Service.StartTimer(ctx, "Scripted", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	form := url.Values{
+		"description": {"Scripted"},
+		"start_time":  {"1710000000"},
+		"end_time":    {"1710003600500"},
+	}
+	req := httptest.NewRequest("PUT", fmt.Sprintf("/entry/%d", entry.ID), strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	got, err := srv.Service.GetTimeEntry(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("GetTimeEntry failed: %v", err)
+	}
+	if !got.StartTime.Equal(time.Unix(1710000000, 0)) {
+		t.Errorf("expected start from epoch seconds, got %s", got.StartTime)
+	}
+	if !got.EndTime.Valid || !got.EndTime.Time.Equal(time.UnixMilli(1710003600500)) {
+		t.Errorf("expected end from epoch millis, got %+v", got.EndTime)
+	}
+}
+
+func TestHandleUpdateEntryDuration(t *testing.T) {
+	root, err := getProjectRoot()
+	if err != nil {
+		t.Fatalf("failed to find project root: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	entry, err := srv.Service.StartTimer(ctx, "Meeting", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, srv.Service.Location())
+
+	put := func(form url.Values) {
+		t.Helper()
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/entry/%d", entry.ID), strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	put(url.Values{"description": {"Meeting"}, "start_time": {"2024-03-04 09:00"}, "duration": {"1h30m"}})
+	got, _ := srv.Service.GetTimeEntry(ctx, entry.ID)
+	if !got.EndTime.Valid || !got.EndTime.Time.Equal(start.Add(90*time.Minute)) {
+		t.Errorf("expected the end time to be start + 1h30m, got %+v", got.EndTime)
+	}
+
+	// An explicit end time wins over the duration
+	put(url.Values{"description": {"Meeting"}, "start_time": {"2024-03-04 09:00"}, "end_time": {"2024-03-04 09:45"}, "duration": {"1h30m"}})
+	got, _ = srv.Service.GetTimeEntry(ctx, entry.ID)
+	if !got.EndTime.Time.Equal(start.Add(45 * time.Minute)) {
+		t.Errorf("expected the end time field to be used, got %+v", got.EndTime)
+	}
+}
+
+func TestHandleUpdateEntryMultiLine(t *testing.T) {
+	root, err := getProjectRoot()
+	if err != nil {
+		t.Fatalf("failed to find project root: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	entry, err := srv.Service.StartTimer(ctx, "Notes", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	form := url.Values{
+		"description": {"Standup #team\n<b>blocked</b> on #review"},
+		"start_time":  {"2024-03-04 09:00"},
+		"end_time":    {"2024-03-04 09:15"},
+	}
+	req := httptest.NewRequest("PUT", fmt.Sprintf("/entry/%d", entry.ID), strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := w.Body.String(); !strings.Contains(body, "Standup #team<br>&lt;b&gt;blocked&lt;/b&gt; on #review") {
+		t.Errorf("expected the lines to be kept and escaped, got: %s", body)
+	}
+
+	got, _ := srv.Service.GetTimeEntry(ctx, entry.ID)
+	if got.Description != form.Get("description") {
+		t.Errorf("expected the description to be stored as-is, got %q", got.Description)
+	}
+
+	// The edit form keeps the line break in a textarea
+	req = httptest.NewRequest("GET", fmt.Sprintf("/entry/%d/edit", entry.ID), nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if body := w.Body.String(); !strings.Contains(body, "Standup #team\n&lt;b&gt;blocked&lt;/b&gt; on #review</textarea>") {
+		t.Errorf("expected the description in a textarea, got: %s", body)
+	}
+}
+
+func TestHandleUpdateEntryBillable(t *testing.T) {
+	root, err := getProjectRoot()
+	if err != nil {
+		t.Fatalf("failed to find project root: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	entry, err := srv.Service.StartTimer(ctx, "Client call", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	put := func(form url.Values) bool {
+		t.Helper()
+		form.Set("description", "Client call")
+		form.Set("start_time", "2024-03-04 09:00")
+		form.Set("end_time", "2024-03-04 10:00")
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/entry/%d", entry.ID), strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		got, _ := srv.Service.GetTimeEntry(ctx, entry.ID)
+		return got.Billable
+	}
+
+	// As sent by the form: the hidden field, then the checkbox when checked
+	if !put(url.Values{"billable": {"0", "1"}}) {
+		t.Error("expected the checked box to mark the entry billable")
+	}
+	if !put(url.Values{}) {
+		t.Error("expected leaving the field out to keep the flag")
+	}
+	if put(url.Values{"billable": {"0"}}) {
+		t.Error("expected the unchecked box to clear the flag")
+	}
+}
+
+func TestHandleUpdateEntryValidation(t *testing.T) {
+	root, err := getProjectRoot()
+	if err != nil {
+		t.Fatalf("failed to find project root: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	entry, err := srv.Service.StartTimer(ctx, "Original", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	start := entry.StartTime.Format("2006-01-02 15:04:05")
+
+	tests := []struct {
+		name    string
+		form    url.Values
+		message string
+		kept    []string // submitted values expected back in the form
+	}{
+		{
+			name:    "missing start",
+			form:    url.Values{"description": {"Edited"}, "end_time": {"2030-01-01 10:00"}},
+			message: "Start time is required",
+			kept:    []string{`>Edited</textarea>`, `value="2030-01-01 10:00"`},
+		},
+		{
+			name:    "malformed start",
+			form:    url.Values{"description": {"Edited"}, "start_time": {"tomorrow"}},
+			message: "Invalid start time &#34;tomorrow&#34;",
+			kept:    []string{`>Edited</textarea>`, `value="tomorrow"`},
+		},
+		{
+			name:    "malformed end",
+			form:    url.Values{"description": {"Edited"}, "start_time": {start}, "end_time": {"25:00"}},
+			message: "Invalid end time &#34;25:00&#34;",
+			kept:    []string{`>Edited</textarea>`, `value="25:00"`},
+		},
+		{
+			name:    "end before start",
+			form:    url.Values{"description": {"Edited"}, "start_time": {start}, "end_time": {"2000-01-01 10:00"}},
+			message: "End time must be after start time",
+			kept:    []string{`>Edited</textarea>`, `value="2000-01-01 10:00"`},
+		},
+		{
+			name:    "malformed duration",
+			form:    url.Values{"description": {"Edited"}, "start_time": {start}, "duration": {"90"}},
+			message: "Invalid duration &#34;90&#34;",
+			kept:    []string{`value="90"`},
+		},
+		{
+			name:    "negative duration",
+			form:    url.Values{"description": {"Edited"}, "start_time": {start}, "duration": {"-1h"}},
+			message: "Duration must be positive",
+			kept:    []string{`value="-1h"`},
+		},
+		{
+			name:    "missing description",
+			form:    url.Values{"start_time": {start}, "end_time": {"2030-01-01 10:00"}},
+			message: "Description is required",
+			kept:    []string{`value="2030-01-01 10:00"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", fmt.Sprintf("/entry/%d", entry.ID), strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d", w.Code)
+			}
+			body := w.Body.String()
+			if !strings.Contains(body, tt.message) {
+				t.Errorf("expected message %q, got: %s", tt.message, body)
+			}
+			for _, v := range tt.kept {
+				if !strings.Contains(body, v) {
+					t.Errorf("expected submitted %s to be kept, got: %s", v, body)
+				}
+			}
+		})
+	}
+
+	unchanged, _ := srv.Service.GetTimeEntry(ctx, entry.ID)
+	if unchanged.Description != "Original" || unchanged.EndTime.Valid {
+		t.Errorf("expected rejected edits not to be saved, got %+v", unchanged)
+	}
+}
+
+func TestHandleBulkCategory(t *testing.T) {
+	root, err := getProjectRoot()
+	if err != nil {
+		t.Fatalf("failed to find project root: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	cat, _ := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+	first, _ := srv.Service.StartTimer(ctx, "First", nil)
+	second, _ := srv.Service.StartTimer(ctx, "Second", nil)
+	untouched, _ := srv.Service.StartTimer(ctx, "Untouched", nil)
+
+	post := func(categoryID string, ids ...int64) *httptest.ResponseRecorder {
+		t.Helper()
+		form := url.Values{"category_id": {categoryID}}
+		for _, id := range ids {
+			form.Add("entry_ids[]", fmt.Sprint(id))
+		}
+		req := httptest.NewRequest("POST", "/entries/bulk-category", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(fmt.Sprint(cat.ID), first.ID, second.ID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, id := range []int64{first.ID, second.ID} {
+		if !strings.Contains(body, fmt.Sprintf(`<tr id="entry-%d" hx-swap-oob="true"`, id)) {
+			t.Errorf("expected out-of-band row for entry %d, got: %s", id, body)
+		}
+	}
+	if strings.Contains(body, fmt.Sprintf(`id="entry-%d"`, untouched.ID)) || !strings.Contains(body, "Work") {
+		t.Errorf("expected only the affected rows with the new category, got: %s", body)
+	}
+	for _, id := range []int64{first.ID, second.ID} {
+		e, _ := srv.Service.GetTimeEntry(ctx, id)
+		if !e.CategoryID.Valid || e.CategoryID.Int64 != cat.ID {
+			t.Errorf("expected entry %d to get category %d, got %v", id, cat.ID, e.CategoryID)
+		}
+	}
+	if e, _ := srv.Service.GetTimeEntry(ctx, untouched.ID); e.CategoryID.Valid {
+		t.Errorf("expected unselected entry to keep no category, got %v", e.CategoryID)
+	}
+
+	// The -1 sentinel clears the category
+	if w := post("-1", first.ID); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if e, _ := srv.Service.GetTimeEntry(ctx, first.ID); e.CategoryID.Valid {
+		t.Errorf("expected category to be cleared, got %v", e.CategoryID)
+	}
+
+	// An unknown entry fails the whole update
+	if w := post("-1", second.ID, 9999); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown entry, got %d", w.Code)
+	}
+	if e, _ := srv.Service.GetTimeEntry(ctx, second.ID); !e.CategoryID.Valid {
+		t.Errorf("expected failed update to be rolled back")
+	}
+}
+
+func TestHandleCategoryNotFound(t *testing.T) {
+	srv := newTestServer(t)
+
+	form := url.Values{}
+	form.Add("name", "Ghost")
+	form.Add("color", "#000000")
+	req := httptest.NewRequest("POST", "/categories/9999", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 updating missing category, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("DELETE", "/categories/9999", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting missing category, got %d", w.Code)
+	}
+}
+
+func TestHandleUpdateCategoryParent(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	client, _ := srv.Service.CreateCategory(ctx, "Client A", "#ff0000")
+	design, _ := srv.Service.CreateCategory(ctx, "Design", "#00ff00")
+
+	post := func(id int64, parent string) int {
+		t.Helper()
+		form := url.Values{"name": {"Renamed"}, "color": {"#123456"}, "parent_id": {parent}}
+		req := httptest.NewRequest("POST", fmt.Sprintf("/categories/%d", id), strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := post(design.ID, fmt.Sprint(client.ID)); code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d", code)
+	}
+	got, _ := srv.Service.GetCategory(ctx, design.ID)
+	if !got.ParentID.Valid || got.ParentID.Int64 != client.ID {
+		t.Errorf("expected Design under Client A, got %+v", got.ParentID)
+	}
+	if code := post(client.ID, fmt.Sprint(design.ID)); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a cycle, got %d", code)
+	}
+	if code := post(design.ID, ""); code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d", code)
+	}
+	if got, _ := srv.Service.GetCategory(ctx, design.ID); got.ParentID.Valid {
+		t.Errorf("expected an empty parent to make Design top-level, got %+v", got.ParentID)
+	}
+}
+
+func TestHandleCreateCategoryReservedName(t *testing.T) {
+	srv := newTestServer(t)
+
+	form := url.Values{"name": {"No Category"}, "color": {"#ff0000"}}
+	req := httptest.NewRequest("POST", "/categories", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+	if cats, _ := srv.Service.ListCategories(context.Background()); len(cats) != 0 {
+		t.Errorf("expected no category to be created, got %+v", cats)
+	}
+}
+
+func TestHandleTagEntries(t *testing.T) {
+	root, _ := getProjectRoot()
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+	tagged, err := srv.Service.StartTimer(ctx, "Coding #golang #review", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	untagged, err := srv.Service.StartTimer(ctx, "Lunch", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/tags/GoLang/entries", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, fmt.Sprintf(`id="entry-%d"`, tagged.ID)) {
+		t.Errorf("expected tagged entry in page, got: %s", body)
+	}
+	if strings.Contains(body, fmt.Sprintf(`id="entry-%d"`, untagged.ID)) {
+		t.Errorf("expected untagged entry to be left out, got: %s", body)
+	}
+	if !strings.Contains(body, `href="/tags/review/entries"`) {
+		t.Errorf("expected the co-occurring review tag to be linked, got: %s", body)
+	}
+
+	// Unknown tags render an empty page
+	req = httptest.NewRequest("GET", "/tags/missing/entries", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for an unknown tag, got %d", w.Code)
+	}
+}
+
+func TestHandleNormalizeTags(t *testing.T) {
+	srv := newTestServer(t)
+
+	req := httptest.NewRequest("POST", "/tags/normalize", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/tags?merged=0" {
+		t.Errorf("expected redirect to /tags?merged=0, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestHandleEntryNotFound(t *testing.T) {
+	srv := newTestServer(t)
+
+	for _, path := range []string{"/entry/9999", "/entry/9999/edit"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404, got %d", path, w.Code)
+		}
+	}
+
+	form := url.Values{"description": {"Ghost"}, "start_time": {"2024-01-01T09:00"}}
+	req := httptest.NewRequest("PUT", "/entry/9999", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("PUT: expected 404, got %d", w.Code)
+	}
+}
+
+func TestHandleReorderCategories(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	work, _ := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+	personal, _ := srv.Service.CreateCategory(ctx, "Personal", "#00ff00")
+
+	form := url.Values{"id": {fmt.Sprint(personal.ID), fmt.Sprint(work.ID)}}
+	req := httptest.NewRequest("POST", "/categories/reorder", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", w.Code, w.Body.String())
+	}
+
+	cats, _ := srv.Service.ListCategories(ctx)
+	if len(cats) != 2 || cats[0].ID != personal.ID || cats[1].ID != work.ID {
+		t.Errorf("expected reorder to persist, got %+v", cats)
+	}
+
+	form = url.Values{"id": {"9999"}}
+	req = httptest.NewRequest("POST", "/categories/reorder", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown category, got %d", w.Code)
+	}
+}
+
+func TestHandleImportCSVStreamsProgress(t *testing.T) {
+	srv := newTestServer(t)
+
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	fw, _ := mw.CreateFormFile("csv_file", "test.csv")
+	if _, err := fw.Write([]byte("description,start_time,end_time\nA,2024-01-01T10:00:00Z,2024-01-01T11:00:00Z\nB,2024-01-02T10:00:00Z,2024-01-02T11:00:00Z\n")); err != nil {
+		t.Fatalf("failed to write to multipart form: %v", err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("failed to close multipart writer: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/import", &b)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Accept", "text/event-stream")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected event stream, got %q", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"event: progress\ndata: 1/2\n\n", "event: progress\ndata: 2/2\n\n", "event: done\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in stream, got: %s", want, body)
+		}
+	}
+}
+
+func TestHandleStatus(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	getStatus := func() map[string]interface{} {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/status", nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected JSON, got %q", ct)
+		}
+		var status map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		return status
+	}
+
+	// Idle
+	status := getStatus()
+	if status["running"] != false || status["entry_count"] != float64(0) {
+		t.Errorf("unexpected idle status: %v", status)
+	}
+	if _, ok := status["description"]; ok {
+		t.Errorf("expected no description when idle, got %v", status["description"])
+	}
+
+	// Running
+	if _, err := srv.Service.StartTimer(ctx, "Writing docs", nil); err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	status = getStatus()
+	if status["running"] != true || status["description"] != "Writing docs" || status["entry_count"] != float64(1) {
+		t.Errorf("unexpected running status: %v", status)
+	}
+}
+
+func TestHandleFocusSession(t *testing.T) {
+	srv := newTestServer(t)
+
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+	getStatus := func() service.FocusStatus {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/focus/status", nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		var status service.FocusStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		return status
+	}
+
+	if status := getStatus(); status.Active {
+		t.Fatalf("expected no focus session, got %+v", status)
+	}
+	if w := post("/focus/end", nil); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 ending without a session, got %d", w.Code)
+	}
+	if w := post("/focus/start", url.Values{"planned_minutes": {"soon"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid plan, got %d", w.Code)
+	}
+
+	w := post("/focus/start", url.Values{"description": {"Deep work"}, "planned_minutes": {"50"}})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", w.Code, w.Body.String())
+	}
+	status := getStatus()
+	if !status.Active || status.Description != "Deep work" || status.PlannedSeconds != 50*60 || status.RemainingSeconds == nil {
+		t.Errorf("unexpected focus status: %+v", status)
+	}
+
+	if w := post("/start", url.Values{"description": {"Email"}}); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 starting a timer during focus, got %d", w.Code)
+	}
+
+	if w := post("/focus/end", nil); w.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", w.Code, w.Body.String())
+	}
+	if status := getStatus(); status.Active {
+		t.Errorf("expected the session to be over, got %+v", status)
+	}
+	if _, err := srv.Service.GetActiveTimeEntry(context.Background()); err != sql.ErrNoRows {
+		t.Errorf("expected the focus timer to be stopped, got %v", err)
+	}
+}
+
+func TestHandleGoalProgress(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	work, _ := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+	if _, err := srv.Service.CreateCategory(ctx, "Personal", "#00ff00"); err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+
+	form := url.Values{"target_hours": {"2.5"}}
+	req := httptest.NewRequest("POST", fmt.Sprintf("/categories/%d/goal", work.ID), strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/api/goals/progress?period=week", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var progress []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &progress); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(progress) != 1 {
+		t.Fatalf("expected only the category with a goal, got %v", progress)
+	}
+	if progress[0]["category_name"] != "Work" || progress[0]["target_seconds"] != float64(9000) || progress[0]["percentage"] != float64(0) {
+		t.Errorf("unexpected progress: %v", progress[0])
+	}
+
+	req = httptest.NewRequest("GET", "/api/goals/progress?period=decade", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid period, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/categories/999/goal", strings.NewReader("target_hours=1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing category, got %d", w.Code)
+	}
+}
+
+func TestHandleReportViews(t *testing.T) {
+	root, _ := getProjectRoot()
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+	work, _ := srv.Service.CreateCategory(ctx, "Work", "#ff0000")
+
+	req := httptest.NewRequest("GET", "/reports/views", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var views []struct {
+		Label      string `json:"label"`
+		Period     string `json:"period"`
+		CategoryID int64  `json:"category_id"`
+		URL        string `json:"url"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &views); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	// Five periods for all categories and five for Work
+	if len(views) != 10 {
+		t.Fatalf("expected 10 views, got %d: %+v", len(views), views)
+	}
+	var workMonth string
+	for _, v := range views {
+		if v.CategoryID == work.ID && v.Period == "month" {
+			workMonth = v.URL
+			if v.Label != "Work, This Month" {
+				t.Errorf("unexpected label %q", v.Label)
+			}
+		}
+	}
+	want := fmt.Sprintf("/reports?category_id=%d&period=month", work.ID)
+	if workMonth != want {
+		t.Fatalf("expected URL %q, got %q", want, workMonth)
+	}
+
+	// The permalink opens the report with the filter applied
+	req = httptest.NewRequest("GET", workMonth, nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, fmt.Sprintf(`<option value="%d" selected>Work</option>`, work.ID)) ||
+		!strings.Contains(body, `<option value="month" selected>`) {
+		t.Errorf("expected Work and This Month to be selected")
+	}
+}
+
+func TestHandleReportsCustomRangeIncludesEndDay(t *testing.T) {
+	root, _ := getProjectRoot()
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+	loc := srv.Service.Location()
+
+	seed := func(desc string, start time.Time) {
+		t.Helper()
+		e, err := srv.Service.StartTimer(ctx, desc, nil)
+		if err != nil {
+			t.Fatalf("StartTimer failed: %v", err)
+		}
+		if _, err := srv.Service.UpdateTimeEntry(ctx, e.ID, desc, start, sql.NullTime{Time: start.Add(time.Hour), Valid: true}, nil); err != nil {
+			t.Fatalf("UpdateTimeEntry failed: %v", err)
+		}
+	}
+	seed("Evening on end day", time.Date(2024, 3, 17, 20, 0, 0, 0, loc))
+	seed("Morning after", time.Date(2024, 3, 18, 10, 0, 0, 0, loc))
+
+	req := httptest.NewRequest("GET", "/reports?start_date=2024-03-16&end_date=2024-03-17", nil)
+	req.Header.Set("HX-Request", "true")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "Evening on end day") {
+		t.Errorf("expected entry at 20:00 on the end date to be included")
+	}
+	if strings.Contains(body, "Morning after") {
+		t.Errorf("expected entry after the end date to be excluded")
+	}
+
+	req = httptest.NewRequest("GET", "/reports?period=custom&end_date=nope", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid date, got %d", w.Code)
+	}
+}
+
+func TestHandleEntryHistory(t *testing.T) {
+	root, _ := getProjectRoot()
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	entry, err := srv.Service.StartTimer(context.Background(), "Tracked change", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/entry/%d/history", entry.ID), nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "Tracked change") {
+		t.Errorf("expected history to show the entry snapshot")
+	}
+
+	req = httptest.NewRequest("GET", "/entry/9999/history", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown entry, got %d", w.Code)
+	}
+}
+
+func TestHandleImportCSVColumnMapping(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	for k, v := range map[string]string{"map_description": "Task", "map_start_time": "Began", "map_end_time": "Ended", "map_category": ""} {
+		if err := w.WriteField(k, v); err != nil {
+			t.Fatalf("failed to write field: %v", err)
+		}
+	}
+	fw, _ := w.CreateFormFile("csv_file", "entries.csv")
+	if _, err := fw.Write([]byte("Task,Began,Ended\nMapped,2024-01-01T10:00:00Z,2024-01-01T11:00:00Z\n")); err != nil {
+		t.Fatalf("failed to write to multipart form: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close multipart writer: %v", err)
+	}
+	req := httptest.NewRequest("POST", "/import", &b)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", rec.Code, rec.Body.String())
+	}
+	entries, _ := srv.Service.ListTimeEntries(ctx)
+	if len(entries) != 1 || entries[0].Description != "Mapped" {
+		t.Errorf("expected the mapped entry, got %+v", entries)
+	}
+}
+
+func TestHandleReportsOrder(t *testing.T) {
+	root, _ := getProjectRoot()
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+	for i, desc := range []string{"Older entry", "Newer entry"} {
+		start := time.Date(2024, time.March, 4+i, 9, 0, 0, 0, time.Local)
+		entry, err := srv.Service.StartTimer(ctx, desc, nil)
+		if err != nil {
+			t.Fatalf("StartTimer failed: %v", err)
+		}
+		if _, err := srv.Service.UpdateTimeEntry(ctx, entry.ID, desc, start, sql.NullTime{Time: start.Add(time.Hour), Valid: true}, nil); err != nil {
+			t.Fatalf("UpdateTimeEntry failed: %v", err)
+		}
+	}
+
+	get := func(url string) string {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", url, w.Code)
+		}
+		return w.Body.String()
+	}
+	const base = "/reports?start_date=2024-03-01&end_date=2024-03-31"
+
+	for _, url := range []string{base, base + "&order=desc"} {
+		body := get(url)
+		if strings.Index(body, "Newer entry") > strings.Index(body, "Older entry") {
+			t.Errorf("GET %s: expected the newest entry first", url)
+		}
+	}
+	body := get(base + "&order=asc")
+	if !strings.Contains(body, "Older entry") || !strings.Contains(body, "Newer entry") {
+		t.Fatal("expected both entries in the report")
+	}
+	if strings.Index(body, "Older entry") > strings.Index(body, "Newer entry") {
+		t.Error("expected order=asc to list the oldest entry first")
+	}
+	if !strings.Contains(body, "order=asc") {
+		t.Error("expected the export link to keep the order")
+	}
+}
+
+func TestHandleOpenAPI(t *testing.T) {
+	srv := newTestServer(t)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+
+	var doc struct {
+		OpenAPI    string                            `json:"openapi"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if doc.OpenAPI == "" {
+		t.Error("expected an openapi version")
+	}
+	for path, method := range map[string]string{
+		"/api/status":         "get",
+		"/api/entries":        "get",
+		"/api/goals/progress": "get",
+		"/entry/{id}.json":    "get",
+		"/start":              "post",
+		"/stop":               "post",
+	} {
+		if _, ok := doc.Paths[path][method]; !ok {
+			t.Errorf("expected %s %s to be documented", method, path)
+		}
+	}
+	entry := doc.Components.Schemas["Entry"].Properties
+	for _, field := range []string{"id", "description", "start_time", "end_time", "tags", "billable"} {
+		if _, ok := entry[field]; !ok {
+			t.Errorf("expected the Entry schema to have %q", field)
+		}
+	}
+}
+
+func TestHandleAnnualReport(t *testing.T) {
+	root, _ := getProjectRoot()
+	oldWd, _ := os.Getwd()
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("failed to chdir to root: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore wd: %v", err)
+		}
+	}()
+
+	srv := newTestServer(t)
+	ctx := context.Background()
+	cat, _ := srv.Service.CreateCategory(ctx, "Clients", "#00aa00")
+	start := time.Date(2024, time.May, 14, 9, 0, 0, 0, srv.Service.Location())
+	entry, err := srv.Service.StartTimer(ctx, "Workshop", &cat.ID)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	if _, err := srv.Service.UpdateTimeEntry(ctx, entry.ID, "Workshop", start, sql.NullTime{Time: start.Add(90 * time.Minute), Valid: true}, &cat.ID); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/reports/annual?year=2024&format=json", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var report service.AnnualReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if report.Year != 2024 || len(report.Rows) != 1 || report.Rows[0].CategoryName != "Clients" || report.Rows[0].Months[time.May-1] != 5400 {
+		t.Errorf("expected 90 minutes of Clients in May, got %+v", report)
+	}
+
+	req = httptest.NewRequest("GET", "/reports/annual?year=2024", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := w.Body.String(); !strings.Contains(body, `id="annual-grid"`) || !strings.Contains(body, "Clients") || !strings.Contains(body, "1h 30m") {
+		t.Errorf("expected the grid with the Clients row, got %s", body)
+	}
+
+	for _, year := range []string{"last", "0"} {
+		req := httptest.NewRequest("GET", "/reports/annual?year="+year, nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("year=%s: expected 400, got %d", year, w.Code)
+		}
+	}
+}
@@ -1,9 +1,17 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/pressly/goose/v3"
 	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
@@ -13,9 +21,27 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+const defaultDBPath = "./precious-time-tracker.sqlite3"
+
 func main() {
+	cmd, err := parseArgs(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Resolve the configured time zone before touching anything else so a
+	// typo fails fast instead of silently falling back to UTC.
+	loc, err := loadLocation(os.Getenv("TZ"))
+	if err != nil {
+		log.Fatalf("Invalid TZ: %v", err)
+	}
+
 	// Setup DB
-	db, err := sql.Open("sqlite", "./precious-time-tracker.sqlite3")
+	dbPath := os.Getenv("DB_PATH")
+	if dbPath == "" {
+		dbPath = defaultDBPath
+	}
+	db, err := openDB(dbPath)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -23,29 +49,254 @@ func main() {
 	if _, err := db.Exec("PRAGMA foreign_keys = ON;"); err != nil {
 		log.Fatal(err)
 	}
-	defer func() {
+
+	if cmd.rollback > 0 {
+		if err := rollback(db, cmd.rollback); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Rolled back %d migration(s)", cmd.rollback)
 		if err := db.Close(); err != nil {
 			log.Printf("Error closing database: %v", err)
 		}
-	}()
-
-	// Run migrations
-	goose.SetBaseFS(schema.FS)
-
-	if err := goose.SetDialect("sqlite"); err != nil {
-		log.Fatal(err)
+		return
 	}
-
-	if err := goose.Up(db, "."); err != nil {
+	if err := migrate(db); err != nil {
 		log.Fatal(err)
 	}
+	if cmd.migrateOnly {
+		log.Println("Migrations applied")
+		if err := db.Close(); err != nil {
+			log.Printf("Error closing database: %v", err)
+		}
+		return
+	}
 
 	dbQueries := database.New(db)
-	svc := service.New(dbQueries, db)
-	srv := server.NewServer(svc)
+	maxBytes, err := loadInt(os.Getenv("IMPORT_MAX_BYTES"))
+	if err != nil {
+		log.Fatalf("Invalid IMPORT_MAX_BYTES: %v", err)
+	}
+	maxRows, err := loadInt(os.Getenv("IMPORT_MAX_ROWS"))
+	if err != nil {
+		log.Fatalf("Invalid IMPORT_MAX_ROWS: %v", err)
+	}
+	tagPattern, err := loadTagPattern(os.Getenv("TAG_PATTERN"))
+	if err != nil {
+		log.Fatalf("Invalid TAG_PATTERN: %v", err)
+	}
+	var minEntryDuration time.Duration
+	if v := os.Getenv("MIN_ENTRY_DURATION"); v != "" {
+		minEntryDuration, err = time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid MIN_ENTRY_DURATION: %v", err)
+		}
+	}
+	var breakGap time.Duration
+	if v := os.Getenv("BREAK_GAP"); v != "" {
+		breakGap, err = time.ParseDuration(v)
+		if err != nil || breakGap <= 0 {
+			log.Fatalf("Invalid BREAK_GAP: %q", v)
+		}
+	}
+	var multipleTimers bool
+	if v := os.Getenv("MULTIPLE_TIMERS"); v != "" {
+		multipleTimers, err = strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("Invalid MULTIPLE_TIMERS: %v", err)
+		}
+	}
+	svc := service.New(dbQueries, db,
+		service.WithLocation(loc),
+		service.WithImportLimits(int64(maxBytes), maxRows),
+		service.WithTagPattern(tagPattern),
+		service.WithMinEntryDuration(minEntryDuration),
+		service.WithBreakGap(breakGap),
+		service.WithMultipleTimers(multipleTimers),
+	)
+	// Deal with a timer left running by a previous process
+	staleAfter, stalePolicy, err := loadStaleTimerConfig(os.Getenv("STALE_TIMER_AFTER"), os.Getenv("STALE_TIMER_ACTION"))
+	if err != nil {
+		log.Fatalf("Invalid stale timer configuration: %v", err)
+	}
+	stale, err := svc.ReconcileActiveOnStartup(context.Background(), staleAfter, stalePolicy)
+	if err != nil {
+		log.Printf("Error checking for stale timers: %v", err)
+	}
+	for _, entry := range stale {
+		if stalePolicy == service.StaleTimerStop {
+			log.Printf("Stopped stale timer %d (%q) at %s", entry.ID, entry.Description, entry.EndTime.Time.Format(time.RFC3339))
+		} else {
+			log.Printf("Warning: timer %d (%q) has been running since %s", entry.ID, entry.Description, entry.StartTime.Format(time.RFC3339))
+		}
+	}
+
+	requestTimeout := server.DefaultRequestTimeout
+	if v := os.Getenv("REQUEST_TIMEOUT"); v != "" {
+		requestTimeout, err = time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid REQUEST_TIMEOUT: %v", err)
+		}
+	}
+	readOnly := false
+	if v := os.Getenv("READ_ONLY"); v != "" {
+		readOnly, err = strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("Invalid READ_ONLY: %v", err)
+		}
+	}
+	srv := server.NewServer(svc,
+		server.WithRequestTimeout(requestTimeout),
+		server.WithReadOnly(readOnly),
+		server.WithOwnedService(true),
+	)
+	// The server owns the service and, through it, the database
+	defer func() {
+		if err := srv.Close(); err != nil {
+			log.Printf("Error closing database: %v", err)
+		}
+	}()
 
 	log.Println("Server starting on :8080")
 	if err := http.ListenAndServe(":8080", srv); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// command is what the command line asks the server to do instead of, or
+// before, serving.
+type command struct {
+	// migrateOnly applies the migrations and exits.
+	migrateOnly bool
+	// rollback, when positive, undoes that many of the most recent
+	// migrations and exits without migrating up.
+	rollback int
+}
+
+// parseArgs reads the command line. "-migrate", or a "migrate" argument,
+// applies the migrations and exits instead of serving. Rolling back loses
+// data, so it needs its own flag, "-migrate-down N".
+func parseArgs(args []string) (command, error) {
+	var cmd command
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.BoolVar(&cmd.migrateOnly, "migrate", false, "apply database migrations and exit")
+	fs.IntVar(&cmd.rollback, "migrate-down", 0, "roll back this many migrations and exit, dropping the data they added")
+	if err := fs.Parse(args); err != nil {
+		return command{}, err
+	}
+	switch fs.Arg(0) {
+	case "":
+	case "migrate":
+		cmd.migrateOnly = true
+	default:
+		return command{}, fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+	if fs.NArg() > 1 {
+		return command{}, fmt.Errorf("unexpected arguments: %v", fs.Args()[1:])
+	}
+	if cmd.rollback < 0 {
+		return command{}, fmt.Errorf("-migrate-down: expected a positive number of migrations, got %d", cmd.rollback)
+	}
+	if cmd.rollback > 0 && cmd.migrateOnly {
+		return command{}, fmt.Errorf("-migrate-down cannot be combined with migrate")
+	}
+	return cmd, nil
+}
+
+// migrate applies the embedded schema migrations to db.
+func migrate(db *sql.DB) error {
+	if err := setupGoose(); err != nil {
+		return err
+	}
+	return goose.Up(db, ".")
+}
+
+// rollback undoes the n most recently applied migrations of db.
+func rollback(db *sql.DB, n int) error {
+	if err := setupGoose(); err != nil {
+		return err
+	}
+	for range n {
+		if err := goose.Down(db, "."); err != nil {
+			return fmt.Errorf("failed to roll back: %w", err)
+		}
+	}
+	return nil
+}
+
+// setupGoose points goose at the embedded schema.
+func setupGoose() error {
+	goose.SetBaseFS(schema.FS)
+	return goose.SetDialect("sqlite")
+}
+
+// openDB opens the SQLite database at path, creating its parent directory
+// first so a fresh data directory works out of the box.
+func openDB(path string) (*sql.DB, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory %s: %w", dir, err)
+		}
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// loadLocation resolves name into a time zone. An empty name means the
+// server's local zone.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// loadInt parses an optional integer setting. An empty value yields 0, which
+// leaves the service default in place.
+func loadInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// loadTagPattern compiles the regular expression tags are extracted with.
+// An empty value keeps the default #tag pattern.
+func loadTagPattern(value string) (*regexp.Regexp, error) {
+	if value == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return nil, err
+	}
+	if re.NumSubexp() == 0 {
+		return nil, fmt.Errorf("%q has no capture group for the tag name", value)
+	}
+	return re, nil
+}
+
+// loadStaleTimerConfig parses the stale timer threshold and action. An empty
+// threshold defaults to 12h and "0" disables the check; the action is "warn"
+// (default) or "stop".
+func loadStaleTimerConfig(after, action string) (time.Duration, service.StaleTimerPolicy, error) {
+	maxAge := 12 * time.Hour
+	if after != "" {
+		d, err := time.ParseDuration(after)
+		if err != nil {
+			return 0, 0, fmt.Errorf("STALE_TIMER_AFTER: %w", err)
+		}
+		maxAge = d
+	}
+
+	switch action {
+	case "", "warn":
+		return maxAge, service.StaleTimerWarn, nil
+	case "stop":
+		return maxAge, service.StaleTimerStop, nil
+	default:
+		return 0, 0, fmt.Errorf("STALE_TIMER_ACTION: unknown action %q", action)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestOpenDBCreatesParentDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "nested", "ptt.sqlite3")
+
+	db, err := openDB(path)
+	if err != nil {
+		t.Fatalf("openDB failed: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if err := db.Ping(); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected database file to be created: %v", err)
+	}
+}
+
+func TestOpenDBReportsDirError(t *testing.T) {
+	// A regular file where the directory should be cannot be turned into one
+	blocker := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	_, err := openDB(filepath.Join(blocker, "ptt.sqlite3"))
+	if err == nil || !strings.Contains(err.Error(), "failed to create database directory") {
+		t.Errorf("expected directory creation error, got %v", err)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    command
+		wantErr bool
+	}{
+		{nil, command{}, false},
+		{[]string{"-migrate"}, command{migrateOnly: true}, false},
+		{[]string{"migrate"}, command{migrateOnly: true}, false},
+		{[]string{"-migrate-down", "2"}, command{rollback: 2}, false},
+		{[]string{"-migrate-down", "-1"}, command{}, true},
+		{[]string{"-migrate-down", "1", "migrate"}, command{}, true},
+		{[]string{"serve"}, command{}, true},
+		{[]string{"migrate", "now"}, command{}, true},
+		{[]string{"-unknown"}, command{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseArgs(tt.args)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseArgs(%v) = %+v, %v; want %+v, error %v", tt.args, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db, err := openDB(filepath.Join(t.TempDir(), "ptt.sqlite3"))
+	if err != nil {
+		t.Fatalf("openDB failed: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// Applying them again is a no-op
+	for range 2 {
+		if err := migrate(db); err != nil {
+			t.Fatalf("migrate failed: %v", err)
+		}
+	}
+	if _, err := db.Exec("SELECT id FROM time_entries"); err != nil {
+		t.Errorf("expected the schema to be applied: %v", err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	db, err := openDB(filepath.Join(t.TempDir(), "ptt.sqlite3"))
+	if err != nil {
+		t.Fatalf("openDB failed: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := migrate(db); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	latest, err := goose.GetDBVersion(db)
+	if err != nil {
+		t.Fatalf("GetDBVersion failed: %v", err)
+	}
+
+	// Migration 15 added time_entries.billable
+	const beforeBillable = 14
+	if err := rollback(db, int(latest-beforeBillable)); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if version, _ := goose.GetDBVersion(db); version != beforeBillable {
+		t.Errorf("expected version %d, got %d", beforeBillable, version)
+	}
+	if _, err := db.Exec("SELECT billable FROM time_entries"); err == nil {
+		t.Error("expected the billable column to be gone")
+	}
+	if _, err := db.Exec("SELECT notes FROM time_entries"); err != nil {
+		t.Errorf("expected earlier migrations to stay applied: %v", err)
+	}
+
+	// Migrating up again restores it
+	if err := migrate(db); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if _, err := db.Exec("SELECT billable FROM time_entries"); err != nil {
+		t.Errorf("expected the billable column back: %v", err)
+	}
+}
@@ -255,7 +255,7 @@ func TestDeleteOrphanedTags(t *testing.T) {
 	}
 
 	// 4. Run Custom Cleanup
-	if err := q.DeleteOrphanedTags(ctx); err != nil {
+	if _, err := q.DeleteOrphanedTags(ctx); err != nil {
 		t.Fatalf("DeleteOrphanedTags failed: %v", err)
 	}
 
@@ -264,3 +264,71 @@ func TestDeleteOrphanedTags(t *testing.T) {
 		t.Errorf("Tag should have been deleted after cleanup!")
 	}
 }
+
+func TestCategoryNameMigrationMergesCaseVariants(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	goose.SetBaseFS(schema.FS)
+	if err := goose.SetDialect("sqlite"); err != nil {
+		t.Fatalf("failed to set dialect: %v", err)
+	}
+	if err := goose.UpTo(db, ".", 13); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	// Duplicates created while names were compared case-sensitively
+	for _, stmt := range []string{
+		`INSERT INTO categories (id, name) VALUES (1, 'Work'), (2, 'work'), (3, 'Home')`,
+		`UPDATE categories SET parent_id = 2 WHERE id = 3`,
+		`INSERT INTO time_entries (description, start_time, category_id) VALUES ('Variant', '2024-01-01 09:00:00 +0000 UTC', 2)`,
+		`INSERT INTO goals (category_id, period, target_seconds) VALUES (1, 'week', 3600), (2, 'week', 7200), (2, 'month', 36000)`,
+		`INSERT INTO settings (key, value) VALUES ('default_category_id', '2')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if err := goose.Up(db, "."); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	q := New(db)
+	ctx := context.Background()
+	cats, err := q.ListCategories(ctx)
+	if err != nil {
+		t.Fatalf("ListCategories failed: %v", err)
+	}
+	if len(cats) != 2 {
+		t.Fatalf("expected the variants to be merged, got %+v", cats)
+	}
+	home, _ := q.GetCategory(ctx, 3)
+	if home.ParentID.Int64 != 1 {
+		t.Errorf("expected Home under the kept category, got %+v", home.ParentID)
+	}
+	entries, _ := q.ListAllTimeEntries(ctx)
+	if len(entries) != 1 || entries[0].CategoryID.Int64 != 1 {
+		t.Errorf("expected the entry moved to the kept category, got %+v", entries)
+	}
+	goals, _ := q.ListGoalsForPeriod(ctx, "week")
+	if len(goals) != 1 || goals[0].TargetSeconds != 3600 {
+		t.Errorf("expected the kept category's weekly goal to win, got %+v", goals)
+	}
+	if goals, _ := q.ListGoalsForPeriod(ctx, "month"); len(goals) != 1 || goals[0].CategoryID != 1 {
+		t.Errorf("expected the monthly goal to move, got %+v", goals)
+	}
+	if v, _ := q.GetSetting(ctx, "default_category_id"); v != "1" {
+		t.Errorf("expected the default category to move, got %q", v)
+	}
+
+	if _, err := q.CreateCategory(ctx, CreateCategoryParams{Name: "HOME", Color: "#cccccc"}); err == nil {
+		t.Error("expected names to be unique regardless of case")
+	}
+	cat, err := q.GetCategoryByName(ctx, "home")
+	if err != nil || cat.ID != 3 {
+		t.Errorf("expected lookup by name to ignore case, got %+v, %v", cat, err)
+	}
+}
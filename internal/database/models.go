@@ -10,24 +10,67 @@ import (
 )
 
 type Category struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Color     string    `json:"color"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int64         `json:"id"`
+	Name      string        `json:"name"`
+	Color     string        `json:"color"`
+	CreatedAt time.Time     `json:"created_at"`
+	SortOrder int64         `json:"sort_order"`
+	ParentID  sql.NullInt64 `json:"parent_id"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+type DataVersion struct {
+	ID      int64 `json:"id"`
+	Version int64 `json:"version"`
+}
+
+type EntryAudit struct {
+	ID          int64          `json:"id"`
+	TimeEntryID int64          `json:"time_entry_id"`
+	Action      string         `json:"action"`
+	OldValue    sql.NullString `json:"old_value"`
+	NewValue    sql.NullString `json:"new_value"`
+	ChangedAt   time.Time      `json:"changed_at"`
+}
+
+type FocusSession struct {
+	ID             int64         `json:"id"`
+	TimeEntryID    int64         `json:"time_entry_id"`
+	StartTime      time.Time     `json:"start_time"`
+	PlannedSeconds sql.NullInt64 `json:"planned_seconds"`
+}
+
+type Goal struct {
+	CategoryID    int64  `json:"category_id"`
+	Period        string `json:"period"`
+	TargetSeconds int64  `json:"target_seconds"`
+}
+
+type Setting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
 }
 
 type Tag struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	Explicit  bool      `json:"explicit"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type TimeEntry struct {
-	ID          int64         `json:"id"`
-	Description string        `json:"description"`
-	StartTime   time.Time     `json:"start_time"`
-	EndTime     sql.NullTime  `json:"end_time"`
-	CreatedAt   time.Time     `json:"created_at"`
-	CategoryID  sql.NullInt64 `json:"category_id"`
+	ID          int64          `json:"id"`
+	Description string         `json:"description"`
+	StartTime   time.Time      `json:"start_time"`
+	EndTime     sql.NullTime   `json:"end_time"`
+	CreatedAt   time.Time      `json:"created_at"`
+	CategoryID  sql.NullInt64  `json:"category_id"`
+	Color       sql.NullString `json:"color"`
+	ExternalID  sql.NullString `json:"external_id"`
+	Notes       string         `json:"notes"`
+	Billable    bool           `json:"billable"`
+	UpdatedAt   time.Time      `json:"updated_at"`
 }
 
 type TimeEntryTag struct {
@@ -8,13 +8,38 @@ package database
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 )
 
+const countTimeEntries = `-- name: CountTimeEntries :one
+SELECT COUNT(*) FROM time_entries
+`
+
+func (q *Queries) CountTimeEntries(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countTimeEntries)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUncategorizedTimeEntries = `-- name: CountUncategorizedTimeEntries :one
+SELECT COUNT(*) FROM time_entries
+WHERE end_time IS NOT NULL
+AND category_id IS NULL
+`
+
+func (q *Queries) CountUncategorizedTimeEntries(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUncategorizedTimeEntries)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createCategory = `-- name: CreateCategory :one
-INSERT INTO categories (name, color)
-VALUES (?, ?)
-RETURNING id, name, color, created_at
+INSERT INTO categories (name, color, sort_order)
+VALUES (?, ?, (SELECT COALESCE(MAX(sort_order), -1) + 1 FROM categories))
+RETURNING id, name, color, created_at, sort_order, parent_id, updated_at
 `
 
 type CreateCategoryParams struct {
@@ -30,6 +55,57 @@ func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams)
 		&i.Name,
 		&i.Color,
 		&i.CreatedAt,
+		&i.SortOrder,
+		&i.ParentID,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createEntryAudit = `-- name: CreateEntryAudit :exec
+INSERT INTO entry_audit (time_entry_id, action, old_value, new_value, changed_at)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type CreateEntryAuditParams struct {
+	TimeEntryID int64          `json:"time_entry_id"`
+	Action      string         `json:"action"`
+	OldValue    sql.NullString `json:"old_value"`
+	NewValue    sql.NullString `json:"new_value"`
+	ChangedAt   time.Time      `json:"changed_at"`
+}
+
+func (q *Queries) CreateEntryAudit(ctx context.Context, arg CreateEntryAuditParams) error {
+	_, err := q.db.ExecContext(ctx, createEntryAudit,
+		arg.TimeEntryID,
+		arg.Action,
+		arg.OldValue,
+		arg.NewValue,
+		arg.ChangedAt,
+	)
+	return err
+}
+
+const createFocusSession = `-- name: CreateFocusSession :one
+INSERT INTO focus_sessions (time_entry_id, start_time, planned_seconds)
+VALUES (?, ?, ?)
+RETURNING id, time_entry_id, start_time, planned_seconds
+`
+
+type CreateFocusSessionParams struct {
+	TimeEntryID    int64         `json:"time_entry_id"`
+	StartTime      time.Time     `json:"start_time"`
+	PlannedSeconds sql.NullInt64 `json:"planned_seconds"`
+}
+
+func (q *Queries) CreateFocusSession(ctx context.Context, arg CreateFocusSessionParams) (FocusSession, error) {
+	row := q.db.QueryRowContext(ctx, createFocusSession, arg.TimeEntryID, arg.StartTime, arg.PlannedSeconds)
+	var i FocusSession
+	err := row.Scan(
+		&i.ID,
+		&i.TimeEntryID,
+		&i.StartTime,
+		&i.PlannedSeconds,
 	)
 	return i, err
 }
@@ -38,13 +114,19 @@ const createTag = `-- name: CreateTag :one
 INSERT INTO tags (name)
 VALUES (?)
 ON CONFLICT(name) DO UPDATE SET name=name
-RETURNING id, name
+RETURNING id, name, color, explicit, updated_at
 `
 
 func (q *Queries) CreateTag(ctx context.Context, name string) (Tag, error) {
 	row := q.db.QueryRowContext(ctx, createTag, name)
 	var i Tag
-	err := row.Scan(&i.ID, &i.Name)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Color,
+		&i.Explicit,
+		&i.UpdatedAt,
+	)
 	return i, err
 }
 
@@ -56,7 +138,7 @@ INSERT INTO time_entries (
 ) VALUES (
     ?, ?, ?
 )
-RETURNING id, description, start_time, end_time, created_at, category_id
+RETURNING id, description, start_time, end_time, created_at, category_id, color, external_id, notes, billable, updated_at
 `
 
 type CreateTimeEntryParams struct {
@@ -75,6 +157,11 @@ func (q *Queries) CreateTimeEntry(ctx context.Context, arg CreateTimeEntryParams
 		&i.EndTime,
 		&i.CreatedAt,
 		&i.CategoryID,
+		&i.Color,
+		&i.ExternalID,
+		&i.Notes,
+		&i.Billable,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
@@ -84,18 +171,20 @@ INSERT INTO time_entries (
     description,
     start_time,
     end_time,
-    category_id
+    category_id,
+    color
 ) VALUES (
-    ?, ?, ?, ?
+    ?, ?, ?, ?, ?
 )
-RETURNING id, description, start_time, end_time, created_at, category_id
+RETURNING id, description, start_time, end_time, created_at, category_id, color, external_id, notes, billable, updated_at
 `
 
 type CreateTimeEntryFullParams struct {
-	Description string        `json:"description"`
-	StartTime   time.Time     `json:"start_time"`
-	EndTime     sql.NullTime  `json:"end_time"`
-	CategoryID  sql.NullInt64 `json:"category_id"`
+	Description string         `json:"description"`
+	StartTime   time.Time      `json:"start_time"`
+	EndTime     sql.NullTime   `json:"end_time"`
+	CategoryID  sql.NullInt64  `json:"category_id"`
+	Color       sql.NullString `json:"color"`
 }
 
 func (q *Queries) CreateTimeEntryFull(ctx context.Context, arg CreateTimeEntryFullParams) (TimeEntry, error) {
@@ -104,6 +193,7 @@ func (q *Queries) CreateTimeEntryFull(ctx context.Context, arg CreateTimeEntryFu
 		arg.StartTime,
 		arg.EndTime,
 		arg.CategoryID,
+		arg.Color,
 	)
 	var i TimeEntry
 	err := row.Scan(
@@ -113,6 +203,11 @@ func (q *Queries) CreateTimeEntryFull(ctx context.Context, arg CreateTimeEntryFu
 		&i.EndTime,
 		&i.CreatedAt,
 		&i.CategoryID,
+		&i.Color,
+		&i.ExternalID,
+		&i.Notes,
+		&i.Billable,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
@@ -132,25 +227,101 @@ func (q *Queries) CreateTimeEntryTag(ctx context.Context, arg CreateTimeEntryTag
 	return err
 }
 
-const deleteCategory = `-- name: DeleteCategory :exec
+const deleteAllCategories = `-- name: DeleteAllCategories :exec
 DELETE FROM categories
-WHERE id = ?
 `
 
-func (q *Queries) DeleteCategory(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteCategory, id)
+func (q *Queries) DeleteAllCategories(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllCategories)
 	return err
 }
 
-const deleteOrphanedTags = `-- name: DeleteOrphanedTags :exec
+const deleteAllOrphanedTags = `-- name: DeleteAllOrphanedTags :execrows
 DELETE FROM tags
 WHERE NOT EXISTS (
     SELECT 1 FROM time_entry_tags WHERE tag_id = tags.id
 )
 `
 
-func (q *Queries) DeleteOrphanedTags(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteOrphanedTags)
+func (q *Queries) DeleteAllOrphanedTags(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAllOrphanedTags)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteCategory = `-- name: DeleteCategory :execrows
+DELETE FROM categories
+WHERE id = ?
+`
+
+func (q *Queries) DeleteCategory(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteCategory, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteGoal = `-- name: DeleteGoal :exec
+DELETE FROM goals
+WHERE category_id = ? AND period = ?
+`
+
+type DeleteGoalParams struct {
+	CategoryID int64  `json:"category_id"`
+	Period     string `json:"period"`
+}
+
+func (q *Queries) DeleteGoal(ctx context.Context, arg DeleteGoalParams) error {
+	_, err := q.db.ExecContext(ctx, deleteGoal, arg.CategoryID, arg.Period)
+	return err
+}
+
+const deleteOrphanedTags = `-- name: DeleteOrphanedTags :execrows
+DELETE FROM tags
+WHERE explicit = 0
+AND NOT EXISTS (
+    SELECT 1 FROM time_entry_tags WHERE tag_id = tags.id
+)
+`
+
+func (q *Queries) DeleteOrphanedTags(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteOrphanedTags)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteSetting = `-- name: DeleteSetting :exec
+DELETE FROM settings
+WHERE key = ?
+`
+
+func (q *Queries) DeleteSetting(ctx context.Context, key string) error {
+	_, err := q.db.ExecContext(ctx, deleteSetting, key)
+	return err
+}
+
+const deleteTag = `-- name: DeleteTag :exec
+DELETE FROM tags
+WHERE id = ?
+`
+
+func (q *Queries) DeleteTag(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteTag, id)
+	return err
+}
+
+const deleteTagLinks = `-- name: DeleteTagLinks :exec
+DELETE FROM time_entry_tags
+WHERE tag_id = ?
+`
+
+func (q *Queries) DeleteTagLinks(ctx context.Context, tagID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteTagLinks, tagID)
 	return err
 }
 
@@ -174,8 +345,38 @@ func (q *Queries) DeleteTimeEntryTags(ctx context.Context, timeEntryID int64) er
 	return err
 }
 
+const getActiveFocusSession = `-- name: GetActiveFocusSession :one
+SELECT fs.id, fs.time_entry_id, fs.start_time, fs.planned_seconds, te.description
+FROM focus_sessions fs
+JOIN time_entries te ON te.id = fs.time_entry_id
+WHERE te.end_time IS NULL
+ORDER BY fs.start_time DESC, fs.id DESC
+LIMIT 1
+`
+
+type GetActiveFocusSessionRow struct {
+	ID             int64         `json:"id"`
+	TimeEntryID    int64         `json:"time_entry_id"`
+	StartTime      time.Time     `json:"start_time"`
+	PlannedSeconds sql.NullInt64 `json:"planned_seconds"`
+	Description    string        `json:"description"`
+}
+
+func (q *Queries) GetActiveFocusSession(ctx context.Context) (GetActiveFocusSessionRow, error) {
+	row := q.db.QueryRowContext(ctx, getActiveFocusSession)
+	var i GetActiveFocusSessionRow
+	err := row.Scan(
+		&i.ID,
+		&i.TimeEntryID,
+		&i.StartTime,
+		&i.PlannedSeconds,
+		&i.Description,
+	)
+	return i, err
+}
+
 const getActiveTimeEntry = `-- name: GetActiveTimeEntry :one
-SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, c.name as category_name, c.color as category_color 
+SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, te.color, te.external_id, te.notes, te.billable, te.updated_at, c.name as category_name, c.color as category_color 
 FROM time_entries te
 LEFT JOIN categories c ON te.category_id = c.id
 WHERE te.end_time IS NULL
@@ -190,6 +391,11 @@ type GetActiveTimeEntryRow struct {
 	EndTime       sql.NullTime   `json:"end_time"`
 	CreatedAt     time.Time      `json:"created_at"`
 	CategoryID    sql.NullInt64  `json:"category_id"`
+	Color         sql.NullString `json:"color"`
+	ExternalID    sql.NullString `json:"external_id"`
+	Notes         string         `json:"notes"`
+	Billable      bool           `json:"billable"`
+	UpdatedAt     time.Time      `json:"updated_at"`
 	CategoryName  sql.NullString `json:"category_name"`
 	CategoryColor sql.NullString `json:"category_color"`
 }
@@ -204,6 +410,11 @@ func (q *Queries) GetActiveTimeEntry(ctx context.Context) (GetActiveTimeEntryRow
 		&i.EndTime,
 		&i.CreatedAt,
 		&i.CategoryID,
+		&i.Color,
+		&i.ExternalID,
+		&i.Notes,
+		&i.Billable,
+		&i.UpdatedAt,
 		&i.CategoryName,
 		&i.CategoryColor,
 	)
@@ -211,7 +422,7 @@ func (q *Queries) GetActiveTimeEntry(ctx context.Context) (GetActiveTimeEntryRow
 }
 
 const getCategory = `-- name: GetCategory :one
-SELECT id, name, color, created_at FROM categories
+SELECT id, name, color, created_at, sort_order, parent_id, updated_at FROM categories
 WHERE id = ?
 `
 
@@ -223,13 +434,16 @@ func (q *Queries) GetCategory(ctx context.Context, id int64) (Category, error) {
 		&i.Name,
 		&i.Color,
 		&i.CreatedAt,
+		&i.SortOrder,
+		&i.ParentID,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
 const getCategoryByName = `-- name: GetCategoryByName :one
-SELECT id, name, color, created_at FROM categories
-WHERE name = ?
+SELECT id, name, color, created_at, sort_order, parent_id, updated_at FROM categories
+WHERE name = ? COLLATE NOCASE
 `
 
 func (q *Queries) GetCategoryByName(ctx context.Context, name string) (Category, error) {
@@ -240,24 +454,88 @@ func (q *Queries) GetCategoryByName(ctx context.Context, name string) (Category,
 		&i.Name,
 		&i.Color,
 		&i.CreatedAt,
+		&i.SortOrder,
+		&i.ParentID,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getSetting = `-- name: GetSetting :one
+SELECT value FROM settings
+WHERE key = ?
+`
+
+func (q *Queries) GetSetting(ctx context.Context, key string) (string, error) {
+	row := q.db.QueryRowContext(ctx, getSetting, key)
+	var value string
+	err := row.Scan(&value)
+	return value, err
+}
+
+const getTag = `-- name: GetTag :one
+SELECT id, name, color, explicit, updated_at FROM tags
+WHERE id = ?
+`
+
+func (q *Queries) GetTag(ctx context.Context, id int64) (Tag, error) {
+	row := q.db.QueryRowContext(ctx, getTag, id)
+	var i Tag
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Color,
+		&i.Explicit,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
 const getTagByName = `-- name: GetTagByName :one
-SELECT id, name FROM tags
+SELECT id, name, color, explicit, updated_at FROM tags
 WHERE name = ?
 `
 
 func (q *Queries) GetTagByName(ctx context.Context, name string) (Tag, error) {
 	row := q.db.QueryRowContext(ctx, getTagByName, name)
 	var i Tag
-	err := row.Scan(&i.ID, &i.Name)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Color,
+		&i.Explicit,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getTimeEntriesVersion = `-- name: GetTimeEntriesVersion :one
+SELECT
+    (SELECT version FROM data_version WHERE id = 1) AS version,
+    CAST(COALESCE((SELECT MAX(updated_at) FROM (
+        SELECT updated_at FROM time_entries
+        UNION ALL SELECT updated_at FROM categories
+        UNION ALL SELECT updated_at FROM tags
+    )), '') AS TEXT) AS last_updated
+`
+
+type GetTimeEntriesVersionRow struct {
+	Version     int64  `json:"version"`
+	LastUpdated string `json:"last_updated"`
+}
+
+func (q *Queries) GetTimeEntriesVersion(ctx context.Context) (GetTimeEntriesVersionRow, error) {
+	row := q.db.QueryRowContext(ctx, getTimeEntriesVersion)
+	var i GetTimeEntriesVersionRow
+	err := row.Scan(
+		&i.Version,
+		&i.LastUpdated,
+	)
 	return i, err
 }
 
 const getTimeEntry = `-- name: GetTimeEntry :one
-SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, c.name as category_name, c.color as category_color 
+SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, te.color, te.external_id, te.notes, te.billable, te.updated_at, c.name as category_name, c.color as category_color 
 FROM time_entries te
 LEFT JOIN categories c ON te.category_id = c.id
 WHERE te.id = ?
@@ -270,6 +548,11 @@ type GetTimeEntryRow struct {
 	EndTime       sql.NullTime   `json:"end_time"`
 	CreatedAt     time.Time      `json:"created_at"`
 	CategoryID    sql.NullInt64  `json:"category_id"`
+	Color         sql.NullString `json:"color"`
+	ExternalID    sql.NullString `json:"external_id"`
+	Notes         string         `json:"notes"`
+	Billable      bool           `json:"billable"`
+	UpdatedAt     time.Time      `json:"updated_at"`
 	CategoryName  sql.NullString `json:"category_name"`
 	CategoryColor sql.NullString `json:"category_color"`
 }
@@ -284,14 +567,92 @@ func (q *Queries) GetTimeEntry(ctx context.Context, id int64) (GetTimeEntryRow,
 		&i.EndTime,
 		&i.CreatedAt,
 		&i.CategoryID,
+		&i.Color,
+		&i.ExternalID,
+		&i.Notes,
+		&i.Billable,
+		&i.UpdatedAt,
 		&i.CategoryName,
 		&i.CategoryColor,
 	)
 	return i, err
 }
 
+const getTimeEntryIDByExternalID = `-- name: GetTimeEntryIDByExternalID :one
+SELECT id FROM time_entries
+WHERE external_id = ?
+`
+
+func (q *Queries) GetTimeEntryIDByExternalID(ctx context.Context, externalID sql.NullString) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getTimeEntryIDByExternalID, externalID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const listActiveTimeEntries = `-- name: ListActiveTimeEntries :many
+SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, te.color, te.external_id, te.notes, te.billable, te.updated_at, c.name as category_name, c.color as category_color 
+FROM time_entries te
+LEFT JOIN categories c ON te.category_id = c.id
+WHERE te.end_time IS NULL
+ORDER BY te.start_time DESC
+`
+
+type ListActiveTimeEntriesRow struct {
+	ID            int64          `json:"id"`
+	Description   string         `json:"description"`
+	StartTime     time.Time      `json:"start_time"`
+	EndTime       sql.NullTime   `json:"end_time"`
+	CreatedAt     time.Time      `json:"created_at"`
+	CategoryID    sql.NullInt64  `json:"category_id"`
+	Color         sql.NullString `json:"color"`
+	ExternalID    sql.NullString `json:"external_id"`
+	Notes         string         `json:"notes"`
+	Billable      bool           `json:"billable"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	CategoryName  sql.NullString `json:"category_name"`
+	CategoryColor sql.NullString `json:"category_color"`
+}
+
+func (q *Queries) ListActiveTimeEntries(ctx context.Context) ([]ListActiveTimeEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveTimeEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListActiveTimeEntriesRow
+	for rows.Next() {
+		var i ListActiveTimeEntriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Description,
+			&i.StartTime,
+			&i.EndTime,
+			&i.CreatedAt,
+			&i.CategoryID,
+			&i.Color,
+			&i.ExternalID,
+			&i.Notes,
+			&i.Billable,
+			&i.UpdatedAt,
+			&i.CategoryName,
+			&i.CategoryColor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAllTimeEntries = `-- name: ListAllTimeEntries :many
-SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, c.name as category_name, c.color as category_color 
+SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, te.color, te.external_id, te.notes, te.billable, te.updated_at, c.name as category_name, c.color as category_color 
 FROM time_entries te
 LEFT JOIN categories c ON te.category_id = c.id
 ORDER BY te.start_time DESC
@@ -304,6 +665,11 @@ type ListAllTimeEntriesRow struct {
 	EndTime       sql.NullTime   `json:"end_time"`
 	CreatedAt     time.Time      `json:"created_at"`
 	CategoryID    sql.NullInt64  `json:"category_id"`
+	Color         sql.NullString `json:"color"`
+	ExternalID    sql.NullString `json:"external_id"`
+	Notes         string         `json:"notes"`
+	Billable      bool           `json:"billable"`
+	UpdatedAt     time.Time      `json:"updated_at"`
 	CategoryName  sql.NullString `json:"category_name"`
 	CategoryColor sql.NullString `json:"category_color"`
 }
@@ -324,6 +690,11 @@ func (q *Queries) ListAllTimeEntries(ctx context.Context) ([]ListAllTimeEntriesR
 			&i.EndTime,
 			&i.CreatedAt,
 			&i.CategoryID,
+			&i.Color,
+			&i.ExternalID,
+			&i.Notes,
+			&i.Billable,
+			&i.UpdatedAt,
 			&i.CategoryName,
 			&i.CategoryColor,
 		); err != nil {
@@ -341,8 +712,8 @@ func (q *Queries) ListAllTimeEntries(ctx context.Context) ([]ListAllTimeEntriesR
 }
 
 const listCategories = `-- name: ListCategories :many
-SELECT id, name, color, created_at FROM categories
-ORDER BY name
+SELECT id, name, color, created_at, sort_order, parent_id, updated_at FROM categories
+ORDER BY sort_order, name
 `
 
 func (q *Queries) ListCategories(ctx context.Context) ([]Category, error) {
@@ -359,6 +730,9 @@ func (q *Queries) ListCategories(ctx context.Context) ([]Category, error) {
 			&i.Name,
 			&i.Color,
 			&i.CreatedAt,
+			&i.SortOrder,
+			&i.ParentID,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -373,21 +747,26 @@ func (q *Queries) ListCategories(ctx context.Context) ([]Category, error) {
 	return items, nil
 }
 
-const listTags = `-- name: ListTags :many
-SELECT id, name FROM tags
-ORDER BY name
+const listCompletedEntrySpans = `-- name: ListCompletedEntrySpans :many
+SELECT start_time, end_time FROM time_entries
+WHERE end_time IS NOT NULL
 `
 
-func (q *Queries) ListTags(ctx context.Context) ([]Tag, error) {
-	rows, err := q.db.QueryContext(ctx, listTags)
+type ListCompletedEntrySpansRow struct {
+	StartTime time.Time    `json:"start_time"`
+	EndTime   sql.NullTime `json:"end_time"`
+}
+
+func (q *Queries) ListCompletedEntrySpans(ctx context.Context) ([]ListCompletedEntrySpansRow, error) {
+	rows, err := q.db.QueryContext(ctx, listCompletedEntrySpans)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Tag
+	var items []ListCompletedEntrySpansRow
 	for rows.Next() {
-		var i Tag
-		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+		var i ListCompletedEntrySpansRow
+		if err := rows.Scan(&i.StartTime, &i.EndTime); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -401,22 +780,29 @@ func (q *Queries) ListTags(ctx context.Context) ([]Tag, error) {
 	return items, nil
 }
 
-const listTagsForTimeEntry = `-- name: ListTagsForTimeEntry :many
-SELECT t.id, t.name FROM tags t
-JOIN time_entry_tags tet ON t.id = tet.tag_id
-WHERE tet.time_entry_id = ?
+const listEntryAudit = `-- name: ListEntryAudit :many
+SELECT id, time_entry_id, action, old_value, new_value, changed_at FROM entry_audit
+WHERE time_entry_id = ?
+ORDER BY id
 `
 
-func (q *Queries) ListTagsForTimeEntry(ctx context.Context, timeEntryID int64) ([]Tag, error) {
-	rows, err := q.db.QueryContext(ctx, listTagsForTimeEntry, timeEntryID)
+func (q *Queries) ListEntryAudit(ctx context.Context, timeEntryID int64) ([]EntryAudit, error) {
+	rows, err := q.db.QueryContext(ctx, listEntryAudit, timeEntryID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Tag
+	var items []EntryAudit
 	for rows.Next() {
-		var i Tag
-		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+		var i EntryAudit
+		if err := rows.Scan(
+			&i.ID,
+			&i.TimeEntryID,
+			&i.Action,
+			&i.OldValue,
+			&i.NewValue,
+			&i.ChangedAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -430,42 +816,35 @@ func (q *Queries) ListTagsForTimeEntry(ctx context.Context, timeEntryID int64) (
 	return items, nil
 }
 
-const listTimeEntries = `-- name: ListTimeEntries :many
-SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, c.name as category_name, c.color as category_color 
-FROM time_entries te
-LEFT JOIN categories c ON te.category_id = c.id
-WHERE te.end_time IS NOT NULL
-ORDER BY te.start_time DESC
-LIMIT 50
+const listGoalsForPeriod = `-- name: ListGoalsForPeriod :many
+SELECT g.category_id, g.period, g.target_seconds, c.name AS category_name, c.color AS category_color
+FROM goals g
+JOIN categories c ON c.id = g.category_id
+WHERE g.period = ?
+ORDER BY c.name
 `
 
-type ListTimeEntriesRow struct {
-	ID            int64          `json:"id"`
-	Description   string         `json:"description"`
-	StartTime     time.Time      `json:"start_time"`
-	EndTime       sql.NullTime   `json:"end_time"`
-	CreatedAt     time.Time      `json:"created_at"`
-	CategoryID    sql.NullInt64  `json:"category_id"`
-	CategoryName  sql.NullString `json:"category_name"`
-	CategoryColor sql.NullString `json:"category_color"`
+type ListGoalsForPeriodRow struct {
+	CategoryID    int64  `json:"category_id"`
+	Period        string `json:"period"`
+	TargetSeconds int64  `json:"target_seconds"`
+	CategoryName  string `json:"category_name"`
+	CategoryColor string `json:"category_color"`
 }
 
-func (q *Queries) ListTimeEntries(ctx context.Context) ([]ListTimeEntriesRow, error) {
-	rows, err := q.db.QueryContext(ctx, listTimeEntries)
+func (q *Queries) ListGoalsForPeriod(ctx context.Context, period string) ([]ListGoalsForPeriodRow, error) {
+	rows, err := q.db.QueryContext(ctx, listGoalsForPeriod, period)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListTimeEntriesRow
+	var items []ListGoalsForPeriodRow
 	for rows.Next() {
-		var i ListTimeEntriesRow
+		var i ListGoalsForPeriodRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.Description,
-			&i.StartTime,
-			&i.EndTime,
-			&i.CreatedAt,
 			&i.CategoryID,
+			&i.Period,
+			&i.TargetSeconds,
 			&i.CategoryName,
 			&i.CategoryColor,
 		); err != nil {
@@ -482,54 +861,617 @@ func (q *Queries) ListTimeEntries(ctx context.Context) ([]ListTimeEntriesRow, er
 	return items, nil
 }
 
-const listTimeEntriesReport = `-- name: ListTimeEntriesReport :many
-SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, c.name as category_name, c.color as category_color 
-FROM time_entries te
-LEFT JOIN categories c ON te.category_id = c.id
-WHERE te.end_time IS NOT NULL
-AND te.start_time >= ?
-AND te.start_time <= ?
-AND (
-    (?3 = 0)
-    OR (te.category_id = ?3)
-    OR (?3 = -1 AND te.category_id IS NULL)
-)
-ORDER BY te.start_time DESC
+const listSettings = `-- name: ListSettings :many
+SELECT key, value FROM settings
+ORDER BY key
 `
 
-type ListTimeEntriesReportParams struct {
-	StartTime      time.Time   `json:"start_time"`
-	StartTime_2    time.Time   `json:"start_time_2"`
-	CategoryFilter interface{} `json:"category_filter"`
-}
-
-type ListTimeEntriesReportRow struct {
-	ID            int64          `json:"id"`
-	Description   string         `json:"description"`
-	StartTime     time.Time      `json:"start_time"`
-	EndTime       sql.NullTime   `json:"end_time"`
-	CreatedAt     time.Time      `json:"created_at"`
-	CategoryID    sql.NullInt64  `json:"category_id"`
-	CategoryName  sql.NullString `json:"category_name"`
-	CategoryColor sql.NullString `json:"category_color"`
-}
-
-func (q *Queries) ListTimeEntriesReport(ctx context.Context, arg ListTimeEntriesReportParams) ([]ListTimeEntriesReportRow, error) {
-	rows, err := q.db.QueryContext(ctx, listTimeEntriesReport, arg.StartTime, arg.StartTime_2, arg.CategoryFilter)
+func (q *Queries) ListSettings(ctx context.Context) ([]Setting, error) {
+	rows, err := q.db.QueryContext(ctx, listSettings)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListTimeEntriesReportRow
+	var items []Setting
 	for rows.Next() {
-		var i ListTimeEntriesReportRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.Description,
-			&i.StartTime,
-			&i.EndTime,
-			&i.CreatedAt,
+		var i Setting
+		if err := rows.Scan(&i.Key, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagCooccurrences = `-- name: ListTagCooccurrences :many
+SELECT t.id, t.name, t.color, COUNT(*) AS entry_count
+FROM time_entry_tags a
+JOIN time_entry_tags b ON b.time_entry_id = a.time_entry_id AND b.tag_id != a.tag_id
+JOIN tags t ON t.id = b.tag_id
+WHERE a.tag_id = ?
+GROUP BY t.id
+ORDER BY entry_count DESC, t.name
+`
+
+type ListTagCooccurrencesRow struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Color      string `json:"color"`
+	EntryCount int64  `json:"entry_count"`
+}
+
+func (q *Queries) ListTagCooccurrences(ctx context.Context, tagID int64) ([]ListTagCooccurrencesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTagCooccurrences, tagID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTagCooccurrencesRow
+	for rows.Next() {
+		var i ListTagCooccurrencesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Color,
+			&i.EntryCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTags = `-- name: ListTags :many
+SELECT id, name, color, explicit, updated_at FROM tags
+ORDER BY name
+`
+
+func (q *Queries) ListTags(ctx context.Context) ([]Tag, error) {
+	rows, err := q.db.QueryContext(ctx, listTags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tag
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Color,
+			&i.Explicit,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagsForTimeEntries = `-- name: ListTagsForTimeEntries :many
+SELECT tet.time_entry_id, t.id, t.name, t.color
+FROM time_entry_tags tet
+JOIN tags t ON t.id = tet.tag_id
+WHERE tet.time_entry_id IN (/*SLICE:time_entry_ids*/?)
+ORDER BY t.name
+`
+
+type ListTagsForTimeEntriesRow struct {
+	TimeEntryID int64  `json:"time_entry_id"`
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+}
+
+func (q *Queries) ListTagsForTimeEntries(ctx context.Context, timeEntryIds []int64) ([]ListTagsForTimeEntriesRow, error) {
+	query := listTagsForTimeEntries
+	var queryParams []interface{}
+	if len(timeEntryIds) > 0 {
+		for _, v := range timeEntryIds {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:time_entry_ids*/?", strings.Repeat(",?", len(timeEntryIds))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:time_entry_ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTagsForTimeEntriesRow
+	for rows.Next() {
+		var i ListTagsForTimeEntriesRow
+		if err := rows.Scan(
+			&i.TimeEntryID,
+			&i.ID,
+			&i.Name,
+			&i.Color,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagsForTimeEntry = `-- name: ListTagsForTimeEntry :many
+SELECT t.id, t.name, t.color, t.explicit, t.updated_at FROM tags t
+JOIN time_entry_tags tet ON t.id = tet.tag_id
+WHERE tet.time_entry_id = ?
+`
+
+func (q *Queries) ListTagsForTimeEntry(ctx context.Context, timeEntryID int64) ([]Tag, error) {
+	rows, err := q.db.QueryContext(ctx, listTagsForTimeEntry, timeEntryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tag
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Color,
+			&i.Explicit,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagsWithCounts = `-- name: ListTagsWithCounts :many
+SELECT t.id, t.name, t.color, COUNT(tet.time_entry_id) AS entry_count
+FROM tags t
+LEFT JOIN time_entry_tags tet ON tet.tag_id = t.id
+GROUP BY t.id
+ORDER BY t.name
+`
+
+type ListTagsWithCountsRow struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Color      string `json:"color"`
+	EntryCount int64  `json:"entry_count"`
+}
+
+func (q *Queries) ListTagsWithCounts(ctx context.Context) ([]ListTagsWithCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTagsWithCounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTagsWithCountsRow
+	for rows.Next() {
+		var i ListTagsWithCountsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Color,
+			&i.EntryCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimeEntries = `-- name: ListTimeEntries :many
+SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, te.color, te.external_id, te.notes, te.billable, te.updated_at, c.name as category_name, c.color as category_color 
+FROM time_entries te
+LEFT JOIN categories c ON te.category_id = c.id
+WHERE te.end_time IS NOT NULL
+ORDER BY te.start_time DESC
+LIMIT 50
+`
+
+type ListTimeEntriesRow struct {
+	ID            int64          `json:"id"`
+	Description   string         `json:"description"`
+	StartTime     time.Time      `json:"start_time"`
+	EndTime       sql.NullTime   `json:"end_time"`
+	CreatedAt     time.Time      `json:"created_at"`
+	CategoryID    sql.NullInt64  `json:"category_id"`
+	Color         sql.NullString `json:"color"`
+	ExternalID    sql.NullString `json:"external_id"`
+	Notes         string         `json:"notes"`
+	Billable      bool           `json:"billable"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	CategoryName  sql.NullString `json:"category_name"`
+	CategoryColor sql.NullString `json:"category_color"`
+}
+
+func (q *Queries) ListTimeEntries(ctx context.Context) ([]ListTimeEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTimeEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTimeEntriesRow
+	for rows.Next() {
+		var i ListTimeEntriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Description,
+			&i.StartTime,
+			&i.EndTime,
+			&i.CreatedAt,
+			&i.CategoryID,
+			&i.Color,
+			&i.ExternalID,
+			&i.Notes,
+			&i.Billable,
+			&i.UpdatedAt,
+			&i.CategoryName,
+			&i.CategoryColor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimeEntriesByTag = `-- name: ListTimeEntriesByTag :many
+SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, te.color, te.external_id, te.notes, te.billable, te.updated_at, c.name as category_name, c.color as category_color 
+FROM time_entries te
+JOIN time_entry_tags tet ON te.id = tet.time_entry_id
+JOIN tags t ON t.id = tet.tag_id
+LEFT JOIN categories c ON te.category_id = c.id
+WHERE t.name = ?
+ORDER BY te.start_time DESC
+`
+
+type ListTimeEntriesByTagRow struct {
+	ID            int64          `json:"id"`
+	Description   string         `json:"description"`
+	StartTime     time.Time      `json:"start_time"`
+	EndTime       sql.NullTime   `json:"end_time"`
+	CreatedAt     time.Time      `json:"created_at"`
+	CategoryID    sql.NullInt64  `json:"category_id"`
+	Color         sql.NullString `json:"color"`
+	ExternalID    sql.NullString `json:"external_id"`
+	Notes         string         `json:"notes"`
+	Billable      bool           `json:"billable"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	CategoryName  sql.NullString `json:"category_name"`
+	CategoryColor sql.NullString `json:"category_color"`
+}
+
+func (q *Queries) ListTimeEntriesByTag(ctx context.Context, name string) ([]ListTimeEntriesByTagRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTimeEntriesByTag, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTimeEntriesByTagRow
+	for rows.Next() {
+		var i ListTimeEntriesByTagRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Description,
+			&i.StartTime,
+			&i.EndTime,
+			&i.CreatedAt,
+			&i.CategoryID,
+			&i.Color,
+			&i.ExternalID,
+			&i.Notes,
+			&i.Billable,
+			&i.UpdatedAt,
+			&i.CategoryName,
+			&i.CategoryColor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimeEntriesPage = `-- name: ListTimeEntriesPage :many
+SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, te.color, te.external_id, te.notes, te.billable, te.updated_at, c.name as category_name, c.color as category_color 
+FROM time_entries te
+LEFT JOIN categories c ON te.category_id = c.id
+ORDER BY
+    CASE WHEN ?1 = 'start_asc' THEN te.start_time END ASC,
+    te.start_time DESC,
+    te.id DESC
+LIMIT ?2 OFFSET ?3
+`
+
+type ListTimeEntriesPageParams struct {
+	Sort   interface{} `json:"sort"`
+	Limit  int64       `json:"limit"`
+	Offset int64       `json:"offset"`
+}
+
+type ListTimeEntriesPageRow struct {
+	ID            int64          `json:"id"`
+	Description   string         `json:"description"`
+	StartTime     time.Time      `json:"start_time"`
+	EndTime       sql.NullTime   `json:"end_time"`
+	CreatedAt     time.Time      `json:"created_at"`
+	CategoryID    sql.NullInt64  `json:"category_id"`
+	Color         sql.NullString `json:"color"`
+	ExternalID    sql.NullString `json:"external_id"`
+	Notes         string         `json:"notes"`
+	Billable      bool           `json:"billable"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	CategoryName  sql.NullString `json:"category_name"`
+	CategoryColor sql.NullString `json:"category_color"`
+}
+
+func (q *Queries) ListTimeEntriesPage(ctx context.Context, arg ListTimeEntriesPageParams) ([]ListTimeEntriesPageRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTimeEntriesPage, arg.Sort, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTimeEntriesPageRow
+	for rows.Next() {
+		var i ListTimeEntriesPageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Description,
+			&i.StartTime,
+			&i.EndTime,
+			&i.CreatedAt,
+			&i.CategoryID,
+			&i.Color,
+			&i.ExternalID,
+			&i.Notes,
+			&i.Billable,
+			&i.UpdatedAt,
+			&i.CategoryName,
+			&i.CategoryColor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimeEntriesReport = `-- name: ListTimeEntriesReport :many
+SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, te.color, te.external_id, te.notes, te.billable, te.updated_at, c.name as category_name, c.color as category_color 
+FROM time_entries te
+LEFT JOIN categories c ON te.category_id = c.id
+WHERE (
+    (
+        (?1 = 0 OR te.start_time >= ?2)
+        AND (?3 = 0 OR te.start_time <= ?4)
+    )
+    OR (
+        ?5 = 1
+        AND te.start_time < ?2
+        AND (te.end_time IS NULL OR te.end_time > ?2)
+    )
+)
+AND (
+    (?6 = 0)
+    OR (te.category_id = ?6)
+    OR (?6 = -1 AND te.category_id IS NULL)
+)
+AND (te.end_time IS NOT NULL OR ?7 = 1)
+ORDER BY
+    CASE WHEN ?8 = 1 THEN te.start_time END ASC,
+    te.start_time DESC
+`
+
+type ListTimeEntriesReportParams struct {
+	HasStart           interface{} `json:"has_start"`
+	StartDate          time.Time   `json:"start_date"`
+	HasEnd             interface{} `json:"has_end"`
+	EndDate            time.Time   `json:"end_date"`
+	IncludeOverlapping interface{} `json:"include_overlapping"`
+	CategoryFilter     interface{} `json:"category_filter"`
+	IncludeRunning     interface{} `json:"include_running"`
+	OldestFirst        interface{} `json:"oldest_first"`
+}
+
+type ListTimeEntriesReportRow struct {
+	ID            int64          `json:"id"`
+	Description   string         `json:"description"`
+	StartTime     time.Time      `json:"start_time"`
+	EndTime       sql.NullTime   `json:"end_time"`
+	CreatedAt     time.Time      `json:"created_at"`
+	CategoryID    sql.NullInt64  `json:"category_id"`
+	Color         sql.NullString `json:"color"`
+	ExternalID    sql.NullString `json:"external_id"`
+	Notes         string         `json:"notes"`
+	Billable      bool           `json:"billable"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	CategoryName  sql.NullString `json:"category_name"`
+	CategoryColor sql.NullString `json:"category_color"`
+}
+
+func (q *Queries) ListTimeEntriesReport(ctx context.Context, arg ListTimeEntriesReportParams) ([]ListTimeEntriesReportRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTimeEntriesReport,
+		arg.HasStart,
+		arg.StartDate,
+		arg.HasEnd,
+		arg.EndDate,
+		arg.IncludeOverlapping,
+		arg.CategoryFilter,
+		arg.IncludeRunning,
+		arg.OldestFirst,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTimeEntriesReportRow
+	for rows.Next() {
+		var i ListTimeEntriesReportRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Description,
+			&i.StartTime,
+			&i.EndTime,
+			&i.CreatedAt,
+			&i.CategoryID,
+			&i.Color,
+			&i.ExternalID,
+			&i.Notes,
+			&i.Billable,
+			&i.UpdatedAt,
+			&i.CategoryName,
+			&i.CategoryColor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimeEntryBounds = `-- name: ListTimeEntryBounds :many
+SELECT start_time, end_time FROM time_entries
+ORDER BY start_time
+`
+
+type ListTimeEntryBoundsRow struct {
+	StartTime time.Time    `json:"start_time"`
+	EndTime   sql.NullTime `json:"end_time"`
+}
+
+func (q *Queries) ListTimeEntryBounds(ctx context.Context) ([]ListTimeEntryBoundsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTimeEntryBounds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTimeEntryBoundsRow
+	for rows.Next() {
+		var i ListTimeEntryBoundsRow
+		if err := rows.Scan(&i.StartTime, &i.EndTime); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUncategorizedTimeEntries = `-- name: ListUncategorizedTimeEntries :many
+SELECT te.id, te.description, te.start_time, te.end_time, te.created_at, te.category_id, te.color, te.external_id, te.notes, te.billable, te.updated_at, c.name as category_name, c.color as category_color 
+FROM time_entries te
+LEFT JOIN categories c ON te.category_id = c.id
+WHERE te.end_time IS NOT NULL
+AND te.category_id IS NULL
+ORDER BY te.start_time DESC
+`
+
+type ListUncategorizedTimeEntriesRow struct {
+	ID            int64          `json:"id"`
+	Description   string         `json:"description"`
+	StartTime     time.Time      `json:"start_time"`
+	EndTime       sql.NullTime   `json:"end_time"`
+	CreatedAt     time.Time      `json:"created_at"`
+	CategoryID    sql.NullInt64  `json:"category_id"`
+	Color         sql.NullString `json:"color"`
+	ExternalID    sql.NullString `json:"external_id"`
+	Notes         string         `json:"notes"`
+	Billable      bool           `json:"billable"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	CategoryName  sql.NullString `json:"category_name"`
+	CategoryColor sql.NullString `json:"category_color"`
+}
+
+func (q *Queries) ListUncategorizedTimeEntries(ctx context.Context) ([]ListUncategorizedTimeEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUncategorizedTimeEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUncategorizedTimeEntriesRow
+	for rows.Next() {
+		var i ListUncategorizedTimeEntriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Description,
+			&i.StartTime,
+			&i.EndTime,
+			&i.CreatedAt,
 			&i.CategoryID,
+			&i.Color,
+			&i.ExternalID,
+			&i.Notes,
+			&i.Billable,
+			&i.UpdatedAt,
 			&i.CategoryName,
 			&i.CategoryColor,
 		); err != nil {
@@ -546,36 +1488,130 @@ func (q *Queries) ListTimeEntriesReport(ctx context.Context, arg ListTimeEntries
 	return items, nil
 }
 
+const moveTimeEntryTags = `-- name: MoveTimeEntryTags :exec
+INSERT OR IGNORE INTO time_entry_tags (time_entry_id, tag_id)
+SELECT time_entry_id, CAST(?1 AS INTEGER)
+FROM time_entry_tags
+WHERE tag_id = ?2
+`
+
+type MoveTimeEntryTagsParams struct {
+	ToTagID   int64 `json:"to_tag_id"`
+	FromTagID int64 `json:"from_tag_id"`
+}
+
+func (q *Queries) MoveTimeEntryTags(ctx context.Context, arg MoveTimeEntryTagsParams) error {
+	_, err := q.db.ExecContext(ctx, moveTimeEntryTags, arg.ToTagID, arg.FromTagID)
+	return err
+}
+
+const setGoal = `-- name: SetGoal :exec
+INSERT INTO goals (category_id, period, target_seconds)
+VALUES (?, ?, ?)
+ON CONFLICT(category_id, period) DO UPDATE SET target_seconds = excluded.target_seconds
+`
+
+type SetGoalParams struct {
+	CategoryID    int64  `json:"category_id"`
+	Period        string `json:"period"`
+	TargetSeconds int64  `json:"target_seconds"`
+}
+
+func (q *Queries) SetGoal(ctx context.Context, arg SetGoalParams) error {
+	_, err := q.db.ExecContext(ctx, setGoal, arg.CategoryID, arg.Period, arg.TargetSeconds)
+	return err
+}
+
+const setSetting = `-- name: SetSetting :exec
+INSERT INTO settings (key, value)
+VALUES (?, ?)
+ON CONFLICT(key) DO UPDATE SET value = excluded.value
+`
+
+type SetSettingParams struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func (q *Queries) SetSetting(ctx context.Context, arg SetSettingParams) error {
+	_, err := q.db.ExecContext(ctx, setSetting, arg.Key, arg.Value)
+	return err
+}
+
 const updateCategory = `-- name: UpdateCategory :one
 UPDATE categories
-SET name = ?, color = ?
+SET name = ?, color = ?, parent_id = ?
 WHERE id = ?
-RETURNING id, name, color, created_at
+RETURNING id, name, color, created_at, sort_order, parent_id, updated_at
 `
 
 type UpdateCategoryParams struct {
-	Name  string `json:"name"`
-	Color string `json:"color"`
-	ID    int64  `json:"id"`
+	Name     string        `json:"name"`
+	Color    string        `json:"color"`
+	ParentID sql.NullInt64 `json:"parent_id"`
+	ID       int64         `json:"id"`
 }
 
 func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (Category, error) {
-	row := q.db.QueryRowContext(ctx, updateCategory, arg.Name, arg.Color, arg.ID)
+	row := q.db.QueryRowContext(ctx, updateCategory,
+		arg.Name,
+		arg.Color,
+		arg.ParentID,
+		arg.ID,
+	)
 	var i Category
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.Color,
 		&i.CreatedAt,
+		&i.SortOrder,
+		&i.ParentID,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const updateCategorySortOrder = `-- name: UpdateCategorySortOrder :execrows
+UPDATE categories
+SET sort_order = ?
+WHERE id = ?
+`
+
+type UpdateCategorySortOrderParams struct {
+	SortOrder int64 `json:"sort_order"`
+	ID        int64 `json:"id"`
+}
+
+func (q *Queries) UpdateCategorySortOrder(ctx context.Context, arg UpdateCategorySortOrderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateCategorySortOrder, arg.SortOrder, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateTagName = `-- name: UpdateTagName :exec
+UPDATE tags
+SET name = ?
+WHERE id = ?
+`
+
+type UpdateTagNameParams struct {
+	Name string `json:"name"`
+	ID   int64  `json:"id"`
+}
+
+func (q *Queries) UpdateTagName(ctx context.Context, arg UpdateTagNameParams) error {
+	_, err := q.db.ExecContext(ctx, updateTagName, arg.Name, arg.ID)
+	return err
+}
+
 const updateTimeEntry = `-- name: UpdateTimeEntry :one
 UPDATE time_entries
 SET end_time = ?
 WHERE id = ?
-RETURNING id, description, start_time, end_time, created_at, category_id
+RETURNING id, description, start_time, end_time, created_at, category_id, color, external_id, notes, billable, updated_at
 `
 
 type UpdateTimeEntryParams struct {
@@ -593,15 +1629,52 @@ func (q *Queries) UpdateTimeEntry(ctx context.Context, arg UpdateTimeEntryParams
 		&i.EndTime,
 		&i.CreatedAt,
 		&i.CategoryID,
+		&i.Color,
+		&i.ExternalID,
+		&i.Notes,
+		&i.Billable,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const updateTimeEntryBillable = `-- name: UpdateTimeEntryBillable :exec
+UPDATE time_entries
+SET billable = ?
+WHERE id = ?
+`
+
+type UpdateTimeEntryBillableParams struct {
+	Billable bool  `json:"billable"`
+	ID       int64 `json:"id"`
+}
+
+func (q *Queries) UpdateTimeEntryBillable(ctx context.Context, arg UpdateTimeEntryBillableParams) error {
+	_, err := q.db.ExecContext(ctx, updateTimeEntryBillable, arg.Billable, arg.ID)
+	return err
+}
+
+const updateTimeEntryColor = `-- name: UpdateTimeEntryColor :exec
+UPDATE time_entries
+SET color = ?
+WHERE id = ?
+`
+
+type UpdateTimeEntryColorParams struct {
+	Color sql.NullString `json:"color"`
+	ID    int64          `json:"id"`
+}
+
+func (q *Queries) UpdateTimeEntryColor(ctx context.Context, arg UpdateTimeEntryColorParams) error {
+	_, err := q.db.ExecContext(ctx, updateTimeEntryColor, arg.Color, arg.ID)
+	return err
+}
+
 const updateTimeEntryFull = `-- name: UpdateTimeEntryFull :one
 UPDATE time_entries
 SET description = ?, start_time = ?, end_time = ?, category_id = ?
 WHERE id = ?
-RETURNING id, description, start_time, end_time, created_at, category_id
+RETURNING id, description, start_time, end_time, created_at, category_id, color, external_id, notes, billable, updated_at
 `
 
 type UpdateTimeEntryFullParams struct {
@@ -628,6 +1701,79 @@ func (q *Queries) UpdateTimeEntryFull(ctx context.Context, arg UpdateTimeEntryFu
 		&i.EndTime,
 		&i.CreatedAt,
 		&i.CategoryID,
+		&i.Color,
+		&i.ExternalID,
+		&i.Notes,
+		&i.Billable,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateTimeEntryNotes = `-- name: UpdateTimeEntryNotes :exec
+UPDATE time_entries
+SET notes = ?
+WHERE id = ?
+`
+
+type UpdateTimeEntryNotesParams struct {
+	Notes string `json:"notes"`
+	ID    int64  `json:"id"`
+}
+
+func (q *Queries) UpdateTimeEntryNotes(ctx context.Context, arg UpdateTimeEntryNotesParams) error {
+	_, err := q.db.ExecContext(ctx, updateTimeEntryNotes, arg.Notes, arg.ID)
+	return err
+}
+
+const upsertCategoryByName = `-- name: UpsertCategoryByName :one
+INSERT INTO categories (name, color, sort_order)
+VALUES (?, ?, (SELECT COALESCE(MAX(sort_order), -1) + 1 FROM categories))
+ON CONFLICT(name) DO UPDATE SET color = excluded.color
+RETURNING id, name, color, created_at, sort_order, parent_id, updated_at
+`
+
+type UpsertCategoryByNameParams struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+func (q *Queries) UpsertCategoryByName(ctx context.Context, arg UpsertCategoryByNameParams) (Category, error) {
+	row := q.db.QueryRowContext(ctx, upsertCategoryByName, arg.Name, arg.Color)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Color,
+		&i.CreatedAt,
+		&i.SortOrder,
+		&i.ParentID,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertTagByName = `-- name: UpsertTagByName :one
+INSERT INTO tags (name, color, explicit)
+VALUES (?, ?, 1)
+ON CONFLICT(name) DO UPDATE SET color = excluded.color, explicit = 1
+RETURNING id, name, color, explicit, updated_at
+`
+
+type UpsertTagByNameParams struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+func (q *Queries) UpsertTagByName(ctx context.Context, arg UpsertTagByNameParams) (Tag, error) {
+	row := q.db.QueryRowContext(ctx, upsertTagByName, arg.Name, arg.Color)
+	var i Tag
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Color,
+		&i.Explicit,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
@@ -638,24 +1784,27 @@ INSERT INTO time_entries (
     description,
     start_time,
     end_time,
-    category_id
+    category_id,
+    color
 ) VALUES (
-    ?, ?, ?, ?, ?
+    ?, ?, ?, ?, ?, ?
 )
 ON CONFLICT(id) DO UPDATE SET
     description = excluded.description,
     start_time = excluded.start_time,
     end_time = excluded.end_time,
-    category_id = excluded.category_id
-RETURNING id, description, start_time, end_time, created_at, category_id
+    category_id = excluded.category_id,
+    color = COALESCE(excluded.color, time_entries.color)
+RETURNING id, description, start_time, end_time, created_at, category_id, color, external_id, notes, billable, updated_at
 `
 
 type UpsertTimeEntryParams struct {
-	ID          int64         `json:"id"`
-	Description string        `json:"description"`
-	StartTime   time.Time     `json:"start_time"`
-	EndTime     sql.NullTime  `json:"end_time"`
-	CategoryID  sql.NullInt64 `json:"category_id"`
+	ID          int64          `json:"id"`
+	Description string         `json:"description"`
+	StartTime   time.Time      `json:"start_time"`
+	EndTime     sql.NullTime   `json:"end_time"`
+	CategoryID  sql.NullInt64  `json:"category_id"`
+	Color       sql.NullString `json:"color"`
 }
 
 func (q *Queries) UpsertTimeEntry(ctx context.Context, arg UpsertTimeEntryParams) (TimeEntry, error) {
@@ -665,6 +1814,7 @@ func (q *Queries) UpsertTimeEntry(ctx context.Context, arg UpsertTimeEntryParams
 		arg.StartTime,
 		arg.EndTime,
 		arg.CategoryID,
+		arg.Color,
 	)
 	var i TimeEntry
 	err := row.Scan(
@@ -674,6 +1824,66 @@ func (q *Queries) UpsertTimeEntry(ctx context.Context, arg UpsertTimeEntryParams
 		&i.EndTime,
 		&i.CreatedAt,
 		&i.CategoryID,
+		&i.Color,
+		&i.ExternalID,
+		&i.Notes,
+		&i.Billable,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertTimeEntryByExternalID = `-- name: UpsertTimeEntryByExternalID :one
+INSERT INTO time_entries (
+    external_id,
+    description,
+    start_time,
+    end_time,
+    category_id,
+    color
+) VALUES (
+    ?, ?, ?, ?, ?, ?
+)
+ON CONFLICT(external_id) DO UPDATE SET
+    description = excluded.description,
+    start_time = excluded.start_time,
+    end_time = excluded.end_time,
+    category_id = excluded.category_id,
+    color = COALESCE(excluded.color, time_entries.color)
+RETURNING id, description, start_time, end_time, created_at, category_id, color, external_id, notes, billable, updated_at
+`
+
+type UpsertTimeEntryByExternalIDParams struct {
+	ExternalID  sql.NullString `json:"external_id"`
+	Description string         `json:"description"`
+	StartTime   time.Time      `json:"start_time"`
+	EndTime     sql.NullTime   `json:"end_time"`
+	CategoryID  sql.NullInt64  `json:"category_id"`
+	Color       sql.NullString `json:"color"`
+}
+
+func (q *Queries) UpsertTimeEntryByExternalID(ctx context.Context, arg UpsertTimeEntryByExternalIDParams) (TimeEntry, error) {
+	row := q.db.QueryRowContext(ctx, upsertTimeEntryByExternalID,
+		arg.ExternalID,
+		arg.Description,
+		arg.StartTime,
+		arg.EndTime,
+		arg.CategoryID,
+		arg.Color,
+	)
+	var i TimeEntry
+	err := row.Scan(
+		&i.ID,
+		&i.Description,
+		&i.StartTime,
+		&i.EndTime,
+		&i.CreatedAt,
+		&i.CategoryID,
+		&i.Color,
+		&i.ExternalID,
+		&i.Notes,
+		&i.Billable,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
@@ -1,66 +1,214 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
 	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/service"
+	"github.com/alessandrocuzzocrea/precious-time-tracker/static"
 )
 
 type editData struct {
 	Entry      interface{} // Can be GetTimeEntryRow or database.TimeEntry
 	Categories []database.Category
 	Error      string
+	Input      *editInput // What the user submitted, shown instead of Entry's values
+}
+
+// editInput holds the raw values of a rejected edit form so they can be
+// shown again as entered.
+type editInput struct {
+	Description string
+	StartTime   string
+	EndTime     string
+	Duration    string
 }
 
 func (s *Server) routes() {
 	s.Router.HandleFunc("GET /", s.handleIndex)
+	s.Router.HandleFunc("GET /api/status", s.handleStatus)
+	s.Router.HandleFunc("GET /api/goals/progress", s.handleGoalProgress)
+	s.Router.HandleFunc("GET /api/entries", s.handleListEntriesJSON)
+	s.Router.HandleFunc("GET /api/openapi.json", s.handleOpenAPI)
 	s.Router.HandleFunc("POST /start", s.handleStartTimer)
 	s.Router.HandleFunc("POST /stop", s.handleStopTimer)
+	s.Router.HandleFunc("POST /focus/start", s.handleStartFocus)
+	s.Router.HandleFunc("POST /focus/end", s.handleEndFocus)
+	s.Router.HandleFunc("GET /focus/status", s.handleFocusStatus)
 	s.Router.HandleFunc("GET /entry/{id}", s.handleGetEntry)
 	s.Router.HandleFunc("GET /entry/{id}/edit", s.handleEditEntry)
+	s.Router.HandleFunc("GET /entry/{id}/history", s.handleEntryHistory)
 	s.Router.HandleFunc("GET /tags", s.handleListTags)
+	s.Router.HandleFunc("GET /tags/{name}/entries", s.handleTagEntries)
+	s.Router.HandleFunc("POST /tags/cleanup", s.handleCleanupTags)
+	s.Router.HandleFunc("POST /tags/normalize", s.handleNormalizeTags)
 	s.Router.HandleFunc("GET /categories", s.handleListCategories)
 	s.Router.HandleFunc("POST /categories", s.handleCreateCategory)
+	s.Router.HandleFunc("POST /categories/reorder", s.handleReorderCategories)
 	s.Router.HandleFunc("POST /categories/{id}", s.handleUpdateCategory)
 	s.Router.HandleFunc("DELETE /categories/{id}", s.handleDeleteCategory)
+	s.Router.HandleFunc("POST /categories/{id}/goal", s.handleSetCategoryGoal)
+	s.Router.HandleFunc("GET /settings", s.handleListSettings)
+	s.Router.HandleFunc("POST /settings", s.handleSetSetting)
+	s.Router.HandleFunc("DELETE /settings/{key}", s.handleDeleteSetting)
+	s.Router.HandleFunc("POST /settings/default-category", s.handleSetDefaultCategory)
 	s.Router.HandleFunc("GET /reports", s.handleReports)
+	s.Router.HandleFunc("GET /reports/views", s.handleReportViews)
+	s.Router.HandleFunc("GET /reports/annual", s.handleAnnualReport)
 	s.Router.HandleFunc("PUT /entry/{id}", s.handleUpdateEntry)
 	s.Router.HandleFunc("PATCH /entry/active", s.handleUpdateActiveEntry)
 	s.Router.HandleFunc("DELETE /entry/{id}", s.handleDeleteEntry)
+	s.Router.HandleFunc("POST /entries/bulk-category", s.handleBulkCategory)
 	s.Router.HandleFunc("GET /data", s.handleDataPage)
+	s.Router.HandleFunc("POST /data/reset", s.handleResetData)
 	s.Router.HandleFunc("GET /export", s.handleExportCSV)
+	s.Router.HandleFunc("GET /export/pivot", s.handleExportPivotCSV)
+	s.Router.HandleFunc("GET /export/timeseries.csv", s.handleExportTimeseriesCSV)
+	s.Router.HandleFunc("GET /export/taxonomy.json", s.handleExportTaxonomy)
+	s.Router.HandleFunc("GET /export.json", s.handleExportJSON)
 	s.Router.HandleFunc("POST /import", s.handleImportCSV)
 	s.Router.HandleFunc("POST /import/preview", s.handlePreviewCSV)
-	s.Router.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	s.Router.HandleFunc("POST /import/taxonomy", s.handleImportTaxonomy)
+	s.Router.HandleFunc("POST /import/json", s.handleImportJSON)
+	assets := staticHandler(static.FS)
+	s.Router.Handle("GET /static/", http.StripPrefix("/static/", assets))
+	s.Router.Handle("GET /favicon.ico", assets)
 }
 
-func formatDuration(start time.Time, end sql.NullTime) string {
+// runningMarker follows the elapsed time of an entry that is still running.
+const runningMarker = " (running)"
+
+// formatDuration formats the length of an entry. A running entry shows as
+// "Running", or, given now, as its elapsed time so far plus runningMarker.
+func formatDuration(start time.Time, end sql.NullTime, now ...time.Time) string {
 	if !end.Valid {
-		return "Running"
+		if len(now) == 0 {
+			return "Running"
+		}
+		return now[0].Sub(start).Round(time.Second).String() + runningMarker
 	}
 	d := end.Time.Sub(start)
 	return d.Round(time.Second).String()
 }
 
+// formatDurationSeconds formats seconds as "Xm Ys" under an hour, "Xh Ym"
+// under a day, and "Xd Yh Zm" from a day on, for multi-day spans such as a
+// forgotten timer.
 func formatDurationSeconds(seconds int64) string {
 	d := time.Duration(seconds) * time.Second
 	if d < time.Hour {
 		return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
 	}
-	return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dd %dh %dm", int(d.Hours())/24, int(d.Hours())%24, int(d.Minutes())%60)
+}
+
+// formatDurationCompact formats seconds as H:MM, or MM:SS under an hour, for
+// narrow displays. From a day on the days come first, as in "3d 04:10".
+func formatDurationCompact(seconds int64) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	if seconds < 3600 {
+		return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+	}
+	if seconds < 86400 {
+		return fmt.Sprintf("%d:%02d", seconds/3600, seconds%3600/60)
+	}
+	return fmt.Sprintf("%dd %02d:%02d", seconds/86400, seconds%86400/3600, seconds%3600/60)
+}
+
+// multiline escapes text for HTML and turns its line breaks into <br>, so
+// that multi-line descriptions keep their lines.
+func multiline(text string) template.HTML {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return template.HTML(strings.ReplaceAll(template.HTMLEscapeString(text), "\n", "<br>"))
+}
+
+// hexColorRegex matches the #RRGGBB colors tags and categories are saved with.
+var hexColorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// tagChips renders tags as badges in their own colors. Names are escaped,
+// and a color that is not #RRGGBB is replaced with grey so that it cannot
+// break out of the style attribute.
+func tagChips(tags []database.Tag) template.HTML {
+	chips := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		color := tag.Color
+		if !hexColorRegex.MatchString(color) {
+			color = "#cccccc"
+		}
+		chips = append(chips, fmt.Sprintf(`<span class="badge tag-chip" style="background-color: %s; color: %s">#%s</span>`,
+			color, textColor(color), template.HTMLEscapeString(tag.Name)))
+	}
+	return template.HTML(strings.Join(chips, " "))
+}
+
+// textColor returns black or white, whichever reads better on the #RRGGBB
+// background hex. Unparseable colors get black.
+func textColor(hex string) string {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b); err != nil || len(hex) != 7 {
+		return "#000000"
+	}
+	// Relative luminance as defined by WCAG 2
+	linear := func(c uint8) float64 {
+		v := float64(c) / 255
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	l := 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b)
+	// Above this luminance black text has the higher contrast ratio
+	if l > 0.179 {
+		return "#000000"
+	}
+	return "#ffffff"
 }
 
 func (s *Server) render(w http.ResponseWriter, r *http.Request, tmplName string, data interface{}, files ...string) {
+	// Read on first use, as most pages show no amounts
+	var currency *string
+	formatCurrency := func(cents int64) (string, error) {
+		if currency == nil {
+			c, err := s.Service.Currency(r.Context())
+			if err != nil {
+				return "", err
+			}
+			currency = &c
+		}
+		return service.FormatCurrency(cents, *currency), nil
+	}
+
 	funcs := template.FuncMap{
-		"duration":         formatDuration,
-		"duration_seconds": formatDurationSeconds,
+		"duration":               formatDuration,
+		"duration_seconds":       formatDurationSeconds,
+		"duration_compact":       formatDurationCompact,
+		"duration_decimal_hours": service.FormatDecimalHours,
+		"text_color":             textColor,
+		"multiline":              multiline,
+		"tag_chips":              tagChips,
+		"format_currency":        formatCurrency,
+		"now":                    s.Service.Now,
 	}
 
 	allFiles := append([]string{"templates/fragments.html"}, files...)
@@ -75,11 +223,6 @@ func (s *Server) render(w http.ResponseWriter, r *http.Request, tmplName string,
 	var finalData interface{}
 	if tmplName == "" {
 		// Full page render: ensure Active entry is available for the sticky bar
-		active, err := s.Service.GetActiveTimeEntry(r.Context())
-		if err != nil && err != sql.ErrNoRows {
-			log.Printf("Error getting active entry for render: %v", err)
-		}
-
 		m := make(map[string]interface{})
 		if data != nil {
 			if existingMap, ok := data.(map[string]interface{}); ok {
@@ -88,12 +231,7 @@ func (s *Server) render(w http.ResponseWriter, r *http.Request, tmplName string,
 				m["PageData"] = data
 			}
 		}
-
-		if err == nil {
-			m["Active"] = active
-		} else {
-			m["Active"] = nil
-		}
+		s.addStickyBarData(r, m)
 		finalData = m
 	} else {
 		finalData = data
@@ -110,261 +248,939 @@ func (s *Server) render(w http.ResponseWriter, r *http.Request, tmplName string,
 	}
 }
 
+// addStickyBarData fills in the data the sticky timer bar needs.
+func (s *Server) addStickyBarData(r *http.Request, m map[string]interface{}) {
+	active, err := s.Service.GetActiveTimeEntry(r.Context())
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error getting active entry for render: %v", err)
+	}
+	if err == nil {
+		m["Active"] = active
+	} else {
+		m["Active"] = nil
+	}
+
+	// Timers running besides the primary one, with multiple timers enabled
+	var others []database.ListActiveTimeEntriesRow
+	if err == nil && s.Service.MultipleTimers() {
+		actives, err := s.Service.ListActiveTimeEntries(r.Context())
+		if err != nil {
+			log.Printf("Error listing active entries for render: %v", err)
+		}
+		for _, a := range actives {
+			if a.ID != active.ID {
+				others = append(others, a)
+			}
+		}
+	}
+	m["OtherActive"] = others
+	m["MultipleTimers"] = s.Service.MultipleTimers()
+
+	// Preselect the default category in the start form
+	var defaultCatID int64
+	if id, err := s.Service.DefaultCategoryID(r.Context()); err != nil {
+		log.Printf("Error getting default category for render: %v", err)
+	} else if id != nil {
+		defaultCatID = *id
+	}
+	m["DefaultCategoryID"] = defaultCatID
+	m["ReadOnly"] = s.readOnly
+}
+
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
-	entries, err := s.Service.ListTimeEntries(r.Context())
+	data := s.indexData(r)
+
+	start, end := s.Service.ReportPeriod("today")
+	today, err := s.Service.GetReport(r.Context(), service.ReportFilter{
+		StartDate:      start,
+		EndDate:        end,
+		IncludeRunning: true,
+	})
 	if err != nil {
-		log.Printf("Error listing entries: %v", err)
-		entries = []database.ListTimeEntriesRow{}
+		log.Printf("Error getting today's total: %v", err)
 	}
-	categories, err := s.Service.ListCategories(r.Context())
+	data["TodayTotalSeconds"] = today.TotalSeconds
+
+	last7, err := s.Service.RecentDailyTotals(r.Context(), 7)
 	if err != nil {
-		log.Printf("Error listing categories: %v", err)
-		categories = []database.Category{}
+		log.Printf("Error getting the last 7 days: %v", err)
+		last7 = make([]int64, 7)
 	}
+	// The chart is scaled to the busiest day
+	last7Max := int64(1)
+	for _, seconds := range last7 {
+		last7Max = max(last7Max, seconds)
+	}
+	data["Last7Days"] = last7
+	data["Last7DaysMax"] = last7Max
 
-	data := map[string]interface{}{
-		"Entries":    entries,
-		"Categories": categories,
+	uncategorized, err := s.Service.CountUncategorizedTimeEntries(r.Context())
+	if err != nil {
+		log.Printf("Error counting uncategorized entries: %v", err)
 	}
-	// Active will be filled by render if tmplName is ""
+	data["UncategorizedCount"] = uncategorized
 
 	s.render(w, r, "", data, "templates/base.html", "templates/index.html")
 }
 
-func (s *Server) handleStartTimer(w http.ResponseWriter, r *http.Request) {
-	description := r.FormValue("description")
-	catIDStr := r.FormValue("category_id")
-	var catID *int64
-	if catIDStr != "" {
-		if id, err := strconv.ParseInt(catIDStr, 10, 64); err == nil {
-			catID = &id
+// indexData collects the entry list and form options shown on the index page.
+// With ?uncategorized=1 only entries without a category are listed.
+func (s *Server) indexData(r *http.Request) map[string]interface{} {
+	uncategorizedOnly := r.URL.Query().Get("uncategorized") == "1"
+	var entries interface{}
+	if uncategorizedOnly {
+		list, err := s.Service.ListUncategorizedTimeEntries(r.Context())
+		if err != nil {
+			log.Printf("Error listing uncategorized entries: %v", err)
+			list = []database.ListUncategorizedTimeEntriesRow{}
+		}
+		entries = list
+	} else {
+		list, err := s.Service.ListTimeEntries(r.Context())
+		if err != nil {
+			log.Printf("Error listing entries: %v", err)
+			list = []database.ListTimeEntriesRow{}
 		}
+		entries = list
+	}
+	categories, err := s.Service.ListCategories(r.Context())
+	if err != nil {
+		log.Printf("Error listing categories: %v", err)
+		categories = []database.Category{}
 	}
 
-	_, err := s.Service.StartTimer(r.Context(), description, catID)
+	tags, err := s.Service.ListTags(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to start timer: "+err.Error(), http.StatusInternalServerError)
-		return
+		log.Printf("Error listing tags: %v", err)
+		tags = []database.Tag{}
 	}
 
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return map[string]interface{}{
+		"Entries":           entries,
+		"UncategorizedOnly": uncategorizedOnly,
+		"Categories":        categories,
+		"Tags":              tags,
+	}
 }
 
-func (s *Server) handleUpdateActiveEntry(w http.ResponseWriter, r *http.Request) {
+// statusResponse is the body of GET /api/status.
+type statusResponse struct {
+	Running           bool   `json:"running"`
+	Description       string `json:"description,omitempty"`
+	ElapsedSeconds    int64  `json:"elapsed_seconds"`
+	TodayTotalSeconds int64  `json:"today_total_seconds"`
+	EntryCount        int64  `json:"entry_count"`
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	var status statusResponse
+
 	active, err := s.Service.GetActiveTimeEntry(r.Context())
-	if err != nil {
+	if err == nil {
+		status.Running = true
+		status.Description = active.Description
+		status.ElapsedSeconds = int64(s.Service.Now().Sub(active.StartTime).Seconds())
+	} else if err != sql.ErrNoRows {
 		log.Printf("Error getting active entry: %v", err)
-		http.Error(w, "No active entry", http.StatusNotFound)
+		http.Error(w, "Failed to get status", errorStatus(err))
 		return
 	}
 
-	description := r.FormValue("description")
-	categoryIDStr := r.FormValue("category_id")
-
-	var categoryID *int64
-	if categoryIDStr != "" {
-		id, err := strconv.ParseInt(categoryIDStr, 10, 64)
-		if err == nil {
-			categoryID = &id
-		}
+	start, end := s.Service.ReportPeriod("today")
+	today, err := s.Service.GetReport(r.Context(), service.ReportFilter{
+		StartDate:      start,
+		EndDate:        end,
+		IncludeRunning: true,
+	})
+	if err != nil {
+		log.Printf("Error getting today's total: %v", err)
+		http.Error(w, "Failed to get status", errorStatus(err))
+		return
 	}
+	status.TodayTotalSeconds = today.TotalSeconds
 
-	_, err = s.Service.UpdateTimeEntry(r.Context(), active.ID, description, active.StartTime, active.EndTime, categoryID)
+	status.EntryCount, err = s.Service.CountTimeEntries(r.Context())
 	if err != nil {
-		log.Printf("Error updating active entry: %v", err)
-		http.Error(w, "Failed to update", http.StatusInternalServerError)
+		log.Printf("Error counting entries: %v", err)
+		http.Error(w, "Failed to get status", errorStatus(err))
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Printf("Status write error: %v", err)
+	}
 }
 
-func (s *Server) handleStopTimer(w http.ResponseWriter, r *http.Request) {
-	if err := s.Service.StopTimer(r.Context()); err != nil {
-		http.Error(w, "Failed to stop timer: "+err.Error(), http.StatusInternalServerError)
+// handleGoalProgress returns, as JSON, each category's goal for the period
+// ("week" unless given) next to the time tracked in it. Categories without
+// a goal are omitted.
+func (s *Server) handleGoalProgress(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+
+	progress, err := s.Service.GetGoalProgress(r.Context(), period)
+	if err != nil {
+		http.Error(w, "Failed to get goal progress: "+err.Error(), errorStatus(err))
 		return
 	}
 
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(progress); err != nil {
+		log.Printf("Goal progress write error: %v", err)
+	}
 }
 
-func (s *Server) handleGetEntry(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+// handleListEntriesJSON returns one page of entries as JSON. The total number
+// of entries is sent in the X-Total-Count header for client pagination. A
+// request whose If-None-Match holds the ETag of the current entries gets 304,
+// so pollers can check for changes cheaply.
+func (s *Server) handleListEntriesJSON(w http.ResponseWriter, r *http.Request) {
+	// A bad query is an error even when the entries have not changed
+	q := r.URL.Query()
+	var limit, offset int
+	for name, dst := range map[string]*int{"limit": &limit, "offset": &offset} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid "+name, http.StatusBadRequest)
+			return
+		}
+		*dst = n
+	}
+	if err := service.CheckEntriesPage(q.Get("sort"), offset); err != nil {
+		http.Error(w, "Failed to list entries: "+err.Error(), errorStatus(err))
 		return
 	}
 
-	entry, err := s.Service.GetTimeEntry(r.Context(), id)
+	version, lastModified, err := s.Service.EntriesVersion(r.Context())
 	if err != nil {
-		http.Error(w, "Entry not found", http.StatusNotFound)
+		log.Printf("Error getting entries version: %v", err)
+		http.Error(w, "Failed to list entries", errorStatus(err))
 		return
 	}
-
-	s.render(w, r, "entry-row", entry)
-}
-
-func (s *Server) handleEditEntry(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+	// Weak, as the same entries could be encoded differently
+	etag := `W/"` + version + `"`
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	// If-Modified-Since is not honored: whole seconds would miss changes
+	// made within the same second
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	entry, err := s.Service.GetTimeEntry(r.Context(), id)
+	entries, total, err := s.Service.ListEntriesPage(r.Context(), q.Get("sort"), limit, offset)
 	if err != nil {
-		http.Error(w, "Entry not found", http.StatusNotFound)
+		http.Error(w, "Failed to list entries: "+err.Error(), errorStatus(err))
 		return
 	}
 
-	categories, _ := s.Service.ListCategories(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		log.Printf("Entries write error: %v", err)
+	}
+}
 
-	s.render(w, r, "edit-entry-row", editData{Entry: entry, Categories: categories})
+// etagMatches reports whether the If-None-Match header value lists etag,
+// using the weak comparison of RFC 9110.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
 }
 
-func (s *Server) handleUpdateEntry(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+// respondTimerChange answers a start/stop request. HTMX clients get the
+// refreshed sticky bar plus an out-of-band entry list; others are redirected.
+func (s *Server) respondTimerChange(w http.ResponseWriter, r *http.Request, event string) {
+	if r.Header.Get("HX-Request") != "true" {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
+	data := s.indexData(r)
+	s.addStickyBarData(r, data)
+	w.Header().Set("HX-Trigger", event)
+	s.render(w, r, "timer-update", data)
+}
+
+func (s *Server) handleStartTimer(w http.ResponseWriter, r *http.Request) {
 	description := r.FormValue("description")
-	if description == "" {
-		http.Error(w, "Description required", http.StatusBadRequest)
-		return
+	catIDStr := r.FormValue("category_id")
+	var catID *int64
+	if catIDStr != "" {
+		if id, err := strconv.ParseInt(catIDStr, 10, 64); err == nil {
+			catID = &id
+		}
 	}
 
-	// Helper for parsing flexible time formats
-	parseTime := func(value string) (time.Time, error) {
-		layouts := []string{
-			"2006-01-02T15:04:05",
-			"2006-01-02 15:04:05",
-			"2006-01-02T15:04",
-			"2006-01-02 15:04",
-		}
-		for _, layout := range layouts {
-			if t, err := time.Parse(layout, value); err == nil {
-				return t, nil
+	var tagIDs []int64
+	for _, key := range []string{"tag_ids", "tag_ids[]"} {
+		for _, idStr := range r.Form[key] {
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+				return
 			}
+			tagIDs = append(tagIDs, id)
 		}
-		return time.Time{}, fmt.Errorf("invalid format")
 	}
 
-	// Fetch original entry to use as fallback/template
-	originalEntry, err := s.Service.GetTimeEntry(r.Context(), id)
-	if err != nil {
-		http.Error(w, "Entry not found", http.StatusNotFound)
-		return
+	// start_offset_minutes backdates the start, for work begun a bit ago
+	start := s.Service.Now()
+	if v := r.FormValue("start_offset_minutes"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes < 0 {
+			http.Error(w, "Invalid start offset: expected a non-negative number of minutes", http.StatusBadRequest)
+			return
+		}
+		start = start.Add(-time.Duration(minutes) * time.Minute)
 	}
 
-	startTimeStr := r.FormValue("start_time")
-	startTime, err := parseTime(startTimeStr)
+	_, err := s.Service.StartTimerAt(r.Context(), start, description, catID, tagIDs...)
 	if err != nil {
-		s.render(w, r, "edit-entry-row", editData{Entry: originalEntry, Error: "Invalid start time format"})
+		http.Error(w, "Failed to start timer: "+err.Error(), errorStatus(err))
 		return
 	}
 
-	endTimeStr := r.FormValue("end_time")
-	var endTime sql.NullTime
-	if endTimeStr != "" {
-		et, err := parseTime(endTimeStr)
+	s.respondTimerChange(w, r, "timerStarted")
+}
+
+func (s *Server) handleUpdateActiveEntry(w http.ResponseWriter, r *http.Request) {
+	// The sticky bar posts the ID of the timer it shows; without one the
+	// edit applies to whichever timer is running
+	var entryID int64
+	if v := r.FormValue("entry_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
-			s.render(w, r, "edit-entry-row", editData{Entry: originalEntry, Error: "Invalid end time format"})
+			http.Error(w, "Invalid entry ID", http.StatusBadRequest)
 			return
 		}
-		if !et.After(startTime) {
-			unsavedEntry := originalEntry
-			unsavedEntry.Description = description
-			unsavedEntry.StartTime = startTime
-			unsavedEntry.EndTime = sql.NullTime{Time: et, Valid: true}
-			s.render(w, r, "edit-entry-row", editData{Entry: unsavedEntry, Error: "End time must be after start time"})
-			return
+		entryID = id
+	}
+
+	description := r.FormValue("description")
+	categoryIDStr := r.FormValue("category_id")
+
+	var categoryID *int64
+	if categoryIDStr != "" {
+		id, err := strconv.ParseInt(categoryIDStr, 10, 64)
+		if err == nil {
+			categoryID = &id
 		}
-		endTime = sql.NullTime{Time: et, Valid: true}
 	}
 
-	catIDStr := r.FormValue("category_id")
-	var catID *int64
-	if catIDStr != "" {
-		if cid, err := strconv.ParseInt(catIDStr, 10, 64); err == nil {
-			catID = &cid
+	// start_time corrects when the timer started; it is read like stop_time
+	var start time.Time
+	if v := r.FormValue("start_time"); v != "" {
+		t, err := parseStopTime(v, s.Service.Now())
+		if err != nil {
+			http.Error(w, "Invalid start time", http.StatusBadRequest)
+			return
 		}
+		start = t
 	}
 
-	entry, err := s.Service.UpdateTimeEntry(r.Context(), id, description, startTime, endTime, catID)
-	if err != nil {
-		categories, _ := s.Service.ListCategories(r.Context())
-		s.render(w, r, "edit-entry-row", editData{Entry: originalEntry, Categories: categories, Error: "Failed to update: " + err.Error()})
+	_, err := s.Service.UpdateActiveEntry(r.Context(), entryID, description, categoryID, start)
+	if errors.Is(err, service.ErrNotFound) {
+		http.Error(w, "No active entry", http.StatusNotFound)
 		return
 	}
-
-	s.render(w, r, "entry-row", entry)
-}
-
-func (s *Server) handleDeleteEntry(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+	if errors.Is(err, service.ErrActiveEntryChanged) {
+		http.Error(w, "The timer was stopped or replaced, reload to edit the current one", http.StatusConflict)
 		return
 	}
-
-	if err := s.Service.DeleteTimeEntry(r.Context(), id); err != nil {
-		http.Error(w, "Failed to delete entry", http.StatusInternalServerError)
+	if errors.Is(err, service.ErrValidation) {
+		http.Error(w, "Failed to update: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating active entry: %v", err)
+		http.Error(w, "Failed to update", errorStatus(err))
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
-func (s *Server) handleListTags(w http.ResponseWriter, r *http.Request) {
-	tags, err := s.Service.ListTags(r.Context())
-	if err != nil {
-		log.Printf("Error listing tags: %v", err)
-		http.Error(w, "Failed to list tags", http.StatusInternalServerError)
-		return
+func (s *Server) handleStopTimer(w http.ResponseWriter, r *http.Request) {
+	end := s.Service.Now()
+	if v := r.FormValue("stop_time"); v != "" {
+		t, err := parseStopTime(v, end)
+		if err != nil {
+			http.Error(w, "Invalid stop time", http.StatusBadRequest)
+			return
+		}
+		end = t
 	}
 
-	data := map[string]interface{}{
-		"Tags": tags,
+	// Without an id the primary timer is stopped
+	var id *int64
+	if v := r.FormValue("id"); v != "" {
+		entryID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+		id = &entryID
 	}
 
-	s.render(w, r, "", data, "templates/base.html", "templates/tags.html")
+	if err := s.Service.StopTimerAt(r.Context(), id, end); err != nil {
+		http.Error(w, "Failed to stop timer: "+err.Error(), errorStatus(err))
+		return
+	}
+
+	s.respondTimerChange(w, r, "timerStopped")
 }
 
-func (s *Server) handleListCategories(w http.ResponseWriter, r *http.Request) {
-	categories, err := s.Service.ListCategories(r.Context())
-	if err != nil {
-		log.Printf("Error listing categories: %v", err)
-		http.Error(w, "Failed to list categories", http.StatusInternalServerError)
-		return
+// handleStartFocus starts a timer as a focus session, planned to last
+// planned_minutes when given.
+func (s *Server) handleStartFocus(w http.ResponseWriter, r *http.Request) {
+	var catID *int64
+	if v := r.FormValue("category_id"); v != "" {
+		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+			catID = &id
+		}
 	}
 
-	data := map[string]interface{}{
-		"Categories": categories,
+	var planned time.Duration
+	if v := r.FormValue("planned_minutes"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes < 0 {
+			http.Error(w, "Invalid planned duration: expected a non-negative number of minutes", http.StatusBadRequest)
+			return
+		}
+		planned = time.Duration(minutes) * time.Minute
 	}
 
-	s.render(w, r, "", data, "templates/base.html", "templates/categories.html")
+	if _, err := s.Service.StartFocus(r.Context(), r.FormValue("description"), catID, planned); err != nil {
+		http.Error(w, "Failed to start focus session: "+err.Error(), errorStatus(err))
+		return
+	}
+
+	s.respondTimerChange(w, r, "timerStarted")
 }
 
-func (s *Server) handleCreateCategory(w http.ResponseWriter, r *http.Request) {
-	name := r.FormValue("name")
-	color := r.FormValue("color")
-	if color == "" {
-		color = "#cccccc"
+// handleEndFocus stops the timer of the running focus session.
+func (s *Server) handleEndFocus(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.Service.EndFocus(r.Context()); err != nil {
+		http.Error(w, "Failed to end focus session: "+err.Error(), errorStatus(err))
+		return
 	}
 
-	_, err := s.Service.CreateCategory(r.Context(), name, color)
+	s.respondTimerChange(w, r, "timerStopped")
+}
+
+// handleFocusStatus returns, as JSON, whether a focus session is running and
+// how much of its planned time is left.
+func (s *Server) handleFocusStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := s.Service.GetFocusStatus(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to create category: "+err.Error(), http.StatusInternalServerError)
+		log.Printf("Error getting focus status: %v", err)
+		http.Error(w, "Failed to get focus status", errorStatus(err))
 		return
 	}
 
-	http.Redirect(w, r, "/categories", http.StatusSeeOther)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Printf("Focus status write error: %v", err)
+	}
+}
+
+// parseStopTime parses the stop_time field, and the start_time of a running
+// timer: a full date and time, or a bare time of day at its most recent
+// occurrence up to now, so that 23:30 just after midnight means last night.
+func parseStopTime(value string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, now.Location())
+			if at.After(now) {
+				at = at.AddDate(0, 0, -1)
+			}
+			return at, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid stop time %q", value)
+}
+
+// handleGetEntry renders an entry row, or returns the entry as JSON for
+// /entry/{id}.json. The mux cannot match a suffix after a wildcard, so the
+// extension arrives as part of the id.
+func (s *Server) handleGetEntry(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	if v, ok := strings.CutSuffix(idStr, ".json"); ok {
+		s.handleGetEntryJSON(w, r, v)
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := s.Service.GetTimeEntry(r.Context(), id)
+	if err != nil {
+		entryError(w, err)
+		return
+	}
+
+	s.render(w, r, "entry-row", entry)
+}
+
+func (s *Server) handleGetEntryJSON(w http.ResponseWriter, r *http.Request, idStr string) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := s.Service.GetEntryJSON(r.Context(), id)
+	if err != nil {
+		entryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entry); err != nil {
+		log.Printf("Entry write error: %v", err)
+	}
+}
+
+// handleBulkCategory assigns category_id to every entry in entry_ids[];
+// -1 clears the category. HTMX clients get the updated rows as out-of-band
+// swaps.
+func (s *Server) handleBulkCategory(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	values := append(r.Form["entry_ids[]"], r.Form["entry_ids"]...)
+	if len(values) == 0 {
+		http.Error(w, "No entries selected", http.StatusBadRequest)
+		return
+	}
+	ids := make([]int64, 0, len(values))
+	for _, v := range values {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid entry ID", http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	catID, err := strconv.ParseInt(r.FormValue("category_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid category", http.StatusBadRequest)
+		return
+	}
+	var categoryID *int64
+	if catID != -1 {
+		categoryID = &catID
+	}
+
+	entries, err := s.Service.SetEntriesCategory(r.Context(), ids, categoryID)
+	if errors.Is(err, service.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update entries: "+err.Error(), errorStatus(err))
+		return
+	}
+
+	if r.Header.Get("HX-Request") != "true" {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	s.render(w, r, "entry-rows-oob", entries)
+}
+
+func (s *Server) handleEntryHistory(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	history, err := s.Service.EntryHistory(r.Context(), id)
+	if err != nil {
+		log.Printf("Error listing entry history: %v", err)
+		http.Error(w, "Failed to load history", errorStatus(err))
+		return
+	}
+	if len(history) == 0 {
+		// Entries older than the history table have none yet
+		if _, err := s.Service.GetTimeEntry(r.Context(), id); err != nil {
+			entryError(w, err)
+			return
+		}
+	}
+
+	data := map[string]interface{}{
+		"EntryID": id,
+		"History": history,
+	}
+	s.render(w, r, "", data, "templates/base.html", "templates/history.html")
+}
+
+func (s *Server) handleEditEntry(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := s.Service.GetTimeEntry(r.Context(), id)
+	if err != nil {
+		entryError(w, err)
+		return
+	}
+
+	categories, _ := s.Service.ListCategories(r.Context())
+
+	s.render(w, r, "edit-entry-row", editData{Entry: entry, Categories: categories})
+}
+
+func (s *Server) handleUpdateEntry(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	// Helper for parsing flexible time formats, Unix timestamps included
+	parseTime := func(value string) (time.Time, error) {
+		if t, ok := service.ParseUnixTime(value, s.Service.Location()); ok {
+			return t, nil
+		}
+		layouts := []string{
+			"2006-01-02T15:04:05",
+			"2006-01-02 15:04:05",
+			"2006-01-02T15:04",
+			"2006-01-02 15:04",
+		}
+		for _, layout := range layouts {
+			if t, err := time.ParseInLocation(layout, value, s.Service.Location()); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid format")
+	}
+
+	// Fetch original entry to use as fallback/template
+	originalEntry, err := s.Service.GetTimeEntry(r.Context(), id)
+	if err != nil {
+		entryError(w, err)
+		return
+	}
+
+	description := r.FormValue("description")
+	startTimeStr := r.FormValue("start_time")
+	endTimeStr := r.FormValue("end_time")
+	durationStr := r.FormValue("duration")
+	input := &editInput{Description: description, StartTime: startTimeStr, EndTime: endTimeStr, Duration: durationStr}
+
+	if description == "" {
+		s.renderEditError(w, r, originalEntry, input, "Description is required")
+		return
+	}
+
+	if startTimeStr == "" {
+		s.renderEditError(w, r, originalEntry, input, "Start time is required")
+		return
+	}
+	startTime, err := parseTime(startTimeStr)
+	if err != nil {
+		s.renderEditError(w, r, originalEntry, input, fmt.Sprintf("Invalid start time %q: expected YYYY-MM-DD HH:MM[:SS] or Unix seconds", startTimeStr))
+		return
+	}
+
+	var endTime sql.NullTime
+	if endTimeStr != "" {
+		et, err := parseTime(endTimeStr)
+		if err != nil {
+			s.renderEditError(w, r, originalEntry, input, fmt.Sprintf("Invalid end time %q: expected YYYY-MM-DD HH:MM[:SS] or Unix seconds", endTimeStr))
+			return
+		}
+		if !et.After(startTime) {
+			s.renderEditError(w, r, originalEntry, input, "End time must be after start time")
+			return
+		}
+		endTime = sql.NullTime{Time: et, Valid: true}
+	} else if durationStr != "" {
+		// Without an end time, a duration such as 1h30m sets it
+		d, err := time.ParseDuration(durationStr)
+		if err != nil {
+			s.renderEditError(w, r, originalEntry, input, fmt.Sprintf("Invalid duration %q: expected e.g. 1h30m", durationStr))
+			return
+		}
+		if d <= 0 {
+			s.renderEditError(w, r, originalEntry, input, "Duration must be positive")
+			return
+		}
+		endTime = sql.NullTime{Time: startTime.Add(d), Valid: true}
+	}
+
+	// category_id=-1 (the "No Category" option) clears the category; leaving
+	// the field out keeps the current one.
+	var catID *int64
+	if _, ok := r.Form["category_id"]; !ok {
+		if originalEntry.CategoryID.Valid {
+			catID = &originalEntry.CategoryID.Int64
+		}
+	} else if catIDStr := r.FormValue("category_id"); catIDStr != "" && catIDStr != "-1" {
+		cid, err := strconv.ParseInt(catIDStr, 10, 64)
+		if err != nil {
+			s.renderEditError(w, r, originalEntry, input, fmt.Sprintf("Invalid category %q", catIDStr))
+			return
+		}
+		catID = &cid
+	}
+
+	// Unchecked override means "inherit from category"
+	var color string
+	if r.FormValue("color_override") != "" {
+		color = r.FormValue("color")
+	}
+	changes := []service.EntryChange{service.WithEntryColor(color)}
+
+	// The form sends billable=0 ahead of the checkbox, so the last value
+	// wins; leaving the field out keeps the current flag.
+	if values, ok := r.Form["billable"]; ok {
+		changes = append(changes, service.WithEntryBillable(values[len(values)-1] == "1"))
+	}
+
+	entry, err := s.Service.UpdateTimeEntry(r.Context(), id, description, startTime, endTime, catID, changes...)
+	if errors.Is(err, service.ErrNotFound) {
+		entryError(w, err)
+		return
+	}
+	if errors.Is(err, service.ErrValidation) {
+		msg := "End time must be after start time"
+		if errors.Is(err, service.ErrInvalidColor) {
+			msg = err.Error()
+		}
+		s.renderEditError(w, r, originalEntry, input, msg)
+		return
+	}
+	if err != nil {
+		categories, _ := s.Service.ListCategories(r.Context())
+		s.render(w, r, "edit-entry-row", editData{Entry: originalEntry, Categories: categories, Error: "Failed to update: " + err.Error()})
+		return
+	}
+
+	s.render(w, r, "entry-row", entry)
+}
+
+// entryError reports a failure to load or change an entry.
+func entryError(w http.ResponseWriter, err error) {
+	status := errorStatus(err)
+	if status == http.StatusNotFound {
+		http.Error(w, "Entry not found", status)
+		return
+	}
+	log.Printf("Error loading entry: %v", err)
+	http.Error(w, "Failed to load entry", status)
+}
+
+// renderEditError re-renders the edit row for entry with a 400, keeping the
+// values the user entered.
+func (s *Server) renderEditError(w http.ResponseWriter, r *http.Request, entry database.GetTimeEntryRow, input *editInput, message string) {
+	categories, err := s.Service.ListCategories(r.Context())
+	if err != nil {
+		log.Printf("Error listing categories: %v", err)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusBadRequest)
+	s.render(w, r, "edit-entry-row", editData{Entry: entry, Categories: categories, Error: message, Input: input})
+}
+
+func (s *Server) handleDeleteEntry(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Service.DeleteTimeEntry(r.Context(), id); err != nil {
+		http.Error(w, "Failed to delete entry", errorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := s.Service.ListTags(r.Context())
+	if err != nil {
+		log.Printf("Error listing tags: %v", err)
+		http.Error(w, "Failed to list tags", errorStatus(err))
+		return
+	}
+
+	data := map[string]interface{}{
+		"Tags": tags,
+	}
+	if removed := r.URL.Query().Get("removed"); removed != "" {
+		data["Removed"] = removed
+	}
+	if merged := r.URL.Query().Get("merged"); merged != "" {
+		data["Merged"] = merged
+	}
+
+	s.render(w, r, "", data, "templates/base.html", "templates/tags.html")
+}
+
+// handleTagEntries lists the entries with the tag in the path.
+func (s *Server) handleTagEntries(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	entries, err := s.Service.ListEntriesByTag(r.Context(), name)
+	if err != nil {
+		log.Printf("Error listing entries for tag %q: %v", name, err)
+		http.Error(w, "Failed to list entries", errorStatus(err))
+		return
+	}
+
+	// An unknown tag simply has no entries and no related tags
+	var related []database.ListTagCooccurrencesRow
+	tag, err := s.Service.GetTagByName(r.Context(), name)
+	if err == nil {
+		related, err = s.Service.TagCooccurrence(r.Context(), tag.ID)
+	}
+	if err != nil && !errors.Is(err, service.ErrNotFound) {
+		log.Printf("Error finding tags used with %q: %v", name, err)
+		http.Error(w, "Failed to list entries", errorStatus(err))
+		return
+	}
+
+	data := map[string]interface{}{
+		"Tag":     strings.ToLower(strings.TrimPrefix(name, "#")),
+		"Entries": entries,
+		"Related": related,
+	}
+	s.render(w, r, "", data, "templates/base.html", "templates/tag_entries.html")
+}
+
+func (s *Server) handleCleanupTags(w http.ResponseWriter, r *http.Request) {
+	removed, err := s.Service.CleanupOrphanedTags(r.Context())
+	if err != nil {
+		log.Printf("Error cleaning up tags: %v", err)
+		http.Error(w, "Failed to clean up tags", errorStatus(err))
+		return
+	}
+	http.Redirect(w, r, "/tags?removed="+strconv.Itoa(removed), http.StatusSeeOther)
+}
+
+func (s *Server) handleNormalizeTags(w http.ResponseWriter, r *http.Request) {
+	merged, err := s.Service.NormalizeTags(r.Context())
+	if err != nil {
+		log.Printf("Error normalizing tags: %v", err)
+		http.Error(w, "Failed to merge tags", errorStatus(err))
+		return
+	}
+	http.Redirect(w, r, "/tags?merged="+strconv.Itoa(merged), http.StatusSeeOther)
+}
+
+func (s *Server) handleListCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := s.Service.ListCategories(r.Context())
+	if err != nil {
+		log.Printf("Error listing categories: %v", err)
+		http.Error(w, "Failed to list categories", errorStatus(err))
+		return
+	}
+
+	goals, err := s.Service.ListGoals(r.Context(), "week")
+	if err != nil {
+		log.Printf("Error listing goals: %v", err)
+		http.Error(w, "Failed to list categories", errorStatus(err))
+		return
+	}
+	weeklyGoals := make(map[int64]float64, len(goals))
+	for _, g := range goals {
+		weeklyGoals[g.CategoryID] = float64(g.TargetSeconds) / 3600
+	}
+
+	data := map[string]interface{}{
+		"Categories":  categories,
+		"WeeklyGoals": weeklyGoals,
+	}
+
+	s.render(w, r, "", data, "templates/base.html", "templates/categories.html")
+}
+
+func (s *Server) handleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	color := r.FormValue("color")
+	if color == "" {
+		color = "#cccccc"
+	}
+
+	_, err := s.Service.CreateCategory(r.Context(), name, color)
+	if errors.Is(err, service.ErrValidation) {
+		http.Error(w, "Failed to create category: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create category: "+err.Error(), errorStatus(err))
+		return
+	}
+
+	http.Redirect(w, r, "/categories", http.StatusSeeOther)
+}
+
+// handleReorderCategories sets the category order from the repeated id
+// field, first to last.
+func (s *Server) handleReorderCategories(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	ids := make([]int64, 0, len(r.Form["id"]))
+	for _, v := range r.Form["id"] {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	err := s.Service.ReorderCategories(r.Context(), ids)
+	if errors.Is(err, service.ErrNotFound) {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to reorder categories: "+err.Error(), errorStatus(err))
+		return
+	}
+
+	http.Redirect(w, r, "/categories", http.StatusSeeOther)
 }
 
 func (s *Server) handleUpdateCategory(w http.ResponseWriter, r *http.Request) {
@@ -375,50 +1191,270 @@ func (s *Server) handleUpdateCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	name := r.FormValue("name")
-	color := r.FormValue("color")
+	name := r.FormValue("name")
+	color := r.FormValue("color")
+
+	// An empty parent makes the category top-level
+	var parentID *int64
+	if v := r.FormValue("parent_id"); v != "" {
+		pid, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid parent category", http.StatusBadRequest)
+			return
+		}
+		parentID = &pid
+	}
+
+	_, err = s.Service.UpdateCategory(r.Context(), id, name, color, parentID)
+	if errors.Is(err, service.ErrNotFound) {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, service.ErrValidation) {
+		http.Error(w, "Failed to update category: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update category: "+err.Error(), errorStatus(err))
+		return
+	}
+
+	http.Redirect(w, r, "/categories", http.StatusSeeOther)
+}
+
+// handleSetCategoryGoal sets the target hours of a category for a period,
+// "week" unless given. An empty or zero target removes the goal.
+func (s *Server) handleSetCategoryGoal(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	period := r.FormValue("period")
+	if period == "" {
+		period = "week"
+	}
+	var hours float64
+	if v := r.FormValue("target_hours"); v != "" {
+		hours, err = strconv.ParseFloat(v, 64)
+		if err != nil || hours < 0 {
+			http.Error(w, "Invalid target hours", http.StatusBadRequest)
+			return
+		}
+	}
 
-	_, err = s.Service.UpdateCategory(r.Context(), id, name, color)
+	err = s.Service.SetGoal(r.Context(), id, period, time.Duration(hours*float64(time.Hour)))
 	if err != nil {
-		http.Error(w, "Failed to update category: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to save goal: "+err.Error(), errorStatus(err))
 		return
 	}
 
+	if r.Header.Get("HX-Request") == "true" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 	http.Redirect(w, r, "/categories", http.StatusSeeOther)
 }
 
-func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
-	period := r.URL.Query().Get("period")
-	if period == "" {
-		period = "today"
+func (s *Server) handleListSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := s.Service.ListSettings(r.Context())
+	if err != nil {
+		log.Printf("Error listing settings: %v", err)
+		http.Error(w, "Failed to list settings", errorStatus(err))
+		return
+	}
+
+	data := map[string]interface{}{
+		"Settings": settings,
+	}
+
+	s.render(w, r, "", data, "templates/base.html", "templates/settings.html")
+}
+
+func (s *Server) handleSetSetting(w http.ResponseWriter, r *http.Request) {
+	key := r.FormValue("key")
+	value := r.FormValue("value")
+
+	if err := s.Service.SetSetting(r.Context(), key, value); err != nil {
+		http.Error(w, "Failed to save setting: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+}
+
+func (s *Server) handleDeleteSetting(w http.ResponseWriter, r *http.Request) {
+	if err := s.Service.DeleteSetting(r.Context(), r.PathValue("key")); err != nil {
+		http.Error(w, "Failed to delete setting", errorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleSetDefaultCategory(w http.ResponseWriter, r *http.Request) {
+	var catID *int64
+	if catIDStr := r.FormValue("category_id"); catIDStr != "" {
+		id, err := strconv.ParseInt(catIDStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid category ID", http.StatusBadRequest)
+			return
+		}
+		catID = &id
+	}
+
+	if err := s.Service.SetDefaultCategoryID(r.Context(), catID); err != nil {
+		http.Error(w, "Failed to set default category: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, "/categories", http.StatusSeeOther)
+}
+
+// parseReportBound parses one end of a custom report range. Date-only values
+// cover the whole day, as CalculateReportPeriod does: a start becomes
+// 00:00:00 and an end 23:59:59. An empty value gives the zero time.
+func parseReportBound(value string, loc *time.Location, isEnd bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		if isEnd {
+			return t.AddDate(0, 0, 1).Add(-time.Second), nil
+		}
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// checkReportPeriod rejects a period that is neither a report period nor
+// one of extra when the query asks for strict=1. Otherwise unknown periods
+// select all entries, which suits the page but hides typos from API clients.
+func checkReportPeriod(query url.Values, period string, extra ...string) error {
+	if query.Get("strict") != "1" || service.IsReportPeriod(period) || slices.Contains(extra, period) {
+		return nil
+	}
+	return fmt.Errorf("%w: unknown period %q", service.ErrValidation, period)
+}
+
+// reportQuery is the report filter selected by a request's query string,
+// together with the raw values to show in the filter form.
+type reportQuery struct {
+	Period    string
+	StartDate string
+	EndDate   string
+	Filter    service.ReportFilter
+}
+
+// parseReportQuery reads the report filters shared by the reports page and
+// filtered exports; without a period or dates it selects defaultPeriod.
+// Invalid values yield an ErrValidation error.
+func (s *Server) parseReportQuery(r *http.Request, defaultPeriod string) (reportQuery, error) {
+	query := r.URL.Query()
+	q := reportQuery{
+		Period:    query.Get("period"),
+		StartDate: query.Get("start_date"),
+		EndDate:   query.Get("end_date"),
+	}
+	if q.Period == "" {
+		q.Period = defaultPeriod
+	}
+	if err := checkReportPeriod(query, q.Period, "since_last_gap", "custom"); err != nil {
+		return q, err
+	}
+
+	start, end := s.Service.ReportPeriod(q.Period)
+	if q.Period == "since_last_gap" {
+		var err error
+		if start, err = s.Service.LastGapStart(r.Context()); err != nil {
+			return q, fmt.Errorf("failed to find last gap: %w", err)
+		}
+		end = s.Service.Now()
 	}
 
-	now := time.Now()
-	start, end := service.CalculateReportPeriod(period, now)
+	// Dates select a custom range unless another period is chosen explicitly
+	if q.Period == "custom" || (query.Get("period") == "" && (q.StartDate != "" || q.EndDate != "")) {
+		q.Period = "custom"
+		var err error
+		if start, err = parseReportBound(q.StartDate, s.Service.Location(), false); err != nil {
+			return q, fmt.Errorf("%w: invalid start date", service.ErrValidation)
+		}
+		if end, err = parseReportBound(q.EndDate, s.Service.Location(), true); err != nil {
+			return q, fmt.Errorf("%w: invalid end date", service.ErrValidation)
+		}
+	}
 
-	catFilterStr := r.URL.Query().Get("category_id")
 	var catFilter int64
-	if catFilterStr != "" {
-		catFilter, _ = strconv.ParseInt(catFilterStr, 10, 64)
+	if v := query.Get("category_id"); v != "" {
+		catFilter, _ = strconv.ParseInt(v, 10, 64)
 	}
 
-	tagIDsStr := r.URL.Query()["tag_ids"]
 	var tagIDs []int64
-	for _, idStr := range tagIDsStr {
+	for _, idStr := range query["tag_ids"] {
 		if id, err := strconv.ParseInt(idStr, 10, 64); err == nil {
 			tagIDs = append(tagIDs, id)
 		}
 	}
 
-	report, err := s.Service.GetReport(r.Context(), service.ReportFilter{
-		StartDate:      start,
-		EndDate:        end,
-		CategoryFilter: catFilter,
-		TagIDs:         tagIDs,
-	})
+	q.Filter = service.ReportFilter{
+		StartDate:           start,
+		EndDate:             end,
+		CategoryFilter:      catFilter,
+		TagIDs:              tagIDs,
+		SplitAtMidnight:     query.Get("split_days") == "1",
+		RollupSubcategories: query.Get("rollup") == "1",
+		DecimalHours:        query.Get("units") == "decimal",
+		IncludeOverlapping:  query.Get("overlapping") == "1",
+		BillableOnly:        query.Get("billable") == "1",
+		OldestFirst:         query.Get("order") == "asc",
+		// The current stretch of work includes the timer still running
+		IncludeRunning: q.Period == "since_last_gap",
+	}
+	return q, nil
+}
+
+// exportURL links to the CSV export of exactly the entries q selects.
+func (q reportQuery) exportURL() string {
+	v := url.Values{"period": {q.Period}}
+	if q.Period == "custom" {
+		v.Set("start_date", q.StartDate)
+		v.Set("end_date", q.EndDate)
+	}
+	if q.Filter.CategoryFilter != 0 {
+		v.Set("category_id", strconv.FormatInt(q.Filter.CategoryFilter, 10))
+	}
+	for _, id := range q.Filter.TagIDs {
+		v.Add("tag_ids", strconv.FormatInt(id, 10))
+	}
+	if q.Filter.IncludeOverlapping {
+		v.Set("overlapping", "1")
+	}
+	if q.Filter.BillableOnly {
+		v.Set("billable", "1")
+	}
+	if q.Filter.OldestFirst {
+		v.Set("order", "asc")
+	}
+	return "/export?" + v.Encode()
+}
+
+func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	q, err := s.parseReportQuery(r, "today")
+	if err != nil {
+		http.Error(w, "Failed to get report: "+err.Error(), errorStatus(err))
+		return
+	}
+
+	report, err := s.Service.GetReport(r.Context(), q.Filter)
 	if err != nil {
 		log.Printf("Error getting report: %v", err)
-		http.Error(w, "Failed to get report", http.StatusInternalServerError)
+		http.Error(w, "Failed to get report", errorStatus(err))
 		return
 	}
 
@@ -429,9 +1465,19 @@ func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
 		"Report":           report,
 		"Categories":       categories,
 		"Tags":             tags,
-		"Period":           period,
-		"SelectedCategory": catFilter,
-		"SelectedTags":     tagIDs,
+		"Period":           q.Period,
+		"StartDate":        q.StartDate,
+		"EndDate":          q.EndDate,
+		"SelectedCategory": q.Filter.CategoryFilter,
+		"SelectedTags":     q.Filter.TagIDs,
+		"SplitDays":        q.Filter.SplitAtMidnight,
+		"Rollup":           q.Filter.RollupSubcategories,
+		"DecimalHours":     q.Filter.DecimalHours,
+		"Overlapping":      q.Filter.IncludeOverlapping,
+		"BillableOnly":     q.Filter.BillableOnly,
+		"OldestFirst":      q.Filter.OldestFirst,
+		"ExportURL":        q.exportURL(),
+		"Views":            reportViews(categories),
 	}
 
 	if r.Header.Get("HX-Request") == "true" {
@@ -441,6 +1487,102 @@ func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleAnnualReport shows the categories × months grid of a year, the
+// current one unless year is given, as a table or, with format=json, as
+// JSON.
+func (s *Server) handleAnnualReport(w http.ResponseWriter, r *http.Request) {
+	year := s.Service.Now().Year()
+	if v := r.URL.Query().Get("year"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+		year = y
+	}
+
+	report, err := s.Service.GetAnnualReport(r.Context(), year)
+	if err != nil {
+		if errorStatus(err) == http.StatusInternalServerError {
+			log.Printf("Error getting annual report: %v", err)
+		}
+		http.Error(w, "Failed to get annual report: "+err.Error(), errorStatus(err))
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Printf("Annual report write error: %v", err)
+		}
+		return
+	}
+
+	data := map[string]interface{}{
+		"Report":   report,
+		"PrevYear": year - 1,
+		"NextYear": year + 1,
+		"Months":   []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	}
+	s.render(w, r, "", data, "templates/base.html", "templates/annual.html")
+}
+
+// reportView is a predefined report filter with a stable URL to bookmark.
+type reportView struct {
+	Label      string `json:"label"`
+	Period     string `json:"period"`
+	CategoryID int64  `json:"category_id"` // 0 for all categories
+	URL        string `json:"url"`
+}
+
+// reportViewPeriods are the periods offered as quick views, in menu order.
+var reportViewPeriods = []struct{ period, label string }{
+	{"today", "Today"},
+	{"week", "This Week"},
+	{"month", "This Month"},
+	{"quarter", "This Quarter"},
+	{"year", "This Year"},
+}
+
+// reportViews lists every period for all categories, then every period for
+// each category.
+func reportViews(categories []database.Category) []reportView {
+	views := make([]reportView, 0, len(reportViewPeriods)*(len(categories)+1))
+	add := func(name string, categoryID int64) {
+		for _, p := range reportViewPeriods {
+			q := url.Values{"period": {p.period}}
+			if categoryID != 0 {
+				q.Set("category_id", strconv.FormatInt(categoryID, 10))
+			}
+			views = append(views, reportView{
+				Label:      name + ", " + p.label,
+				Period:     p.period,
+				CategoryID: categoryID,
+				URL:        "/reports?" + q.Encode(),
+			})
+		}
+	}
+	add("All categories", 0)
+	for _, c := range categories {
+		add(c.Name, c.ID)
+	}
+	return views
+}
+
+func (s *Server) handleReportViews(w http.ResponseWriter, r *http.Request) {
+	categories, err := s.Service.ListCategories(r.Context())
+	if err != nil {
+		log.Printf("Error listing categories: %v", err)
+		http.Error(w, "Failed to list report views", errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reportViews(categories)); err != nil {
+		log.Printf("Report views write error: %v", err)
+	}
+}
+
 func (s *Server) handleDeleteCategory(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -449,30 +1591,177 @@ func (s *Server) handleDeleteCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.Service.DeleteCategory(r.Context(), id); err != nil {
-		http.Error(w, "Failed to delete category", http.StatusInternalServerError)
+	err = s.Service.DeleteCategory(r.Context(), id)
+	if errors.Is(err, service.ErrNotFound) {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete category", errorStatus(err))
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// resetConfirmation must be sent as the confirm field of a data reset.
+const resetConfirmation = "DELETE"
+
+// handleResetData wipes all entries and tags, and with include_categories=1
+// the categories too.
+func (s *Server) handleResetData(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("confirm") != resetConfirmation {
+		http.Error(w, fmt.Sprintf("Resetting all data requires confirm=%s", resetConfirmation), http.StatusBadRequest)
+		return
+	}
+	if err := s.Service.ResetData(r.Context(), r.FormValue("include_categories") == "1"); err != nil {
+		log.Printf("Error resetting data: %v", err)
+		http.Error(w, "Failed to reset data", errorStatus(err))
+		return
+	}
+	http.Redirect(w, r, "/data?reset=1", http.StatusSeeOther)
+}
+
 func (s *Server) handleDataPage(w http.ResponseWriter, r *http.Request) {
+	overlaps, err := s.Service.FindOverlappingEntries(r.Context())
+	if err != nil {
+		log.Printf("Error finding overlapping entries: %v", err)
+	}
+	totalSeconds, err := s.Service.TotalTrackedSeconds(r.Context())
+	if err != nil {
+		log.Printf("Error totalling tracked time: %v", err)
+	}
+
 	data := map[string]interface{}{
-		"Success": r.URL.Query().Get("success") == "1",
+		"Success":      r.URL.Query().Get("success") == "1",
+		"Reset":        r.URL.Query().Get("reset") == "1",
+		"Overlaps":     overlaps,
+		"TotalSeconds": totalSeconds,
 	}
 	s.render(w, r, "", data, "templates/base.html", "templates/data.html")
 }
 
+// handleExportCSV exports every entry, or, given report filters in the query
+// string, only the entries the reports page shows for them.
 func (s *Server) handleExportCSV(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/csv")
-	w.Header().Set("Content-Disposition", "attachment;filename=time-entries.csv")
-	if err := s.Service.ExportCSV(r.Context(), w); err != nil {
+	if hasReportFilter(r.URL.Query()) {
+		s.handleExportFilteredCSV(w, r)
+		return
+	}
+
+	// Buffered so that a failure is still reported as a 500 rather than
+	// a silently truncated download
+	var buf bytes.Buffer
+	if err := s.Service.ExportCSV(r.Context(), &buf); err != nil {
 		log.Printf("Export error: %v", err)
-		// Can't really send error after headers, but we can try
+		http.Error(w, "Failed to export", errorStatus(err))
+		return
+	}
+	if err := writeAttachment(w, "text/csv", "time-entries.csv", &buf); err != nil {
+		log.Printf("Export write error: %v", err)
+	}
+}
+
+// reportFilterParams are the query parameters of parseReportQuery that
+// change which entries a report holds.
+var reportFilterParams = []string{"period", "start_date", "end_date", "category_id", "tag_ids", "billable", "overlapping", "split_days", "order"}
+
+// hasReportFilter reports whether query narrows or reorders the entries, as
+// opposed to holding only options such as units. Without one an export
+// includes every entry, the running one too.
+func hasReportFilter(query url.Values) bool {
+	return slices.ContainsFunc(reportFilterParams, query.Has)
+}
+
+// writeAttachment sends buf as a file download with its full length.
+func writeAttachment(w http.ResponseWriter, contentType, filename string, buf *bytes.Buffer) error {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment;filename="+filename)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func (s *Server) handleExportFilteredCSV(w http.ResponseWriter, r *http.Request) {
+	// Options such as units alone still export everything
+	q, err := s.parseReportQuery(r, "all")
+	if err != nil {
+		http.Error(w, "Failed to export: "+err.Error(), errorStatus(err))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := s.Service.ExportFilteredCSV(r.Context(), &buf, q.Filter); err != nil {
+		log.Printf("Filtered export error: %v", err)
+		http.Error(w, "Failed to export", errorStatus(err))
+		return
+	}
+
+	if err := writeAttachment(w, "text/csv", "time-entries.csv", &buf); err != nil {
+		log.Printf("Filtered export write error: %v", err)
+	}
+}
+
+func (s *Server) handleExportPivotCSV(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+	if err := checkReportPeriod(r.URL.Query(), period); err != nil {
+		http.Error(w, "Failed to export: "+err.Error(), errorStatus(err))
+		return
+	}
+	start, end := s.Service.ReportPeriod(period)
+
+	var buf bytes.Buffer
+	if err := s.Service.ExportPivotCSV(r.Context(), &buf, service.ReportFilter{
+		StartDate:       start,
+		EndDate:         end,
+		SplitAtMidnight: r.URL.Query().Get("split_days") == "1",
+		DecimalHours:    r.URL.Query().Get("units") == "decimal",
+	}); err != nil {
+		log.Printf("Pivot export error: %v", err)
+		http.Error(w, "Failed to export", errorStatus(err))
+		return
+	}
+
+	if err := writeAttachment(w, "text/csv", "time-pivot.csv", &buf); err != nil {
+		log.Printf("Pivot export write error: %v", err)
+	}
+}
+
+func (s *Server) handleExportTimeseriesCSV(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "month"
+	}
+	if err := checkReportPeriod(r.URL.Query(), period); err != nil {
+		http.Error(w, "Failed to export: "+err.Error(), errorStatus(err))
+		return
+	}
+	start, end := s.Service.ReportPeriod(period)
+
+	var buf bytes.Buffer
+	if err := s.Service.ExportTimeseriesCSV(r.Context(), &buf, service.ReportFilter{
+		StartDate:       start,
+		EndDate:         end,
+		SplitAtMidnight: r.URL.Query().Get("split_days") == "1",
+		DecimalHours:    r.URL.Query().Get("units") == "decimal",
+	}); err != nil {
+		log.Printf("Timeseries export error: %v", err)
+		http.Error(w, "Failed to export", errorStatus(err))
+		return
+	}
+
+	if err := writeAttachment(w, "text/csv", "time-series.csv", &buf); err != nil {
+		log.Printf("Timeseries export write error: %v", err)
 	}
 }
 
+// replaceConfirmation must be sent as the confirm field of a CSV import in
+// replace mode.
+const replaceConfirmation = "REPLACE"
+
 func (s *Server) handleImportCSV(w http.ResponseWriter, r *http.Request) {
 	file, _, err := r.FormFile("csv_file")
 	if err != nil {
@@ -485,15 +1774,179 @@ func (s *Server) handleImportCSV(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	if err := s.Service.ImportCSV(r.Context(), file); err != nil {
+	// Replacing deletes every entry, so it has to be confirmed by typing
+	// the confirmation word
+	mode := service.ImportMode(r.FormValue("mode"))
+	if mode == "" {
+		mode = service.ImportMerge
+	}
+	if mode == service.ImportReplace && r.FormValue("confirm") != replaceConfirmation {
+		http.Error(w, fmt.Sprintf("Replacing all entries requires confirm=%s", replaceConfirmation), http.StatusBadRequest)
+		return
+	}
+
+	mapping := csvMapping(r)
+	if r.Header.Get("Accept") == "text/event-stream" {
+		s.streamImportCSV(w, r, file, mode, mapping)
+		return
+	}
+
+	if err := s.Service.ImportCSVWithProgress(r.Context(), file, mode, mapping, nil); err != nil {
 		log.Printf("Import error: %v", err)
-		http.Error(w, "Import failed: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Import failed: "+err.Error(), importErrorStatus(err))
+		return
+	}
+
+	http.Redirect(w, r, "/data?success=1", http.StatusSeeOther)
+}
+
+func (s *Server) handleExportJSON(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := s.Service.WriteEntriesJSON(r.Context(), &buf); err != nil {
+		log.Printf("JSON export error: %v", err)
+		http.Error(w, "Failed to export", errorStatus(err))
+		return
+	}
+
+	if err := writeAttachment(w, "application/json", "time-entries.json", &buf); err != nil {
+		log.Printf("JSON export write error: %v", err)
+	}
+}
+
+func (s *Server) handleImportJSON(w http.ResponseWriter, r *http.Request) {
+	file, _, err := r.FormFile("json_file")
+	if err != nil {
+		http.Error(w, "Failed to get file", http.StatusBadRequest)
+		return
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Failed to close file: %v", err)
+		}
+	}()
+
+	if err := s.Service.ImportJSON(r.Context(), file); err != nil {
+		log.Printf("JSON import error: %v", err)
+		http.Error(w, "Import failed: "+err.Error(), importErrorStatus(err))
+		return
+	}
+
+	http.Redirect(w, r, "/data?success=1", http.StatusSeeOther)
+}
+
+func (s *Server) handleExportTaxonomy(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := s.Service.WriteTaxonomyJSON(r.Context(), &buf); err != nil {
+		log.Printf("Taxonomy export error: %v", err)
+		http.Error(w, "Failed to export", errorStatus(err))
+		return
+	}
+
+	if err := writeAttachment(w, "application/json", "taxonomy.json", &buf); err != nil {
+		log.Printf("Taxonomy export write error: %v", err)
+	}
+}
+
+func (s *Server) handleImportTaxonomy(w http.ResponseWriter, r *http.Request) {
+	file, _, err := r.FormFile("taxonomy_file")
+	if err != nil {
+		http.Error(w, "Failed to get file", http.StatusBadRequest)
+		return
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Failed to close file: %v", err)
+		}
+	}()
+
+	if err := s.Service.ImportTaxonomy(r.Context(), file); err != nil {
+		log.Printf("Taxonomy import error: %v", err)
+		http.Error(w, "Import failed: "+err.Error(), importErrorStatus(err))
 		return
 	}
 
 	http.Redirect(w, r, "/data?success=1", http.StatusSeeOther)
 }
 
+// csvMapping reads the column mapping of a CSV import from its map_<column>
+// form fields, e.g. map_description=Task. Empty fields keep the default
+// header name.
+func csvMapping(r *http.Request) service.CSVMapping {
+	mapping := service.CSVMapping{}
+	for key, values := range r.Form {
+		col, ok := strings.CutPrefix(key, "map_")
+		if !ok || len(values) == 0 || strings.TrimSpace(values[0]) == "" {
+			continue
+		}
+		mapping[col] = values[0]
+	}
+	return mapping
+}
+
+// streamImportCSV runs an import while reporting progress as server-sent
+// events: "progress" events carry "processed/total", followed by a final
+// "done" (with the page to go to) or "error" event.
+func (s *Server) streamImportCSV(w http.ResponseWriter, r *http.Request, file io.Reader, mode service.ImportMode, mapping service.CSVMapping) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(event, data string) {
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, strings.ReplaceAll(data, "\n", " ")); err != nil {
+			log.Printf("Import progress write error: %v", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	// Progress keeps the client informed, so the import may outlast the
+	// request timeout
+	err := s.Service.ImportCSVWithProgress(withoutTimeout(r.Context()), file, mode, mapping, func(processed, total int) {
+		// Roughly one event per percent keeps big imports from flooding
+		step := max(total/100, 1)
+		if processed == total || processed%step == 0 {
+			send("progress", fmt.Sprintf("%d/%d", processed, total))
+		}
+	})
+	if err != nil {
+		log.Printf("Import error: %v", err)
+		send("error", "Import failed: "+err.Error())
+		return
+	}
+	send("done", "/data?success=1")
+}
+
+// errorStatus maps an error returned by the service to the HTTP status it
+// stands for.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		// The request timeout, or the client giving up, cut the work short
+		return http.StatusServiceUnavailable
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrNoActiveTimer), errors.Is(err, service.ErrOverlap), errors.Is(err, service.ErrActiveEntryChanged),
+		errors.Is(err, service.ErrFocusActive), errors.Is(err, service.ErrNoFocusSession):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrValidation):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// importErrorStatus maps a CSV import error to its HTTP status.
+func importErrorStatus(err error) int {
+	if errors.Is(err, service.ErrImportTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	if errors.Is(err, service.ErrInvalidCSV) || errors.Is(err, service.ErrInvalidJSON) {
+		return http.StatusBadRequest
+	}
+	return errorStatus(err)
+}
+
 func (s *Server) handlePreviewCSV(w http.ResponseWriter, r *http.Request) {
 	file, _, err := r.FormFile("csv_file")
 	if err != nil {
@@ -506,10 +1959,10 @@ func (s *Server) handlePreviewCSV(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	preview, err := s.Service.PreviewCSV(r.Context(), file)
+	preview, err := s.Service.PreviewCSV(r.Context(), file, csvMapping(r))
 	if err != nil {
 		log.Printf("Preview error: %v", err)
-		http.Error(w, "Preview failed: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Preview failed: "+err.Error(), importErrorStatus(err))
 		return
 	}
 
@@ -0,0 +1,368 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/service"
+	_ "modernc.org/sqlite"
+)
+
+func TestTextColor(t *testing.T) {
+	tests := []struct {
+		bg   string
+		want string
+	}{
+		{"#000000", "#ffffff"},
+		{"#ffffff", "#000000"},
+		{"#ffff00", "#000000"},
+		{"#0000ff", "#ffffff"},
+		{"#cccccc", "#000000"},
+		{"#888888", "#000000"},
+		{"#333333", "#ffffff"},
+		{"#FF0000", "#000000"},
+		{"#8B0000", "#ffffff"},
+		{"not-a-color", "#000000"},
+		{"#fff", "#000000"},
+	}
+	for _, tt := range tests {
+		if got := textColor(tt.bg); got != tt.want {
+			t.Errorf("textColor(%q) = %q, want %q", tt.bg, got, tt.want)
+		}
+	}
+}
+
+func TestMultiline(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"single line", "single line"},
+		{"first #a\nsecond #b", "first #a<br>second #b"},
+		{"windows\r\nline", "windows<br>line"},
+		{"<b>bold</b>\n& more", "&lt;b&gt;bold&lt;/b&gt;<br>&amp; more"},
+	}
+	for _, tt := range tests {
+		if got := string(multiline(tt.text)); got != tt.want {
+			t.Errorf("multiline(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTagChips(t *testing.T) {
+	got := string(tagChips([]database.Tag{
+		{Name: "golang", Color: "#00add8"},
+		{Name: "<script>", Color: "#000000"},
+		{Name: "bad", Color: "red; position: fixed"},
+	}))
+	for _, want := range []string{
+		`style="background-color: #00add8; color: #000000">#golang</span>`,
+		`style="background-color: #000000; color: #ffffff">#&lt;script&gt;</span>`,
+		`style="background-color: #cccccc; color: #000000">#bad</span>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+	if strings.Contains(got, "<script>") || strings.Contains(got, "position") {
+		t.Errorf("expected names and colors to be escaped, got %q", got)
+	}
+	if got := tagChips(nil); got != "" {
+		t.Errorf("expected no chips without tags, got %q", got)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	now := start.Add(time.Hour + 2*time.Minute + 3*time.Second + 400*time.Millisecond)
+	completed := sql.NullTime{Time: start.Add(90 * time.Minute), Valid: true}
+
+	tests := []struct {
+		name string
+		end  sql.NullTime
+		now  []time.Time
+		want string
+	}{
+		{"completed", completed, nil, "1h30m0s"},
+		{"completed ignores now", completed, []time.Time{now}, "1h30m0s"},
+		{"running without now", sql.NullTime{}, nil, "Running"},
+		{"running with now", sql.NullTime{}, []time.Time{now}, "1h2m3s (running)"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(start, tt.end, tt.now...); got != tt.want {
+			t.Errorf("%s: formatDuration = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDurationCompact(t *testing.T) {
+	tests := []struct {
+		seconds int64
+		want    string
+	}{
+		{0, "00:00"},
+		{90, "01:30"},
+		{3600, "1:00"},
+		{3661, "1:01"},
+		{36000, "10:00"},
+		{86399, "23:59"},
+		{90000, "1d 01:00"},
+		{360000, "4d 04:00"},
+	}
+	for _, tt := range tests {
+		if got := formatDurationCompact(tt.seconds); got != tt.want {
+			t.Errorf("formatDurationCompact(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDurationSeconds(t *testing.T) {
+	tests := []struct {
+		seconds int64
+		want    string
+	}{
+		{0, "0m 0s"},
+		{90, "1m 30s"},
+		{3660, "1h 1m"},
+		{86340, "23h 59m"},
+		{86400, "1d 0h 0m"},
+		{90000, "1d 1h 0m"},
+		{360000, "4d 4h 0m"},
+		{3*86400 + 4*3600 + 10*60, "3d 4h 10m"},
+	}
+	for _, tt := range tests {
+		if got := formatDurationSeconds(tt.seconds); got != tt.want {
+			t.Errorf("formatDurationSeconds(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestParseStopTime(t *testing.T) {
+	now := time.Date(2025, 3, 10, 0, 15, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2025-03-09T18:30", time.Date(2025, 3, 9, 18, 30, 0, 0, time.UTC)},
+		{"00:10", time.Date(2025, 3, 10, 0, 10, 0, 0, time.UTC)},
+		{"00:15", now},
+		// Later than now today means last night
+		{"23:30", time.Date(2025, 3, 9, 23, 30, 0, 0, time.UTC)},
+		{"23:30:15", time.Date(2025, 3, 9, 23, 30, 15, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseStopTime(tt.value, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseStopTime(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := parseStopTime("yesterday", now); err == nil {
+		t.Error("expected an unparseable stop time to fail")
+	}
+}
+
+func TestWithTimeoutSlowQuery(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int64
+		err := db.QueryRowContext(r.Context(), `
+			WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000000000)
+			SELECT count(*) FROM c`).Scan(&n)
+		if err != nil {
+			http.Error(w, "Query failed: "+err.Error(), errorStatus(err))
+			return
+		}
+		_, _ = w.Write([]byte("done"))
+	})
+
+	started := time.Now()
+	w := httptest.NewRecorder()
+	withTimeout(50*time.Millisecond, slow).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("expected the query to be interrupted, took %s", elapsed)
+	}
+}
+
+func TestWithTimeoutPassesFastResponses(t *testing.T) {
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("expected the request context to have a deadline")
+		}
+		w.Header().Set("X-Test", "1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	})
+
+	w := httptest.NewRecorder()
+	withTimeout(time.Second, fast).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "created" || w.Header().Get("X-Test") != "1" {
+		t.Errorf("expected response to pass through, got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestWithoutTimeout(t *testing.T) {
+	var timed, untimed bool
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, timed = r.Context().Deadline()
+		_, untimed = withoutTimeout(r.Context()).Deadline()
+	})
+
+	// Asking for a stream does not lift the deadline by itself
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	withTimeout(time.Second, h).ServeHTTP(httptest.NewRecorder(), req)
+	if !timed || untimed {
+		t.Errorf("expected only withoutTimeout to drop the deadline, got deadlines %v and %v", timed, untimed)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusServiceUnavailable},
+		{context.Canceled, http.StatusServiceUnavailable},
+		{service.ErrNotFound, http.StatusNotFound},
+		{service.ErrFocusActive, http.StatusConflict},
+		{service.ErrInvalidStopTime, http.StatusBadRequest},
+		{errors.New("disk full"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := errorStatus(tt.err); got != tt.want {
+			t.Errorf("errorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWithTimeoutKeepsLateSuccess(t *testing.T) {
+	// Work that ignores the context and completes after the deadline
+	late := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	})
+
+	w := httptest.NewRecorder()
+	withTimeout(10*time.Millisecond, late).ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "created" {
+		t.Errorf("expected the late response to pass through, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleExportCSVFailure(t *testing.T) {
+	// Without migrations every query fails
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	srv := NewServer(service.New(database.New(db), db))
+
+	for _, path := range []string{"/export", "/export?period=all"} {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("GET %s: expected 500, got %d", path, w.Code)
+		}
+		if cd := w.Header().Get("Content-Disposition"); cd != "" {
+			t.Errorf("GET %s: expected no attachment on failure, got %q", path, cd)
+		}
+	}
+}
+
+func TestHandleImportTaxonomyStatus(t *testing.T) {
+	// Without migrations every query fails
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	srv := NewServer(service.New(database.New(db), db))
+
+	for body, want := range map[string]int{
+		`{"categories":`:                   http.StatusBadRequest,
+		`{"categories":[{"name":"Work"}]}`: http.StatusInternalServerError,
+	} {
+		var b bytes.Buffer
+		mw := multipart.NewWriter(&b)
+		fw, _ := mw.CreateFormFile("taxonomy_file", "taxonomy.json")
+		_, _ = fw.Write([]byte(body))
+		_ = mw.Close()
+
+		req := httptest.NewRequest("POST", "/import/taxonomy", &b)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("importing %s: expected %d, got %d", body, want, w.Code)
+		}
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	open := func() *sql.DB {
+		db, err := sql.Open("sqlite", ":memory:")
+		if err != nil {
+			t.Fatalf("failed to open db: %v", err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+		return db
+	}
+
+	// A server that does not own its service leaves the database open
+	db := open()
+	if err := NewServer(service.New(database.New(db), db)).Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Errorf("expected the database to stay open, got %v", err)
+	}
+
+	db = open()
+	srv := NewServer(service.New(database.New(db), db), WithOwnedService(true))
+	for range 2 {
+		if err := srv.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+	if err := db.Ping(); err == nil {
+		t.Error("expected the owned database to be closed")
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{`W/"3-1"`, true},
+		{`"3-1"`, true},
+		{`W/"2-1", W/"3-1"`, true},
+		{`W/"3-2"`, false},
+		{"*", true},
+	} {
+		if got := etagMatches(tc.header, `W/"3-1"`); got != tc.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/service"
+)
+
+// openAPIDocument is the subset of an OpenAPI 3.0 document the JSON API is
+// described with.
+type openAPIDocument struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       openAPIInfo                     `json:"info"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components openAPIComponents               `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*schema `json:"schemas"`
+}
+
+type operation struct {
+	Summary     string              `json:"summary"`
+	Parameters  []parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]response `json:"responses"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // "query", "header" or "path"
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string                  `json:"description"`
+	Headers     map[string]headerObject `json:"headers,omitempty"`
+	Content     map[string]mediaType    `json:"content,omitempty"`
+}
+
+type headerObject struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *schema `json:"schema"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	Enum       []string           `json:"enum,omitempty"`
+	Items      *schema            `json:"items,omitempty"`
+	Properties map[string]*schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+}
+
+// schemaOf describes the JSON encoding of values of type t, so that the
+// document follows the response types as they change.
+func schemaOf(t reflect.Type) *schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := schemaOf(t.Elem())
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return &schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Slice:
+		return &schema{Type: "array", Items: schemaOf(t.Elem())}
+	case reflect.Struct:
+		s := &schema{Type: "object", Properties: map[string]*schema{}}
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			s.Properties[name] = schemaOf(f.Type)
+			if !strings.Contains(opts, "omitempty") {
+				s.Required = append(s.Required, name)
+			}
+		}
+		return s
+	}
+	return &schema{}
+}
+
+func ref(name string) *schema {
+	return &schema{Ref: "#/components/schemas/" + name}
+}
+
+func jsonContent(s *schema) map[string]mediaType {
+	return map[string]mediaType{"application/json": {Schema: s}}
+}
+
+// formBody describes a form-encoded request with the given string fields.
+func formBody(fields ...string) *requestBody {
+	s := &schema{Type: "object", Properties: map[string]*schema{}}
+	for _, f := range fields {
+		s.Properties[f] = &schema{Type: "string"}
+	}
+	return &requestBody{Content: map[string]mediaType{"application/x-www-form-urlencoded": {Schema: s}}}
+}
+
+// timerResponses are the answers of the start and stop endpoints, which
+// redirect plain clients and render the sticky bar for HTMX.
+var timerResponses = map[string]response{
+	"200": {Description: "HTMX request: the refreshed sticky bar and entry list"},
+	"303": {Description: "Redirect to the index page"},
+	"400": {Description: "Invalid form value"},
+	"409": {Description: "The timer cannot change state"},
+}
+
+// openAPISpec builds the document describing the JSON API and the timer
+// endpoints.
+func openAPISpec() openAPIDocument {
+	entries := &schema{Type: "array", Items: ref("Entry")}
+	return openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: "Precious Time Tracker", Version: "1"},
+		Paths: map[string]map[string]operation{
+			"/api/status": {"get": {
+				Summary:   "Running timer and today's total",
+				Responses: map[string]response{"200": {Description: "Current status", Content: jsonContent(ref("Status"))}},
+			}},
+			"/api/entries": {"get": {
+				Summary: "One page of entries",
+				Parameters: []parameter{
+					{Name: "If-None-Match", In: "header", Description: "ETag of a previous response", Schema: &schema{Type: "string"}},
+					{Name: "limit", In: "query", Schema: &schema{Type: "integer"}},
+					{Name: "offset", In: "query", Schema: &schema{Type: "integer"}},
+					{Name: "sort", In: "query", Schema: &schema{Type: "string", Enum: []string{service.SortStartDesc, service.SortStartAsc, service.SortDurationDesc}}},
+				},
+				Responses: map[string]response{
+					"200": {
+						Description: "The requested page",
+						Headers: map[string]headerObject{
+							"X-Total-Count": {Description: "Number of entries in all pages", Schema: &schema{Type: "integer"}},
+							"ETag":          {Description: "Changes whenever an entry, category or tag is added, edited or deleted", Schema: &schema{Type: "string"}},
+						},
+						Content: jsonContent(entries),
+					},
+					"304": {Description: "If-None-Match holds the current ETag"},
+					"400": {Description: "Invalid limit, offset or sort"},
+				},
+			}},
+			"/api/goals/progress": {"get": {
+				Summary:    "Progress of every category goal for a period",
+				Parameters: []parameter{{Name: "period", In: "query", Description: "Defaults to week", Schema: &schema{Type: "string", Enum: []string{"today", "week", "month", "quarter", "year"}}}},
+				Responses: map[string]response{
+					"200": {Description: "Goals with the time tracked towards them", Content: jsonContent(&schema{Type: "array", Items: ref("GoalProgress")})},
+					"400": {Description: "Unknown period"},
+				},
+			}},
+			"/entry/{id}.json": {"get": {
+				Summary:    "A single entry",
+				Parameters: []parameter{{Name: "id", In: "path", Required: true, Schema: &schema{Type: "integer"}}},
+				Responses: map[string]response{
+					"200": {Description: "The entry", Content: jsonContent(ref("Entry"))},
+					"404": {Description: "No such entry"},
+				},
+			}},
+			"/export.json": {"get": {
+				Summary:   "Every entry, newest first",
+				Responses: map[string]response{"200": {Description: "All entries as an attachment", Content: jsonContent(entries)}},
+			}},
+			"/focus/status": {"get": {
+				Summary:   "The running focus session, if any",
+				Responses: map[string]response{"200": {Description: "Focus status", Content: jsonContent(ref("FocusStatus"))}},
+			}},
+			"/focus/start": {"post": {
+				Summary:     "Start a timer as a focus session; no other timer can start until it ends",
+				RequestBody: formBody("description", "category_id", "planned_minutes"),
+				Responses:   timerResponses,
+			}},
+			"/focus/end": {"post": {
+				Summary:   "Stop the timer of the running focus session",
+				Responses: timerResponses,
+			}},
+			"/start": {"post": {
+				Summary:     "Start a timer, stopping the running one unless multiple timers are enabled",
+				RequestBody: formBody("description", "category_id", "tag_ids", "start_offset_minutes"),
+				Responses:   timerResponses,
+			}},
+			"/stop": {"post": {
+				Summary:     "Stop the timer with the given id, or the primary one",
+				RequestBody: formBody("id", "stop_time"),
+				Responses:   timerResponses,
+			}},
+		},
+		Components: openAPIComponents{Schemas: map[string]*schema{
+			"Entry":        schemaOf(reflect.TypeFor[service.EntryJSON]()),
+			"Status":       schemaOf(reflect.TypeFor[statusResponse]()),
+			"GoalProgress": schemaOf(reflect.TypeFor[service.GoalProgress]()),
+			"FocusStatus":  schemaOf(reflect.TypeFor[service.FocusStatus]()),
+		}},
+	}
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(openAPISpec()); err != nil {
+		log.Printf("OpenAPI write error: %v", err)
+	}
+}
@@ -1,25 +1,118 @@
 package server
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/service"
 )
 
+// DefaultRequestTimeout bounds how long a request may spend on database work
+// unless configured otherwise.
+const DefaultRequestTimeout = 30 * time.Second
+
 type Server struct {
 	Service *service.Service
 	Router  *http.ServeMux
+
+	requestTimeout time.Duration
+	readOnly       bool
+	ownsService    bool
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithRequestTimeout sets the deadline given to each request's context. Zero
+// disables it.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		if d >= 0 {
+			s.requestTimeout = d
+		}
+	}
+}
+
+// WithReadOnly rejects every request that could change data, leaving only
+// read methods available.
+func WithReadOnly(readOnly bool) Option {
+	return func(s *Server) {
+		s.readOnly = readOnly
+	}
 }
 
-func NewServer(svc *service.Service) *Server {
+// WithOwnedService makes Close close the service too, so that the server is
+// the only thing its caller has to shut down.
+func WithOwnedService(owned bool) Option {
+	return func(s *Server) {
+		s.ownsService = owned
+	}
+}
+
+func NewServer(svc *service.Service, opts ...Option) *Server {
 	s := &Server{
-		Service: svc,
-		Router:  http.NewServeMux(),
+		Service:        svc,
+		Router:         http.NewServeMux(),
+		requestTimeout: DefaultRequestTimeout,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 	s.routes()
 	return s
 }
 
+// Close releases what the server owns: the service, when created with
+// WithOwnedService, and nothing otherwise. It is safe to call more than once.
+func (s *Server) Close() error {
+	if !s.ownsService {
+		return nil
+	}
+	return s.Service.Close()
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.Router.ServeHTTP(w, r)
+	if s.readOnly && !isReadMethod(r.Method) {
+		http.Error(w, "Read-only mode", http.StatusForbidden)
+		return
+	}
+	if s.requestTimeout <= 0 {
+		s.Router.ServeHTTP(w, r)
+		return
+	}
+	withTimeout(s.requestTimeout, s.Router).ServeHTTP(w, r)
+}
+
+// isReadMethod reports whether requests with method never modify data.
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// untimedKey holds the request context from before withTimeout added its
+// deadline.
+type untimedKey struct{}
+
+// withTimeout runs next with a context that expires after d. Handlers report
+// a query cut short by it through errorStatus, as a 503.
+func withTimeout(d time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.WithValue(r.Context(), untimedKey{}, r.Context()), d)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withoutTimeout returns ctx without the deadline of withTimeout, for work
+// such as a streamed import that reports progress and may run as long as it
+// needs. It is still canceled when the client goes away.
+func withoutTimeout(ctx context.Context) context.Context {
+	if untimed, ok := ctx.Value(untimedKey{}).(context.Context); ok {
+		return untimed
+	}
+	return ctx
 }
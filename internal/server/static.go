@@ -0,0 +1,48 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// staticMaxAge is how long browsers may reuse a static file before
+// revalidating it. Stylesheet links carry a ?v= query to force a reload
+// after a change.
+const staticMaxAge = 24 * time.Hour
+
+// staticHandler serves the files of fsys with Cache-Control and an ETag
+// derived from their content, so revalidating an unchanged file gets a 304.
+func staticHandler(fsys fs.FS) http.Handler {
+	etags := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(b)
+		etags[path] = `"` + hex.EncodeToString(sum[:8]) + `"`
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error hashing static files: %v", err)
+	}
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(staticMaxAge.Seconds()))
+
+	files := http.FileServerFS(fsys)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if etag, ok := etags[strings.TrimPrefix(r.URL.Path, "/")]; ok {
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
+)
+
+// Audit actions recorded in entry_audit.
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// EntryHistory returns the recorded changes of an entry, oldest first. The
+// history outlives the entry itself.
+func (s *Service) EntryHistory(ctx context.Context, id int64) ([]database.EntryAudit, error) {
+	return s.db.ListEntryAudit(ctx, id)
+}
+
+// recordAudit appends a history row for entryID. before is nil for creates
+// and after is nil for deletes; both are stored as JSON.
+func (s *Service) recordAudit(ctx context.Context, q *database.Queries, entryID int64, action string, before, after *database.GetTimeEntryRow) error {
+	oldValue, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+	newValue, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+	return q.CreateEntryAudit(ctx, database.CreateEntryAuditParams{
+		TimeEntryID: entryID,
+		Action:      action,
+		OldValue:    oldValue,
+		NewValue:    newValue,
+		ChangedAt:   s.Now(),
+	})
+}
+
+func auditJSON(entry *database.GetTimeEntryRow) (sql.NullString, error) {
+	if entry == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntryHistory(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	entry, err := svc.StartTimer(ctx, "Draft", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	start := entry.StartTime
+	end := sql.NullTime{Time: start.Add(time.Hour), Valid: true}
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, "First edit", start, end, nil); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, "Second edit", start, end, nil); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+
+	history, err := svc.EntryHistory(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("EntryHistory failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("expected create plus two updates, got %d rows", len(history))
+	}
+	if history[0].Action != AuditCreate || history[0].OldValue.Valid {
+		t.Errorf("expected create without before, got %+v", history[0])
+	}
+	updates := history[1:]
+	for _, h := range updates {
+		if h.Action != AuditUpdate || !h.OldValue.Valid || !h.NewValue.Valid {
+			t.Errorf("expected update with before and after, got %+v", h)
+		}
+	}
+	if !strings.Contains(updates[0].OldValue.String, `"Draft"`) || !strings.Contains(updates[0].NewValue.String, `"First edit"`) {
+		t.Errorf("unexpected first update: %s -> %s", updates[0].OldValue.String, updates[0].NewValue.String)
+	}
+	if !strings.Contains(updates[1].NewValue.String, `"Second edit"`) {
+		t.Errorf("unexpected second update: %s", updates[1].NewValue.String)
+	}
+
+	// Deleting keeps the history and appends to it
+	if err := svc.DeleteTimeEntry(ctx, entry.ID); err != nil {
+		t.Fatalf("DeleteTimeEntry failed: %v", err)
+	}
+	history, _ = svc.EntryHistory(ctx, entry.ID)
+	if len(history) != 4 || history[3].Action != AuditDelete || history[3].NewValue.Valid {
+		t.Fatalf("expected a trailing delete row, got %+v", history)
+	}
+
+	// The log is append-only
+	if _, err := svc.rawDB.Exec("UPDATE entry_audit SET action = 'tampered'"); err == nil {
+		t.Errorf("expected updating the audit log to fail")
+	}
+	if _, err := svc.rawDB.Exec("DELETE FROM entry_audit"); err == nil {
+		t.Errorf("expected deleting from the audit log to fail")
+	}
+}
+
+func TestUpdateTimeEntryColorIsAtomic(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Second)
+	entry := seedEntry(t, svc, "Draft", now.Add(-time.Hour), now, nil)
+	end := sql.NullTime{Time: now, Valid: true}
+
+	// A failing update leaves the color untouched
+	missing := int64(999)
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, "Draft", entry.StartTime, end, &missing, WithEntryColor("#123456")); err == nil {
+		t.Fatalf("expected an unknown category to fail the update")
+	}
+	got, _ := svc.GetTimeEntry(ctx, entry.ID)
+	if got.Color.Valid {
+		t.Errorf("expected no color after the failed update, got %v", got.Color)
+	}
+
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, "Draft", entry.StartTime, end, nil, WithEntryColor("red")); !errors.Is(err, ErrInvalidColor) {
+		t.Errorf("expected ErrInvalidColor, got %v", err)
+	}
+
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, "Draft", entry.StartTime, end, nil, WithEntryColor("#123456")); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+	got, _ = svc.GetTimeEntry(ctx, entry.ID)
+	if got.Color.String != "#123456" {
+		t.Errorf("expected color #123456, got %v", got.Color)
+	}
+	history, _ := svc.EntryHistory(ctx, entry.ID)
+	last := history[len(history)-1]
+	if last.Action != AuditUpdate || !strings.Contains(last.NewValue.String, "#123456") {
+		t.Errorf("expected the color change in the history, got %+v", last)
+	}
+
+	if err := svc.SetTimeEntryColor(ctx, entry.ID, ""); err != nil {
+		t.Fatalf("SetTimeEntryColor failed: %v", err)
+	}
+	history, _ = svc.EntryHistory(ctx, entry.ID)
+	if last := history[len(history)-1]; !strings.Contains(last.OldValue.String, "#123456") {
+		t.Errorf("expected SetTimeEntryColor to be recorded, got %+v", last)
+	}
+	if err := svc.SetTimeEntryColor(ctx, 999, "#123456"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing entry, got %v", err)
+	}
+}
+
+func TestUpdateTimeEntryBillableIsAtomic(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Second)
+	entry := seedEntry(t, svc, "Client call", now.Add(-time.Hour), now, nil)
+	end := sql.NullTime{Time: now, Valid: true}
+
+	// A failing update leaves the flag untouched
+	missing := int64(999)
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, "Client call", entry.StartTime, end, &missing, WithEntryBillable(true)); err == nil {
+		t.Fatalf("expected an unknown category to fail the update")
+	}
+	got, _ := svc.GetTimeEntry(ctx, entry.ID)
+	if got.Billable {
+		t.Errorf("expected the entry to stay non-billable after the failed update")
+	}
+
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, "Client call", entry.StartTime, end, nil, WithEntryBillable(true)); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+	got, _ = svc.GetTimeEntry(ctx, entry.ID)
+	if !got.Billable {
+		t.Errorf("expected the entry to be billable")
+	}
+	history, _ := svc.EntryHistory(ctx, entry.ID)
+	last := history[len(history)-1]
+	if !strings.Contains(last.OldValue.String, `"billable":false`) || !strings.Contains(last.NewValue.String, `"billable":true`) {
+		t.Errorf("expected the billable change in the history, got %+v", last)
+	}
+
+	if err := svc.SetTimeEntryBillable(ctx, 999, true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing entry, got %v", err)
+	}
+}
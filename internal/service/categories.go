@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
+)
+
+// ErrCategoryCycle is returned when a category would become its own
+// ancestor.
+var ErrCategoryCycle = fmt.Errorf("%w: a category cannot be nested inside itself", ErrValidation)
+
+// ErrDuplicateCategory is returned when a category would take the name of
+// another one. Names are compared ignoring case.
+var ErrDuplicateCategory = fmt.Errorf("%w: a category with this name already exists", ErrValidation)
+
+// ErrReservedCategoryName is returned when a category would be named like
+// the label reports show for entries without a category.
+var ErrReservedCategoryName = fmt.Errorf("%w: category name is reserved", ErrValidation)
+
+// CategoryNode is a category together with its subcategories.
+type CategoryNode struct {
+	database.Category
+	Children []CategoryNode
+}
+
+// ListCategoryTree returns the top-level categories with their
+// subcategories nested below them, each level in display order.
+func (s *Service) ListCategoryTree(ctx context.Context) ([]CategoryNode, error) {
+	cats, err := s.db.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[int64][]database.Category)
+	var roots []database.Category
+	for _, c := range cats {
+		if c.ParentID.Valid {
+			children[c.ParentID.Int64] = append(children[c.ParentID.Int64], c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+
+	var build func([]database.Category) []CategoryNode
+	build = func(cats []database.Category) []CategoryNode {
+		nodes := make([]CategoryNode, 0, len(cats))
+		for _, c := range cats {
+			nodes = append(nodes, CategoryNode{Category: c, Children: build(children[c.ID])})
+		}
+		return nodes
+	}
+	return build(roots), nil
+}
+
+// checkCategoryParent reports whether id may be nested inside parentID:
+// the parent must exist and must not be id or one of its descendants.
+func checkCategoryParent(cats []database.Category, id, parentID int64) error {
+	parents := make(map[int64]int64, len(cats))
+	exists := make(map[int64]bool, len(cats))
+	for _, c := range cats {
+		exists[c.ID] = true
+		if c.ParentID.Valid {
+			parents[c.ID] = c.ParentID.Int64
+		}
+	}
+	if !exists[parentID] {
+		return fmt.Errorf("%w: parent category %d does not exist", ErrValidation, parentID)
+	}
+	// Stored parents never form a cycle, so the walk ends at a root
+	for p, ok := parentID, true; ok; p, ok = parents[p] {
+		if p == id {
+			return ErrCategoryCycle
+		}
+	}
+	return nil
+}
+
+// categoryRoots maps every category to its top-level ancestor, which is the
+// category itself when it has no parent.
+func categoryRoots(cats []database.Category) map[int64]database.Category {
+	byID := make(map[int64]database.Category, len(cats))
+	for _, c := range cats {
+		byID[c.ID] = c
+	}
+	roots := make(map[int64]database.Category, len(cats))
+	for _, c := range cats {
+		root := c
+		for root.ParentID.Valid {
+			parent, ok := byID[root.ParentID.Int64]
+			if !ok {
+				break
+			}
+			root = parent
+		}
+		roots[c.ID] = root
+	}
+	return roots
+}
+
+// checkCategoryName rejects name when it matches, ignoring case, the label
+// used for entries without a category, so the two never look alike.
+func checkCategoryName(ctx context.Context, q *database.Queries, name string) error {
+	reserved, err := label(ctx, q, SettingNoCategoryLabel, DefaultNoCategoryLabel)
+	if err != nil {
+		return err
+	}
+	if isNoCategoryLabel(name, reserved) {
+		return fmt.Errorf("%w: '%s'", ErrReservedCategoryName, name)
+	}
+	return nil
+}
+
+func isNoCategoryLabel(name, reserved string) bool {
+	return strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(reserved))
+}
+
+// categoryNameError turns a violation of the unique category name into
+// ErrDuplicateCategory, leaving other errors as they are.
+func categoryNameError(err error, name string) error {
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: categories.name") {
+		return fmt.Errorf("%w: '%s'", ErrDuplicateCategory, name)
+	}
+	return err
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
+)
+
+func TestCategoryTree(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	client, _ := svc.CreateCategory(ctx, "Client A", "#ff0000")
+	design, _ := svc.CreateCategory(ctx, "Design", "#00ff00")
+	mockups, _ := svc.CreateCategory(ctx, "Mockups", "#0000ff")
+	if _, err := svc.CreateCategory(ctx, "Personal", "#cccccc"); err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	if _, err := svc.UpdateCategory(ctx, design.ID, design.Name, design.Color, &client.ID); err != nil {
+		t.Fatalf("UpdateCategory failed: %v", err)
+	}
+	if _, err := svc.UpdateCategory(ctx, mockups.ID, mockups.Name, mockups.Color, &design.ID); err != nil {
+		t.Fatalf("UpdateCategory failed: %v", err)
+	}
+
+	tree, err := svc.ListCategoryTree(ctx)
+	if err != nil {
+		t.Fatalf("ListCategoryTree failed: %v", err)
+	}
+	if len(tree) != 2 || tree[0].Name != "Client A" || tree[1].Name != "Personal" {
+		t.Fatalf("expected Client A and Personal at the top, got %+v", tree)
+	}
+	if len(tree[0].Children) != 1 || tree[0].Children[0].Name != "Design" {
+		t.Fatalf("expected Design under Client A, got %+v", tree[0].Children)
+	}
+	if len(tree[0].Children[0].Children) != 1 || tree[0].Children[0].Children[0].Name != "Mockups" {
+		t.Errorf("expected Mockups under Design, got %+v", tree[0].Children[0].Children)
+	}
+
+	// Deleting a parent promotes its subcategories
+	if err := svc.DeleteCategory(ctx, design.ID); err != nil {
+		t.Fatalf("DeleteCategory failed: %v", err)
+	}
+	got, _ := svc.GetCategory(ctx, mockups.ID)
+	if got.ParentID.Valid {
+		t.Errorf("expected Mockups to become top-level, got parent %d", got.ParentID.Int64)
+	}
+}
+
+func TestUpdateCategoryRejectsCycles(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	client, _ := svc.CreateCategory(ctx, "Client A", "#ff0000")
+	design, _ := svc.CreateCategory(ctx, "Design", "#00ff00")
+	mockups, _ := svc.CreateCategory(ctx, "Mockups", "#0000ff")
+	if _, err := svc.UpdateCategory(ctx, design.ID, design.Name, design.Color, &client.ID); err != nil {
+		t.Fatalf("UpdateCategory failed: %v", err)
+	}
+	if _, err := svc.UpdateCategory(ctx, mockups.ID, mockups.Name, mockups.Color, &design.ID); err != nil {
+		t.Fatalf("UpdateCategory failed: %v", err)
+	}
+
+	for name, parent := range map[string]int64{
+		"itself":         client.ID,
+		"its child":      design.ID,
+		"its grandchild": mockups.ID,
+	} {
+		if _, err := svc.UpdateCategory(ctx, client.ID, "Client A", "#ff0000", &parent); !errors.Is(err, ErrCategoryCycle) {
+			t.Errorf("nesting inside %s: expected ErrCategoryCycle, got %v", name, err)
+		}
+	}
+	missing := int64(9999)
+	if _, err := svc.UpdateCategory(ctx, client.ID, "Client A", "#ff0000", &missing); !errors.Is(err, ErrValidation) {
+		t.Errorf("missing parent: expected ErrValidation, got %v", err)
+	}
+
+	got, _ := svc.GetCategory(ctx, client.ID)
+	if got.ParentID.Valid {
+		t.Errorf("expected rejected updates to leave Client A top-level, got parent %d", got.ParentID.Int64)
+	}
+}
+
+func TestGetReportRollupSubcategories(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	client, _ := svc.CreateCategory(ctx, "Client A", "#ff0000")
+	design, _ := svc.CreateCategory(ctx, "Design", "#00ff00")
+	personal, _ := svc.CreateCategory(ctx, "Personal", "#cccccc")
+	if _, err := svc.UpdateCategory(ctx, design.ID, design.Name, design.Color, &client.ID); err != nil {
+		t.Fatalf("UpdateCategory failed: %v", err)
+	}
+
+	now := time.Now()
+	seedEntry(t, svc, "Kickoff", now.Add(-4*time.Hour), now.Add(-3*time.Hour), &client.ID)
+	seedEntry(t, svc, "Wireframes", now.Add(-3*time.Hour), now.Add(-time.Hour), &design.ID)
+	seedEntry(t, svc, "Gym", now.Add(-time.Hour), now, &personal.ID)
+
+	filter := ReportFilter{StartDate: now.Add(-24 * time.Hour), EndDate: now.Add(time.Hour)}
+	totals := func(report ReportData) map[string]int64 {
+		m := make(map[string]int64)
+		for _, b := range report.CategoryBreakdown {
+			m[b.CategoryName] = b.TotalSeconds
+		}
+		return m
+	}
+
+	report, err := svc.GetReport(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if got := totals(report); len(got) != 3 || got["Client A"] != 3600 || got["Design"] != 7200 {
+		t.Errorf("expected each category on its own without rollup, got %v", got)
+	}
+
+	filter.RollupSubcategories = true
+	report, err = svc.GetReport(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if got := totals(report); len(got) != 2 || got["Client A"] != 10800 || got["Personal"] != 3600 {
+		t.Errorf("expected Design rolled up into Client A, got %v", got)
+	}
+	if len(report.GroupedEntries) != 2 || report.GroupedEntries[0].CategoryID != client.ID || len(report.GroupedEntries[0].Entries) != 2 {
+		t.Errorf("expected Client A to group both of its entries first, got %+v", report.GroupedEntries)
+	}
+	if report.TotalSeconds != 14400 {
+		t.Errorf("expected the total to be unchanged, got %d", report.TotalSeconds)
+	}
+}
+
+func TestCategoryNameReserved(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"No Category", " no category "} {
+		if _, err := svc.CreateCategory(ctx, name, "#ff0000"); !errors.Is(err, ErrReservedCategoryName) || !errors.Is(err, ErrValidation) {
+			t.Errorf("CreateCategory(%q): expected ErrReservedCategoryName, got %v", name, err)
+		}
+	}
+	work, _ := svc.CreateCategory(ctx, "Work", "#ff0000")
+	if _, err := svc.UpdateCategory(ctx, work.ID, "No Category", work.Color, nil); !errors.Is(err, ErrReservedCategoryName) {
+		t.Errorf("expected renaming to the label to fail, got %v", err)
+	}
+	err := svc.ImportTaxonomy(ctx, strings.NewReader(`{"categories":[{"name":"No Category","color":"#ff0000"}]}`))
+	if !errors.Is(err, ErrReservedCategoryName) {
+		t.Errorf("expected the taxonomy import to fail, got %v", err)
+	}
+
+	// Only the configured label is reserved
+	if err := svc.SetSetting(ctx, SettingNoCategoryLabel, "Unfiled"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	if _, err := svc.CreateCategory(ctx, "No Category", "#ff0000"); err != nil {
+		t.Errorf("expected the default label to be free once relabeled, got %v", err)
+	}
+	if _, err := svc.CreateCategory(ctx, "unfiled", "#ff0000"); !errors.Is(err, ErrReservedCategoryName) {
+		t.Errorf("expected the custom label to be reserved, got %v", err)
+	}
+}
+
+func TestGetReportCategoryNamedLikeNoCategory(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	// A category created before names were checked
+	legacy, err := svc.db.CreateCategory(ctx, database.CreateCategoryParams{Name: "No Category", Color: "#ff0000"})
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	now := time.Now()
+	seedEntry(t, svc, "Filed", now.Add(-3*time.Hour), now.Add(-time.Hour), &legacy.ID)
+	seedEntry(t, svc, "Unfiled", now.Add(-time.Hour), now, nil)
+
+	report, err := svc.GetReport(ctx, ReportFilter{StartDate: now.Add(-24 * time.Hour), EndDate: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	totals := make(map[int64]int64)
+	for _, b := range report.CategoryBreakdown {
+		totals[b.CategoryID] = b.TotalSeconds
+	}
+	if len(totals) != 2 || totals[legacy.ID] != 7200 || totals[-1] != 3600 {
+		t.Errorf("expected separate rows for the category and uncategorized entries, got %+v", report.CategoryBreakdown)
+	}
+	if len(report.GroupedEntries) != 2 {
+		t.Errorf("expected two entry groups, got %+v", report.GroupedEntries)
+	}
+}
+
+func TestImportNoCategoryLabel(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	csvContent := "description,start_time,end_time,category\n" +
+		"Unfiled,2024-01-01T09:00:00Z,2024-01-01T10:00:00Z,No Category\n"
+	if err := svc.ImportCSV(ctx, strings.NewReader(csvContent)); err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	entries, _ := svc.db.ListAllTimeEntries(ctx)
+	if len(entries) != 1 || entries[0].CategoryID.Valid {
+		t.Errorf("expected the entry to be imported without a category, got %+v", entries)
+	}
+	if cats, _ := svc.ListCategories(ctx); len(cats) != 0 {
+		t.Errorf("expected no category to be created, got %+v", cats)
+	}
+}
+
+func TestCategoryNamesUnique(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	work, err := svc.CreateCategory(ctx, "Work", "#ff0000")
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	home, _ := svc.CreateCategory(ctx, "Home", "#00ff00")
+	for _, name := range []string{"Work", "work"} {
+		if _, err := svc.CreateCategory(ctx, name, "#0000ff"); !errors.Is(err, ErrDuplicateCategory) || !errors.Is(err, ErrValidation) {
+			t.Errorf("CreateCategory(%q): expected ErrDuplicateCategory, got %v", name, err)
+		}
+	}
+	if _, err := svc.UpdateCategory(ctx, home.ID, "WORK", home.Color, nil); !errors.Is(err, ErrDuplicateCategory) {
+		t.Errorf("expected renaming onto another category to fail, got %v", err)
+	}
+	if _, err := svc.UpdateCategory(ctx, work.ID, "work", work.Color, nil); err != nil {
+		t.Errorf("expected a category to change the case of its own name, got %v", err)
+	}
+
+	// Imports reuse the existing category instead of adding a variant
+	csvContent := "description,start_time,end_time,category\n" +
+		"Imported,2024-01-01T09:00:00Z,2024-01-01T10:00:00Z,WORK\n"
+	if err := svc.ImportCSV(ctx, strings.NewReader(csvContent)); err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	entries, _ := svc.db.ListAllTimeEntries(ctx)
+	if len(entries) != 1 || entries[0].CategoryID.Int64 != work.ID {
+		t.Errorf("expected the entry in the existing category, got %+v", entries)
+	}
+	if cats, _ := svc.ListCategories(ctx); len(cats) != 2 {
+		t.Errorf("expected no category to be added, got %+v", cats)
+	}
+}
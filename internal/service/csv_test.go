@@ -5,10 +5,13 @@ import (
 	"context"
 	"database/sql"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
 )
 
 func TestExportCSV(t *testing.T) {
@@ -68,7 +71,7 @@ func TestPreviewCSV(t *testing.T) {
 
 	// 1. Create existing entry
 	entry, _ := svc.StartTimer(ctx, "Existing", nil)
-	if err := svc.StopTimer(ctx); err != nil {
+	if err := svc.StopTimer(ctx, nil); err != nil {
 		t.Fatalf("StopTimer failed: %v", err)
 	} // creates valid end time
 	// Refetch to get the updated EndTime
@@ -88,7 +91,7 @@ func TestPreviewCSV(t *testing.T) {
 		)
 
 	// 2. Preview
-	preview, err := svc.PreviewCSV(ctx, strings.NewReader(csvContent))
+	preview, err := svc.PreviewCSV(ctx, strings.NewReader(csvContent), nil)
 	if err != nil {
 		t.Fatalf("PreviewCSV failed: %v", err)
 	}
@@ -131,7 +134,7 @@ func TestImportCSV(t *testing.T) {
 
 	cat, _ := svc.CreateCategory(ctx, "ExistingCat", "#000000")
 	entry, _ := svc.StartTimer(ctx, "Old Msg", &cat.ID)
-	if err := svc.StopTimer(ctx); err != nil {
+	if err := svc.StopTimer(ctx, nil); err != nil {
 		t.Fatalf("StopTimer failed: %v", err)
 	} // Ensure valid end time
 
@@ -184,6 +187,121 @@ func TestImportCSV(t *testing.T) {
 	}
 }
 
+func TestImportCSVUsesConfiguredLocation(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+	svc := newTestService(t, WithLocation(tokyo))
+	ctx := context.Background()
+
+	csvContent := `id,description,start_time,end_time,category
+,Zone-less,2025-01-01 09:00:00,2025-01-01 10:00:00,
+`
+	if err := svc.ImportCSV(ctx, strings.NewReader(csvContent)); err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+
+	entries, _ := svc.ListTimeEntries(ctx)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	expected := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !entries[0].StartTime.Equal(expected) {
+		t.Errorf("expected start %v, got %v", expected, entries[0].StartTime.UTC())
+	}
+}
+
+func TestCSVColorRoundTrip(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Second)
+	entry := seedEntry(t, svc, "Colored", now.Add(-time.Hour), now, nil)
+	if err := svc.SetTimeEntryColor(ctx, entry.ID, "#123456"); err != nil {
+		t.Fatalf("SetTimeEntryColor failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := svc.ExportCSV(ctx, &buf); err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	records, _ := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	if records[0][5] != "color" || records[1][5] != "#123456" {
+		t.Fatalf("expected color column with #123456, got %v / %v", records[0], records[1])
+	}
+
+	// Clear the color, then re-import the export to restore it
+	if err := svc.SetTimeEntryColor(ctx, entry.ID, ""); err != nil {
+		t.Fatalf("SetTimeEntryColor clear failed: %v", err)
+	}
+	if err := svc.ImportCSV(ctx, &buf); err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	restored, _ := svc.GetTimeEntry(ctx, entry.ID)
+	if !restored.Color.Valid || restored.Color.String != "#123456" {
+		t.Errorf("expected color #123456 after import, got %v", restored.Color)
+	}
+
+	if err := svc.SetTimeEntryColor(ctx, entry.ID, "red"); err == nil {
+		t.Errorf("expected invalid color to be rejected")
+	}
+}
+
+func TestImportCSVExternalIDIsIdempotent(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	// A local entry that happens to share the source system's numeric id
+	now := time.Now().Truncate(time.Second)
+	local := seedEntry(t, svc, "Local entry", now.Add(-2*time.Hour), now.Add(-time.Hour), nil)
+
+	first := fmt.Sprintf(`id,description,start_time,end_time,category,external_id
+%d,Synced task,2025-01-01T10:00:00Z,2025-01-01T11:00:00Z,,src-42
+`, local.ID)
+	second := fmt.Sprintf(`id,description,start_time,end_time,category,external_id
+%d,Synced task (renamed),2025-01-01T10:00:00Z,2025-01-01T12:00:00Z,,src-42
+`, local.ID)
+
+	for _, content := range []string{first, second} {
+		if err := svc.ImportCSV(ctx, strings.NewReader(content)); err != nil {
+			t.Fatalf("ImportCSV failed: %v", err)
+		}
+	}
+
+	entries, _ := svc.ListTimeEntries(ctx)
+	if len(entries) != 2 {
+		t.Fatalf("expected local + one synced entry, got %d", len(entries))
+	}
+
+	untouched, _ := svc.GetTimeEntry(ctx, local.ID)
+	if untouched.Description != "Local entry" {
+		t.Errorf("expected local entry to be untouched, got %q", untouched.Description)
+	}
+
+	var synced *database.ListTimeEntriesRow
+	for i := range entries {
+		if entries[i].ExternalID.String == "src-42" {
+			synced = &entries[i]
+		}
+	}
+	if synced == nil {
+		t.Fatal("synced entry not found")
+	}
+	if synced.Description != "Synced task (renamed)" {
+		t.Errorf("expected second sync to update the entry, got %q", synced.Description)
+	}
+
+	// Preview recognizes the existing external entry as unchanged
+	preview, err := svc.PreviewCSV(ctx, strings.NewReader(second), nil)
+	if err != nil {
+		t.Fatalf("PreviewCSV failed: %v", err)
+	}
+	if len(preview) != 0 {
+		t.Errorf("expected no preview changes for an identical sync, got %+v", preview)
+	}
+}
+
 // Helper to construct a CSV row string
 func getCSVRow(t *testing.T, id int64, desc string, start, end time.Time, cat string) string {
 	var buf bytes.Buffer
@@ -201,3 +319,430 @@ func getCSVRow(t *testing.T, id int64, desc string, start, end time.Time, cat st
 	w.Flush()
 	return strings.TrimSpace(buf.String())
 }
+
+func TestImportCSVLimits(t *testing.T) {
+	ctx := context.Background()
+
+	var b strings.Builder
+	b.WriteString("description,start_time,end_time\n")
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(&b, "Row %d,2023-01-01T10:00:00Z,2023-01-01T11:00:00Z\n", i)
+	}
+	data := b.String()
+
+	// Too many rows
+	svc := newTestService(t, WithImportLimits(0, 4))
+	err := svc.ImportCSV(ctx, strings.NewReader(data))
+	if !errors.Is(err, ErrImportTooLarge) {
+		t.Fatalf("expected ErrImportTooLarge for rows, got %v", err)
+	}
+	if _, err := svc.PreviewCSV(ctx, strings.NewReader(data), nil); !errors.Is(err, ErrImportTooLarge) {
+		t.Errorf("expected preview to enforce the row limit, got %v", err)
+	}
+	entries, _ := svc.db.ListAllTimeEntries(ctx)
+	if len(entries) != 0 {
+		t.Errorf("expected nothing imported, got %d entries", len(entries))
+	}
+
+	// Too many bytes
+	svc = newTestService(t, WithImportLimits(int64(len(data)-1), 0))
+	if err := svc.ImportCSV(ctx, strings.NewReader(data)); !errors.Is(err, ErrImportTooLarge) {
+		t.Fatalf("expected ErrImportTooLarge for bytes, got %v", err)
+	}
+
+	// Exactly at both limits is accepted
+	svc = newTestService(t, WithImportLimits(int64(len(data)), 5))
+	if err := svc.ImportCSV(ctx, strings.NewReader(data)); err != nil {
+		t.Fatalf("expected import at the limits to succeed, got %v", err)
+	}
+	entries, _ = svc.db.ListAllTimeEntries(ctx)
+	if len(entries) != 5 {
+		t.Errorf("expected 5 entries, got %d", len(entries))
+	}
+}
+
+func TestImportCSVWithProgress(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	data := "description,start_time,end_time\n" +
+		"First,2023-01-01T10:00:00Z,2023-01-01T11:00:00Z\n" +
+		",,\n" + // skipped rows still count
+		"Second,2023-01-02T10:00:00Z,2023-01-02T11:00:00Z\n"
+
+	var calls [][2]int
+	err := svc.ImportCSVWithProgress(ctx, strings.NewReader(data), ImportMerge, nil, func(processed, total int) {
+		calls = append(calls, [2]int{processed, total})
+	})
+	if err != nil {
+		t.Fatalf("ImportCSVWithProgress failed: %v", err)
+	}
+
+	want := [][2]int{{1, 3}, {2, 3}, {3, 3}}
+	if len(calls) != len(want) {
+		t.Fatalf("expected %d progress calls, got %v", len(want), calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d: expected %v, got %v", i, want[i], calls[i])
+		}
+	}
+}
+
+func TestImportCSVReplace(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	kept := seedEntry(t, svc, "Kept #shared", now.Add(-3*time.Hour), now.Add(-2*time.Hour), nil)
+	old := seedEntry(t, svc, "Old only #stale", now.Add(-2*time.Hour), now.Add(-time.Hour), nil)
+
+	data := "id,description,start_time,end_time\n" +
+		fmt.Sprintf("%d,Kept #shared,2023-01-01T10:00:00Z,2023-01-01T11:00:00Z\n", kept.ID) +
+		",New #fresh,2023-01-02T10:00:00Z,2023-01-02T11:00:00Z\n"
+	if err := svc.ImportCSVWithProgress(ctx, strings.NewReader(data), ImportReplace, nil, nil); err != nil {
+		t.Fatalf("ImportCSVWithProgress failed: %v", err)
+	}
+
+	entries, _ := svc.db.ListAllTimeEntries(ctx)
+	if len(entries) != 2 {
+		t.Fatalf("expected only the file's 2 entries, got %+v", entries)
+	}
+	if _, err := svc.GetTimeEntry(ctx, old.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the old-only entry to be gone, got %v", err)
+	}
+	got, err := svc.GetTimeEntry(ctx, kept.ID)
+	if err != nil || got.StartTime.Year() != 2023 {
+		t.Errorf("expected the kept entry to take the file's values, got %+v (%v)", got, err)
+	}
+
+	var names []string
+	tags, _ := svc.ListTags(ctx)
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	if strings.Join(names, ",") != "fresh,shared" {
+		t.Errorf("expected the stale tag to be cleaned up, got %v", names)
+	}
+
+	// A header alone replaces everything with nothing
+	if err := svc.ImportCSVWithProgress(ctx, strings.NewReader("description,start_time\n"), ImportReplace, nil, nil); err != nil {
+		t.Fatalf("ImportCSVWithProgress failed: %v", err)
+	}
+	if entries, _ := svc.db.ListAllTimeEntries(ctx); len(entries) != 0 {
+		t.Errorf("expected no entries, got %d", len(entries))
+	}
+
+	if err := svc.ImportCSVWithProgress(ctx, strings.NewReader(""), ImportReplace, nil, nil); !errors.Is(err, ErrInvalidCSV) {
+		t.Errorf("expected ErrInvalidCSV for an empty file, got %v", err)
+	}
+	if err := svc.ImportCSVWithProgress(ctx, strings.NewReader(data), "wipe", nil, nil); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected ErrValidation for an unknown mode, got %v", err)
+	}
+}
+
+func TestImportCSVDuration(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	data := "description,start_time,end_time,duration\n" +
+		"Clock format,2023-01-01T10:00:00Z,,01:30\n" +
+		"Raw seconds,2023-01-02T10:00:00Z,,5400\n" +
+		"End wins,2023-01-03T10:00:00Z,2023-01-03T11:00:00Z,00:10\n"
+
+	preview, err := svc.PreviewCSV(ctx, strings.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("PreviewCSV failed: %v", err)
+	}
+	if len(preview) != 3 {
+		t.Fatalf("expected 3 preview rows, got %d", len(preview))
+	}
+	if preview[0].Warning != "" || preview[1].Warning != "" {
+		t.Errorf("expected no warnings for duration-only rows, got %q, %q", preview[0].Warning, preview[1].Warning)
+	}
+	if preview[2].Warning == "" {
+		t.Errorf("expected a warning when end_time and duration disagree")
+	}
+
+	if err := svc.ImportCSV(ctx, strings.NewReader(data)); err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	entries, _ := svc.db.ListAllTimeEntries(ctx)
+	got := make(map[string]time.Duration)
+	for _, e := range entries {
+		if !e.EndTime.Valid {
+			t.Fatalf("expected %q to have an end time", e.Description)
+		}
+		got[e.Description] = e.EndTime.Time.Sub(e.StartTime)
+	}
+	want := map[string]time.Duration{
+		"Clock format": 90 * time.Minute,
+		"Raw seconds":  90 * time.Minute,
+		"End wins":     time.Hour,
+	}
+	for desc, d := range want {
+		if got[desc] != d {
+			t.Errorf("%s: expected duration %s, got %s", desc, d, got[desc])
+		}
+	}
+}
+
+func TestParseImportDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"5400", 90 * time.Minute, false},
+		{"0", 0, false},
+		{"01:30", 90 * time.Minute, false},
+		{"1:02:03", time.Hour + 2*time.Minute + 3*time.Second, false},
+		{"10:75", 0, true},
+		{"-5", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseImportDuration(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseImportDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseImportDuration(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseUnixTime(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Time
+		ok    bool
+	}{
+		{"1710000000", time.Unix(1710000000, 0), true},
+		{"1710000000123", time.UnixMilli(1710000000123), true},
+		{"20240101", time.Time{}, false},
+		{"202401011200", time.Time{}, false},
+		{"171000000x", time.Time{}, false},
+		{"-710000000", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseUnixTime(tt.input, time.UTC)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("ParseUnixTime(%q) = %s, %v, want %s, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// Imports share the parser
+	got, err := parseFlexTime("1710000000", time.UTC)
+	if err != nil || !got.Equal(time.Unix(1710000000, 0)) {
+		t.Errorf("parseFlexTime epoch = %s, %v", got, err)
+	}
+	got, err = parseFlexTime("2024-01-01", time.UTC)
+	if err != nil || !got.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseFlexTime date = %s, %v", got, err)
+	}
+}
+
+func TestImportCSVMissingRequiredColumn(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	data := "id,description,end_time\n,No start,2023-01-01T11:00:00Z\n"
+
+	err := svc.ImportCSV(ctx, strings.NewReader(data))
+	if !errors.Is(err, ErrInvalidCSV) || !strings.Contains(err.Error(), "missing column: start_time") {
+		t.Errorf("expected missing start_time error, got %v", err)
+	}
+	_, err = svc.PreviewCSV(ctx, strings.NewReader(data), nil)
+	if !errors.Is(err, ErrInvalidCSV) || !strings.Contains(err.Error(), "missing column: start_time") {
+		t.Errorf("expected preview to report missing start_time, got %v", err)
+	}
+	if count, _ := svc.CountTimeEntries(ctx); count != 0 {
+		t.Errorf("expected nothing imported, got %d entries", count)
+	}
+}
+
+func TestImportCSVRejectsReversedTimes(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	data := "id,description,start_time,end_time\n" +
+		",Fine,2023-01-01T09:00:00Z,2023-01-01T10:00:00Z\n" +
+		",Backwards,2023-01-01T11:00:00Z,2023-01-01T10:30:00+02:00\n"
+
+	err := svc.ImportCSV(ctx, strings.NewReader(data))
+	if !errors.Is(err, ErrInvalidCSV) || !strings.Contains(err.Error(), "row 3") {
+		t.Fatalf("expected row-level time order error, got %v", err)
+	}
+	if count, _ := svc.CountTimeEntries(ctx); count != 0 {
+		t.Errorf("expected failed import to be rolled back, got %d entries", count)
+	}
+
+	// The database backs the check up for every writer, across time zones
+	start := time.Date(2023, 1, 1, 11, 0, 0, 0, time.UTC)
+	tokyo := time.FixedZone("JST", 9*3600)
+	_, err = svc.db.CreateTimeEntryFull(ctx, database.CreateTimeEntryFullParams{
+		Description: "Backwards",
+		StartTime:   start,
+		EndTime:     sql.NullTime{Time: start.Add(-time.Minute).In(tokyo), Valid: true},
+	})
+	if err == nil || !strings.Contains(err.Error(), "end_time before start_time") {
+		t.Errorf("expected database to reject end before start, got %v", err)
+	}
+	entry, err := svc.db.CreateTimeEntryFull(ctx, database.CreateTimeEntryFullParams{
+		Description: "Forwards",
+		StartTime:   start,
+		EndTime:     sql.NullTime{Time: start.Add(time.Minute).In(tokyo), Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("expected later end in another zone to be accepted: %v", err)
+	}
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, entry.Description, start, sql.NullTime{Time: start.Add(-time.Hour), Valid: true}, nil); !errors.Is(err, ErrEndBeforeStart) {
+		t.Errorf("expected update to be rejected, got %v", err)
+	}
+	// Fractions of a second count too
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, entry.Description, start, sql.NullTime{Time: start.Add(-time.Millisecond), Valid: true}, nil); !errors.Is(err, ErrEndBeforeStart) {
+		t.Errorf("expected a millisecond early end to be rejected, got %v", err)
+	}
+}
+
+func TestImportCSVColumnMapping(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	data := "Task,Began,Ended,Project\nMapped,2024-01-01T10:00:00Z,2024-01-01T11:30:00Z,Work\n"
+	mapping := CSVMapping{"description": "Task", "start_time": "began", "end_time": " Ended ", "category": "Project"}
+
+	if err := svc.ImportCSV(ctx, strings.NewReader(data)); !errors.Is(err, ErrInvalidCSV) {
+		t.Fatalf("expected ErrInvalidCSV without a mapping, got %v", err)
+	}
+
+	preview, err := svc.PreviewCSV(ctx, strings.NewReader(data), mapping)
+	if err != nil {
+		t.Fatalf("PreviewCSV failed: %v", err)
+	}
+	if len(preview) != 1 || preview[0].Description != "Mapped" || preview[0].Category != "Work" {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+
+	if err := svc.ImportCSVWithProgress(ctx, strings.NewReader(data), ImportMerge, mapping, nil); err != nil {
+		t.Fatalf("ImportCSVWithProgress failed: %v", err)
+	}
+	entries, _ := svc.db.ListAllTimeEntries(ctx)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Description != "Mapped" || !e.EndTime.Valid || e.EndTime.Time.Sub(e.StartTime) != 90*time.Minute {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if !e.CategoryName.Valid || e.CategoryName.String != "Work" {
+		t.Errorf("expected category Work, got %+v", e.CategoryName)
+	}
+
+	if err := svc.ImportCSVWithProgress(ctx, strings.NewReader(data), ImportMerge, CSVMapping{"description": "Name"}, nil); !errors.Is(err, ErrInvalidCSV) {
+		t.Errorf("expected ErrInvalidCSV for a missing mapped header, got %v", err)
+	}
+	if err := svc.ImportCSVWithProgress(ctx, strings.NewReader(data), ImportMerge, CSVMapping{"summary": "Task"}, nil); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected ErrValidation for an unknown column, got %v", err)
+	}
+}
+
+func TestPreviewCSVReversedTimes(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	data := "description,start_time,end_time\n" +
+		"Fine,2023-01-01T10:00:00Z,2023-01-01T11:00:00Z\n" +
+		"Reversed,2023-01-02T11:00:00Z,2023-01-02T10:00:00Z\n"
+
+	preview, err := svc.PreviewCSV(ctx, strings.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("PreviewCSV failed: %v", err)
+	}
+	if len(preview) != 2 {
+		t.Fatalf("expected 2 preview rows, got %d", len(preview))
+	}
+	if preview[0].Status != "New" || preview[0].Error != "" {
+		t.Errorf("expected the first row to be new, got %+v", preview[0])
+	}
+	if preview[1].Status != "Error" || !strings.Contains(preview[1].Error, "before start_time") {
+		t.Errorf("expected the reversed row to be an error, got %+v", preview[1])
+	}
+
+	err = svc.ImportCSV(ctx, strings.NewReader(data))
+	if !errors.Is(err, ErrInvalidCSV) || !strings.Contains(err.Error(), "row 3") {
+		t.Fatalf("expected ErrInvalidCSV for row 3, got %v", err)
+	}
+	entries, _ := svc.db.ListAllTimeEntries(ctx)
+	if len(entries) != 0 {
+		t.Errorf("expected the rejected import to store nothing, got %d entries", len(entries))
+	}
+}
+
+func TestCSVBillableAndNotesRoundTrip(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Second)
+	entry := seedEntry(t, svc, "Client call", now.Add(-time.Hour), now, nil)
+	if err := svc.SetTimeEntryBillable(ctx, entry.ID, true); err != nil {
+		t.Fatalf("SetTimeEntryBillable failed: %v", err)
+	}
+	if err := svc.db.UpdateTimeEntryNotes(ctx, database.UpdateTimeEntryNotesParams{Notes: "Agenda, then\nfollow-up", ID: entry.ID}); err != nil {
+		t.Fatalf("UpdateTimeEntryNotes failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := svc.ExportCSV(ctx, &buf); err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	exported := buf.String()
+
+	// Reset both, then re-import the export to restore them
+	if err := svc.SetTimeEntryBillable(ctx, entry.ID, false); err != nil {
+		t.Fatalf("SetTimeEntryBillable failed: %v", err)
+	}
+	if err := svc.db.UpdateTimeEntryNotes(ctx, database.UpdateTimeEntryNotesParams{Notes: "", ID: entry.ID}); err != nil {
+		t.Fatalf("UpdateTimeEntryNotes failed: %v", err)
+	}
+
+	preview, err := svc.PreviewCSV(ctx, strings.NewReader(exported), nil)
+	if err != nil {
+		t.Fatalf("PreviewCSV failed: %v", err)
+	}
+	if len(preview) != 1 || preview[0].Status != "Updated" || !preview[0].BillableChanged || !preview[0].NotesChanged || preview[0].DescriptionChanged {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+
+	if err := svc.ImportCSV(ctx, strings.NewReader(exported)); err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	restored, _ := svc.GetTimeEntry(ctx, entry.ID)
+	if !restored.Billable || restored.Notes != "Agenda, then\nfollow-up" {
+		t.Errorf("expected billable entry with notes after import, got billable=%v notes=%q", restored.Billable, restored.Notes)
+	}
+
+	preview, _ = svc.PreviewCSV(ctx, strings.NewReader(exported), nil)
+	if len(preview) != 0 {
+		t.Errorf("expected no changes after import, got %+v", preview)
+	}
+
+	// A file without the columns leaves both alone
+	csvData := fmt.Sprintf("id,description,start_time,end_time\n%d,Client call,%s,%s\n",
+		entry.ID, now.Add(-time.Hour).Format(time.RFC3339), now.Format(time.RFC3339))
+	if err := svc.ImportCSV(ctx, strings.NewReader(csvData)); err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	kept, _ := svc.GetTimeEntry(ctx, entry.ID)
+	if !kept.Billable || kept.Notes != restored.Notes {
+		t.Errorf("expected billable flag and notes to be kept, got billable=%v notes=%q", kept.Billable, kept.Notes)
+	}
+
+	csvData = "description,start_time,billable\nNew,2024-01-01T10:00:00Z,1\nBad,2024-01-01T11:00:00Z,maybe\n"
+	if err := svc.ImportCSV(ctx, strings.NewReader(csvData)); err == nil {
+		t.Error("expected an invalid billable value to be rejected")
+	}
+}
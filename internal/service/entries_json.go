@@ -0,0 +1,394 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
+)
+
+// EntryJSON is the portable form of a time entry. Unlike CSV it carries the
+// entry's tags, notes and billable flag.
+type EntryJSON struct {
+	ID            int64    `json:"id"` // 0 creates a new entry
+	ExternalID    *string  `json:"external_id"`
+	Description   string   `json:"description"`
+	StartTime     string   `json:"start_time"`
+	EndTime       *string  `json:"end_time"` // null while running
+	Category      *string  `json:"category"`
+	CategoryColor *string  `json:"category_color"`
+	Color         *string  `json:"color"`
+	Tags          []string `json:"tags"`
+	Notes         string   `json:"notes"`
+	Billable      bool     `json:"billable"`
+	// CreatedAt is informational and ignored on import.
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// tagBatchSize bounds the IDs per tag query, well below SQLite's limit on
+// bound parameters.
+const tagBatchSize = 500
+
+// ExportJSON returns every entry, newest first, in the form ImportJSON
+// reads. Timestamps are RFC 3339 and missing values are null.
+func (s *Service) ExportJSON(ctx context.Context) ([]EntryJSON, error) {
+	entries, err := s.db.ListAllTimeEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.entriesJSON(ctx, entries)
+}
+
+// GetEntryJSON returns one entry in the form ExportJSON gives it, or
+// ErrNotFound when it does not exist.
+func (s *Service) GetEntryJSON(ctx context.Context, id int64) (EntryJSON, error) {
+	entry, err := s.GetTimeEntry(ctx, id)
+	if err != nil {
+		return EntryJSON{}, err
+	}
+	out, err := s.entriesJSON(ctx, []database.ListAllTimeEntriesRow{database.ListAllTimeEntriesRow(entry)})
+	if err != nil {
+		return EntryJSON{}, err
+	}
+	return out[0], nil
+}
+
+// Entry orders accepted by ListEntriesPage.
+const (
+	SortStartDesc    = "start_desc"
+	SortStartAsc     = "start_asc"
+	SortDurationDesc = "duration_desc"
+)
+
+// entrySorts is the allow-list of orders ListEntriesPage accepts.
+var entrySorts = map[string]bool{
+	SortStartDesc:    true,
+	SortStartAsc:     true,
+	SortDurationDesc: true,
+}
+
+// Page sizes of ListEntriesPage.
+const (
+	DefaultEntriesPageSize = 50
+	MaxEntriesPageSize     = 500
+)
+
+// CheckEntriesPage returns the ErrValidation error ListEntriesPage would
+// return for sort and offset, so a request can be rejected before anything
+// is read.
+func CheckEntriesPage(sort string, offset int) error {
+	if sort != "" && !entrySorts[sort] {
+		return fmt.Errorf("%w: unknown sort '%s'", ErrValidation, sort)
+	}
+	if offset < 0 {
+		return fmt.Errorf("%w: negative offset", ErrValidation)
+	}
+	return nil
+}
+
+// ListEntriesPage returns one page of entries in the given order, along with
+// the total number of entries. An empty sort means SortStartDesc and a
+// non-positive limit DefaultEntriesPageSize. Running entries come last when
+// sorting by duration.
+func (s *Service) ListEntriesPage(ctx context.Context, sort string, limit, offset int) ([]EntryJSON, int64, error) {
+	if err := CheckEntriesPage(sort, offset); err != nil {
+		return nil, 0, err
+	}
+	if sort == "" {
+		sort = SortStartDesc
+	}
+	if limit <= 0 {
+		limit = DefaultEntriesPageSize
+	}
+	limit = min(limit, MaxEntriesPageSize)
+
+	if sort == SortDurationDesc {
+		// Durations need the parsed times, which the stored text cannot be
+		// ordered by in SQL
+		all, err := s.db.ListAllTimeEntries(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		sortByDurationDesc(all)
+		page, err := s.entriesJSON(ctx, all[min(offset, len(all)):min(offset+limit, len(all))])
+		if err != nil {
+			return nil, 0, err
+		}
+		return page, int64(len(all)), nil
+	}
+
+	total, err := s.db.CountTimeEntries(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.db.ListTimeEntriesPage(ctx, database.ListTimeEntriesPageParams{
+		Sort:   sort,
+		Limit:  int64(limit),
+		Offset: int64(offset),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := make([]database.ListAllTimeEntriesRow, len(rows))
+	for i, r := range rows {
+		entries[i] = database.ListAllTimeEntriesRow(r)
+	}
+	page, err := s.entriesJSON(ctx, entries)
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, total, nil
+}
+
+// sortByDurationDesc orders entries longest first, with running entries last
+// and ties broken by the newest start.
+func sortByDurationDesc(entries []database.ListAllTimeEntriesRow) {
+	slices.SortStableFunc(entries, func(a, b database.ListAllTimeEntriesRow) int {
+		if a.EndTime.Valid != b.EndTime.Valid {
+			if a.EndTime.Valid {
+				return -1
+			}
+			return 1
+		}
+		if a.EndTime.Valid {
+			if c := cmp.Compare(b.EndTime.Time.Sub(b.StartTime), a.EndTime.Time.Sub(a.StartTime)); c != 0 {
+				return c
+			}
+		}
+		if c := b.StartTime.Compare(a.StartTime); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+}
+
+// EntriesVersion identifies the current state of the stored entries: it
+// changes whenever an entry, category or tag is added, edited or deleted, as
+// entries are listed with their category and tags. It is a counter bumped by
+// triggers, so changes within the same millisecond still tell apart.
+// LastModified is when the last change happened, zero when there is nothing
+// stored.
+func (s *Service) EntriesVersion(ctx context.Context) (version string, lastModified time.Time, err error) {
+	v, err := s.db.GetTimeEntriesVersion(ctx)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if v.LastUpdated == "" {
+		return "0", time.Time{}, nil
+	}
+	// The triggers of the updated_at column store UTC with milliseconds
+	lastModified, err = time.ParseInLocation("2006-01-02 15:04:05.999", v.LastUpdated, time.UTC)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid updated_at %q: %w", v.LastUpdated, err)
+	}
+	return strconv.FormatInt(v.Version, 10), lastModified, nil
+}
+
+// entriesJSON converts entries to EntryJSON, keeping their order.
+func (s *Service) entriesJSON(ctx context.Context, entries []database.ListAllTimeEntriesRow) ([]EntryJSON, error) {
+	tags := make(map[int64][]string, len(entries))
+	for i := 0; i < len(entries); i += tagBatchSize {
+		batch := entries[i:min(i+tagBatchSize, len(entries))]
+		ids := make([]int64, len(batch))
+		for j, e := range batch {
+			ids[j] = e.ID
+		}
+		rows, err := s.db.ListTagsForTimeEntries(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range rows {
+			tags[t.TimeEntryID] = append(tags[t.TimeEntryID], t.Name)
+		}
+	}
+
+	out := make([]EntryJSON, 0, len(entries))
+	for _, e := range entries {
+		entry := EntryJSON{
+			ID:            e.ID,
+			ExternalID:    nullString(e.ExternalID),
+			Description:   e.Description,
+			StartTime:     e.StartTime.Format(time.RFC3339),
+			Category:      nullString(e.CategoryName),
+			CategoryColor: nullString(e.CategoryColor),
+			Color:         nullString(e.Color),
+			Tags:          tags[e.ID],
+			Notes:         e.Notes,
+			Billable:      e.Billable,
+			CreatedAt:     e.CreatedAt.Format(time.RFC3339),
+		}
+		if e.EndTime.Valid {
+			end := e.EndTime.Time.Format(time.RFC3339)
+			entry.EndTime = &end
+		}
+		if entry.Tags == nil {
+			entry.Tags = []string{}
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
+
+// WriteEntriesJSON writes every entry as indented JSON.
+func (s *Service) WriteEntriesJSON(ctx context.Context, w io.Writer) error {
+	entries, err := s.ExportJSON(ctx)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// ImportJSON upserts a JSON array of entries in one transaction, matching
+// them like ImportCSV. Each entry gets the listed tags in addition to those
+// in its description, and its notes and billable flag are replaced.
+func (s *Service) ImportJSON(ctx context.Context, r io.Reader) error {
+	lr := &io.LimitedReader{R: r, N: s.importMaxBytes + 1}
+	var entries []EntryJSON
+	err := json.NewDecoder(lr).Decode(&entries)
+	if lr.N <= 0 {
+		return fmt.Errorf("%w: more than %d bytes", ErrImportTooLarge, s.importMaxBytes)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	if len(entries) > s.importMaxRows {
+		return fmt.Errorf("%w: more than %d entries", ErrImportTooLarge, s.importMaxRows)
+	}
+
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
+
+	requireLetter, err := tagsRequireLetter(ctx, qtx)
+	if err != nil {
+		return err
+	}
+
+	for i, e := range entries {
+		// Entries are numbered from 1 in errors
+		n := i + 1
+		imported, err := s.importedEntryFromJSON(e)
+		if err != nil {
+			return fmt.Errorf("%w: entry %d: %v", ErrInvalidJSON, n, err)
+		}
+
+		entry, err := saveImportedEntry(ctx, qtx, imported)
+		if err != nil {
+			return fmt.Errorf("failed to save entry: %w", err)
+		}
+
+		if err := qtx.UpdateTimeEntryNotes(ctx, database.UpdateTimeEntryNotesParams{
+			Notes: e.Notes,
+			ID:    entry.ID,
+		}); err != nil {
+			return fmt.Errorf("failed to save notes for entry %d: %w", entry.ID, err)
+		}
+		if err := qtx.UpdateTimeEntryBillable(ctx, database.UpdateTimeEntryBillableParams{
+			Billable: e.Billable,
+			ID:       entry.ID,
+		}); err != nil {
+			return fmt.Errorf("failed to save billable flag for entry %d: %w", entry.ID, err)
+		}
+
+		tags := parseTags(imported.Description, requireLetter, s.tagPattern)
+		for _, tag := range e.Tags {
+			if name := normalizeTagName(tag); name != "" {
+				tags = append(tags, name)
+			}
+		}
+		if err := s.updateTags(ctx, qtx, entry.ID, dedupe(tags)); err != nil {
+			return fmt.Errorf("failed to update tags for entry %d: %w", entry.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *Service) importedEntryFromJSON(e EntryJSON) (importedEntry, error) {
+	imported := importedEntry{
+		ID:          e.ID,
+		ExternalID:  strings.TrimSpace(deref(e.ExternalID)),
+		Description: strings.TrimSpace(e.Description),
+		Category:    strings.TrimSpace(deref(e.Category)),
+	}
+	if imported.Description == "" {
+		return importedEntry{}, fmt.Errorf("description is required")
+	}
+
+	start, err := parseFlexTime(strings.TrimSpace(e.StartTime), s.loc)
+	if err != nil {
+		return importedEntry{}, fmt.Errorf("invalid start_time '%s': %w", e.StartTime, err)
+	}
+	imported.StartTime = start
+	if end := strings.TrimSpace(deref(e.EndTime)); end != "" {
+		t, err := parseFlexTime(end, s.loc)
+		if err != nil {
+			return importedEntry{}, fmt.Errorf("invalid end_time '%s': %w", end, err)
+		}
+		if t.Before(start) {
+			return importedEntry{}, fmt.Errorf("end_time %s is before start_time %s", end, e.StartTime)
+		}
+		imported.EndTime = sql.NullTime{Time: t, Valid: true}
+	}
+
+	if color := strings.TrimSpace(deref(e.CategoryColor)); color != "" {
+		if !colorRegex.MatchString(color) {
+			return importedEntry{}, fmt.Errorf("invalid category_color '%s': expected #RRGGBB", color)
+		}
+		imported.CategoryColor = color
+	}
+	// A missing color keeps an existing override, as with CSV
+	if color := strings.TrimSpace(deref(e.Color)); color != "" {
+		if !colorRegex.MatchString(color) {
+			return importedEntry{}, fmt.Errorf("invalid color '%s': expected #RRGGBB", color)
+		}
+		imported.Color = sql.NullString{String: color, Valid: true}
+	}
+	return imported, nil
+}
+
+// normalizeTagName turns a tag as written by a user, such as "#Go", into the
+// stored lowercase form.
+func normalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+}
+
+func nullString(ns sql.NullString) *string {
+	if !ns.Valid {
+		return nil
+	}
+	return &ns.String
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// dedupe drops repeated values, keeping the first occurrence.
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
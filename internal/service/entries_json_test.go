@@ -0,0 +1,270 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
+)
+
+func TestImportJSON(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	input := `[
+		{
+			"description": "Design review #planning",
+			"start_time": "2024-03-04T09:00:00Z",
+			"end_time": "2024-03-04T10:30:00Z",
+			"category": "Work",
+			"category_color": "#ff0000",
+			"tags": ["#Design", "planning"],
+			"notes": "Agreed on the new layout.\nFollow up on colors.",
+			"billable": true
+		},
+		{
+			"description": "Reading",
+			"start_time": "2024-03-04T20:00:00Z",
+			"end_time": null,
+			"category": null,
+			"tags": ["books"]
+		}
+	]`
+	if err := svc.ImportJSON(ctx, strings.NewReader(input)); err != nil {
+		t.Fatalf("ImportJSON failed: %v", err)
+	}
+
+	entries, _ := svc.db.ListAllTimeEntries(ctx)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	byDesc := map[string]int64{}
+	for _, e := range entries {
+		byDesc[e.Description] = e.ID
+	}
+
+	review, err := svc.GetTimeEntry(ctx, byDesc["Design review #planning"])
+	if err != nil {
+		t.Fatalf("GetTimeEntry failed: %v", err)
+	}
+	if review.CategoryName.String != "Work" || review.CategoryColor.String != "#ff0000" {
+		t.Errorf("expected a red Work category, got %q %q", review.CategoryName.String, review.CategoryColor.String)
+	}
+	if review.Notes != "Agreed on the new layout.\nFollow up on colors." {
+		t.Errorf("unexpected notes %q", review.Notes)
+	}
+	if !review.Billable {
+		t.Error("expected the review to be billable")
+	}
+	if !review.EndTime.Valid || review.EndTime.Time.Sub(review.StartTime) != 90*time.Minute {
+		t.Errorf("unexpected times: %v - %v", review.StartTime, review.EndTime)
+	}
+	if got := entryTagNames(t, svc, review.ID); got != "design,planning" {
+		t.Errorf("expected tags design,planning, got %s", got)
+	}
+
+	reading, _ := svc.GetTimeEntry(ctx, byDesc["Reading"])
+	if reading.EndTime.Valid || reading.CategoryID.Valid || reading.Billable {
+		t.Errorf("expected a running entry without category, got %+v", reading)
+	}
+	if got := entryTagNames(t, svc, reading.ID); got != "books" {
+		t.Errorf("expected tag books, got %s", got)
+	}
+
+	// Importing again by ID updates the entry in place
+	update := fmt.Sprintf(`[{"id": %d, "description": "Design review", "start_time": "2024-03-04T09:00:00Z", "end_time": "2024-03-04T10:00:00Z", "tags": [], "notes": ""}]`, review.ID)
+	if err := svc.ImportJSON(ctx, strings.NewReader(update)); err != nil {
+		t.Fatalf("ImportJSON update failed: %v", err)
+	}
+	review, _ = svc.GetTimeEntry(ctx, review.ID)
+	if review.Description != "Design review" || review.Notes != "" || review.CategoryID.Valid || review.Billable {
+		t.Errorf("expected the entry to be replaced, got %+v", review)
+	}
+	if got := entryTagNames(t, svc, review.ID); got != "" {
+		t.Errorf("expected no tags, got %s", got)
+	}
+}
+
+func TestImportJSONInvalid(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"not an array", `{"description": "x"}`},
+		{"bad start", `[{"description": "x", "start_time": "yesterday"}]`},
+		{"missing description", `[{"start_time": "2024-03-04T09:00:00Z"}]`},
+		{"reversed times", `[{"description": "x", "start_time": "2024-03-04T10:00:00Z", "end_time": "2024-03-04T09:00:00Z"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The first entry is valid and must be rolled back
+			input := tt.input
+			if strings.HasPrefix(input, "[") {
+				input = `[{"description": "ok", "start_time": "2024-03-04T08:00:00Z", "end_time": "2024-03-04T08:30:00Z"}, ` + input[1:]
+			}
+			err := svc.ImportJSON(ctx, strings.NewReader(input))
+			if !errors.Is(err, ErrInvalidJSON) {
+				t.Fatalf("expected ErrInvalidJSON, got %v", err)
+			}
+			if n, _ := svc.CountTimeEntries(ctx); n != 0 {
+				t.Errorf("expected nothing imported, got %d entries", n)
+			}
+		})
+	}
+}
+
+func entryTagNames(t *testing.T, svc *Service, id int64) string {
+	t.Helper()
+	tags, err := svc.db.ListTagsForTimeEntry(context.Background(), id)
+	if err != nil {
+		t.Fatalf("ListTagsForTimeEntry failed: %v", err)
+	}
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestExportJSON(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	work, _ := svc.CreateCategory(ctx, "Work", "#ff0000")
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	done := seedEntry(t, svc, "Review #golang #api", start, start.Add(time.Hour), &work.ID)
+	if err := svc.db.UpdateTimeEntryNotes(ctx, database.UpdateTimeEntryNotesParams{Notes: "Left comments", ID: done.ID}); err != nil {
+		t.Fatalf("UpdateTimeEntryNotes failed: %v", err)
+	}
+	if _, err := svc.StartTimer(ctx, "Running", nil); err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := svc.WriteEntriesJSON(ctx, &buf); err != nil {
+		t.Fatalf("WriteEntriesJSON failed: %v", err)
+	}
+	var raw []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(raw) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(raw))
+	}
+
+	// Newest first: the running timer has nulls and an empty tag list
+	running := raw[0]
+	for _, key := range []string{"end_time", "category", "category_color", "color", "external_id"} {
+		if v, ok := running[key]; !ok || v != nil {
+			t.Errorf("expected %s to be null, got %v", key, v)
+		}
+	}
+	if tags, ok := running["tags"].([]interface{}); !ok || len(tags) != 0 {
+		t.Errorf("expected an empty tag list, got %v", running["tags"])
+	}
+
+	entry := raw[1]
+	want := map[string]interface{}{
+		"id":             float64(done.ID),
+		"description":    "Review #golang #api",
+		"start_time":     "2024-03-04T09:00:00Z",
+		"end_time":       "2024-03-04T10:00:00Z",
+		"category":       "Work",
+		"category_color": "#ff0000",
+		"notes":          "Left comments",
+	}
+	for key, v := range want {
+		if entry[key] != v {
+			t.Errorf("expected %s = %v, got %v", key, v, entry[key])
+		}
+	}
+	if tags, _ := entry["tags"].([]interface{}); len(tags) != 2 || tags[0] != "api" || tags[1] != "golang" {
+		t.Errorf("expected tags [api golang], got %v", entry["tags"])
+	}
+
+	// The export imports into a fresh installation unchanged
+	dst := newTestService(t, WithLocation(time.UTC))
+	if err := dst.ImportJSON(ctx, &buf); err != nil {
+		t.Fatalf("ImportJSON of export failed: %v", err)
+	}
+	src, _ := svc.ExportJSON(ctx)
+	got, _ := dst.ExportJSON(ctx)
+	if len(got) != len(src) {
+		t.Fatalf("expected %d entries after round trip, got %d", len(src), len(got))
+	}
+	for i := range src {
+		// Creation times differ between installations
+		want, have := src[i], got[i]
+		want.CreatedAt, have.CreatedAt = "", ""
+		a, _ := json.Marshal(want)
+		b, _ := json.Marshal(have)
+		if !bytes.Equal(a, b) {
+			t.Errorf("entry %d changed in round trip:\n%s\n%s", i, a, b)
+		}
+	}
+}
+
+func TestListEntriesPage(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	seedEntry(t, svc, "Short", day.Add(9*time.Hour), day.Add(9*time.Hour+30*time.Minute), nil)
+	seedEntry(t, svc, "Long", day.Add(10*time.Hour), day.Add(12*time.Hour), nil)
+	seedEntry(t, svc, "Medium", day.Add(13*time.Hour), day.Add(14*time.Hour), nil)
+	if _, err := svc.StartTimer(ctx, "Running", nil); err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	tests := []struct {
+		sort          string
+		limit, offset int
+		want          string
+	}{
+		{"", 0, 0, "Running Medium Long Short"},
+		{SortStartDesc, 0, 0, "Running Medium Long Short"},
+		{SortStartAsc, 0, 0, "Short Long Medium Running"},
+		{SortDurationDesc, 0, 0, "Long Medium Short Running"},
+		{SortDurationDesc, 2, 1, "Medium Short"},
+		{SortDurationDesc, 10, 5, ""},
+		{SortStartDesc, 2, 1, "Medium Long"},
+		{SortStartAsc, 10, 3, "Running"},
+		{SortStartAsc, 10, 4, ""},
+	}
+	for _, tt := range tests {
+		entries, total, err := svc.ListEntriesPage(ctx, tt.sort, tt.limit, tt.offset)
+		if err != nil {
+			t.Fatalf("ListEntriesPage(%q, %d, %d) failed: %v", tt.sort, tt.limit, tt.offset, err)
+		}
+		if total != 4 {
+			t.Errorf("%s: expected total 4, got %d", tt.sort, total)
+		}
+		names := make([]string, len(entries))
+		for i, e := range entries {
+			names[i] = e.Description
+		}
+		if got := strings.Join(names, " "); got != tt.want {
+			t.Errorf("ListEntriesPage(%q, %d, %d): expected %q, got %q", tt.sort, tt.limit, tt.offset, tt.want, got)
+		}
+	}
+
+	for _, order := range []string{"id", "start_time; DROP TABLE time_entries"} {
+		if _, _, err := svc.ListEntriesPage(ctx, order, 0, 0); !errors.Is(err, ErrValidation) {
+			t.Errorf("sort %q: expected ErrValidation, got %v", order, err)
+		}
+	}
+	if _, _, err := svc.ListEntriesPage(ctx, "", 0, -1); !errors.Is(err, ErrValidation) {
+		t.Errorf("negative offset: expected ErrValidation, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
+)
+
+// FocusStatus describes a focus session: the running one, or one just
+// ended, compared with its planned length.
+type FocusStatus struct {
+	Active         bool   `json:"active"`
+	EntryID        int64  `json:"entry_id,omitempty"`
+	Description    string `json:"description,omitempty"`
+	ElapsedSeconds int64  `json:"elapsed_seconds"`
+	PlannedSeconds int64  `json:"planned_seconds,omitempty"`
+	// RemainingSeconds is nil for a session without a planned length, and
+	// negative once the plan is overrun.
+	RemainingSeconds *int64 `json:"remaining_seconds"`
+}
+
+// StartFocus starts a timer as a focus session planned to last planned, or
+// open-ended when planned is zero. Until the session ends, with EndFocus or
+// by stopping its timer, no other timer can be started.
+func (s *Service) StartFocus(ctx context.Context, description string, categoryID *int64, planned time.Duration) (FocusStatus, error) {
+	if planned < 0 {
+		return FocusStatus{}, fmt.Errorf("%w: planned duration must not be negative", ErrValidation)
+	}
+
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return FocusStatus{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
+
+	// startTimer refuses to interrupt a running session
+	start := s.Now()
+	entry, err := s.startTimer(ctx, qtx, start, description, categoryID)
+	if err != nil {
+		return FocusStatus{}, err
+	}
+
+	var plannedSeconds sql.NullInt64
+	if planned > 0 {
+		plannedSeconds = sql.NullInt64{Int64: int64(planned / time.Second), Valid: true}
+	}
+	if _, err := qtx.CreateFocusSession(ctx, database.CreateFocusSessionParams{
+		TimeEntryID:    entry.ID,
+		StartTime:      start,
+		PlannedSeconds: plannedSeconds,
+	}); err != nil {
+		return FocusStatus{}, fmt.Errorf("failed to record focus session: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return FocusStatus{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return focusStatus(database.GetActiveFocusSessionRow{
+		TimeEntryID:    entry.ID,
+		StartTime:      start,
+		PlannedSeconds: plannedSeconds,
+		Description:    entry.Description,
+	}, start, true), nil
+}
+
+// EndFocus stops the timer of the running focus session and returns how
+// long the session lasted against its plan. It returns ErrNoFocusSession
+// when none is running.
+func (s *Service) EndFocus(ctx context.Context) (FocusStatus, error) {
+	session, err := s.db.GetActiveFocusSession(ctx)
+	if err == sql.ErrNoRows {
+		return FocusStatus{}, ErrNoFocusSession
+	}
+	if err != nil {
+		return FocusStatus{}, err
+	}
+
+	end := s.Now()
+	if err := s.StopTimerAt(ctx, &session.TimeEntryID, end); err != nil {
+		return FocusStatus{}, err
+	}
+	return focusStatus(session, end, false), nil
+}
+
+// GetFocusStatus returns the running focus session, or an inactive status
+// when there is none.
+func (s *Service) GetFocusStatus(ctx context.Context) (FocusStatus, error) {
+	session, err := s.db.GetActiveFocusSession(ctx)
+	if err == sql.ErrNoRows {
+		return FocusStatus{}, nil
+	}
+	if err != nil {
+		return FocusStatus{}, err
+	}
+	return focusStatus(session, s.Now(), true), nil
+}
+
+// focusStatus describes session as of now.
+func focusStatus(session database.GetActiveFocusSessionRow, now time.Time, active bool) FocusStatus {
+	status := FocusStatus{
+		Active:         active,
+		EntryID:        session.TimeEntryID,
+		Description:    session.Description,
+		ElapsedSeconds: int64(now.Sub(session.StartTime) / time.Second),
+	}
+	if session.PlannedSeconds.Valid {
+		status.PlannedSeconds = session.PlannedSeconds.Int64
+		remaining := status.PlannedSeconds - status.ElapsedSeconds
+		status.RemainingSeconds = &remaining
+	}
+	return status
+}
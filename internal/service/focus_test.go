@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFocusSessionLifecycle(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	if status, err := svc.GetFocusStatus(ctx); err != nil || status.Active {
+		t.Fatalf("expected no focus session, got %+v, %v", status, err)
+	}
+	if _, err := svc.EndFocus(ctx); !errors.Is(err, ErrNoFocusSession) {
+		t.Fatalf("expected ErrNoFocusSession, got %v", err)
+	}
+	if _, err := svc.StartFocus(ctx, "Deep work", nil, -time.Minute); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected a negative plan to be rejected, got %v", err)
+	}
+
+	started, err := svc.StartFocus(ctx, "Deep work", nil, 25*time.Minute)
+	if err != nil {
+		t.Fatalf("StartFocus failed: %v", err)
+	}
+	if !started.Active || started.PlannedSeconds != 25*60 || started.RemainingSeconds == nil || *started.RemainingSeconds != 25*60 {
+		t.Errorf("unexpected started session: %+v", started)
+	}
+
+	status, err := svc.GetFocusStatus(ctx)
+	if err != nil {
+		t.Fatalf("GetFocusStatus failed: %v", err)
+	}
+	if !status.Active || status.EntryID != started.EntryID || status.Description != "Deep work" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if status.RemainingSeconds == nil || *status.RemainingSeconds > 25*60 || *status.RemainingSeconds < 25*60-5 {
+		t.Errorf("expected about 25 minutes remaining, got %+v", status.RemainingSeconds)
+	}
+
+	// Switching is refused while the session runs
+	if _, err := svc.StartTimer(ctx, "Email", nil); !errors.Is(err, ErrFocusActive) {
+		t.Errorf("expected ErrFocusActive, got %v", err)
+	}
+	if _, err := svc.StartFocus(ctx, "More deep work", nil, 0); !errors.Is(err, ErrFocusActive) {
+		t.Errorf("expected ErrFocusActive for a second session, got %v", err)
+	}
+
+	ended, err := svc.EndFocus(ctx)
+	if err != nil {
+		t.Fatalf("EndFocus failed: %v", err)
+	}
+	if ended.Active || ended.EntryID != started.EntryID || ended.PlannedSeconds != 25*60 {
+		t.Errorf("unexpected ended session: %+v", ended)
+	}
+	entry, err := svc.GetTimeEntry(ctx, started.EntryID)
+	if err != nil {
+		t.Fatalf("GetTimeEntry failed: %v", err)
+	}
+	if !entry.EndTime.Valid {
+		t.Error("expected ending the session to stop its timer")
+	}
+
+	if status, _ := svc.GetFocusStatus(ctx); status.Active {
+		t.Errorf("expected the session to be over, got %+v", status)
+	}
+	if _, err := svc.StartTimer(ctx, "Email", nil); err != nil {
+		t.Errorf("expected timers to start again after the session, got %v", err)
+	}
+}
+
+func TestFocusSessionEndsWithItsTimer(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	started, err := svc.StartFocus(ctx, "Open-ended", nil, 0)
+	if err != nil {
+		t.Fatalf("StartFocus failed: %v", err)
+	}
+	if started.PlannedSeconds != 0 || started.RemainingSeconds != nil {
+		t.Errorf("expected no plan, got %+v", started)
+	}
+
+	if err := svc.StopTimer(ctx, nil); err != nil {
+		t.Fatalf("StopTimer failed: %v", err)
+	}
+	if status, _ := svc.GetFocusStatus(ctx); status.Active {
+		t.Errorf("expected stopping the timer to end the session, got %+v", status)
+	}
+	if _, err := svc.EndFocus(ctx); !errors.Is(err, ErrNoFocusSession) {
+		t.Errorf("expected ErrNoFocusSession, got %v", err)
+	}
+}
+
+func TestStartFocusIsAtomic(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	// Make recording the session fail after its timer has been created
+	if _, err := svc.rawDB.Exec(`CREATE TRIGGER reject_focus BEFORE INSERT ON focus_sessions
+		BEGIN SELECT RAISE(ABORT, 'rejected'); END`); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	if _, err := svc.StartFocus(ctx, "Deep work", nil, 25*time.Minute); err == nil {
+		t.Fatal("expected StartFocus to fail")
+	}
+	if _, err := svc.db.GetActiveTimeEntry(ctx); err == nil {
+		t.Error("expected the failed session to leave no running timer")
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
+)
+
+// goalPeriods are the report periods a category goal can be set for.
+var goalPeriods = map[string]bool{
+	"today":   true,
+	"week":    true,
+	"month":   true,
+	"quarter": true,
+	"year":    true,
+}
+
+// GoalProgress compares a category's target for a period with the time
+// tracked so far in it.
+type GoalProgress struct {
+	CategoryID    int64   `json:"category_id"`
+	CategoryName  string  `json:"category_name"`
+	Color         string  `json:"color"`
+	Period        string  `json:"period"`
+	TargetSeconds int64   `json:"target_seconds"`
+	ActualSeconds int64   `json:"actual_seconds"`
+	Percentage    float64 `json:"percentage"`
+}
+
+// SetGoal sets the target time of a category for period. A non-positive
+// target removes the goal.
+func (s *Service) SetGoal(ctx context.Context, categoryID int64, period string, target time.Duration) error {
+	if !goalPeriods[period] {
+		return fmt.Errorf("%w: invalid goal period '%s'", ErrValidation, period)
+	}
+	if target <= 0 {
+		return s.db.DeleteGoal(ctx, database.DeleteGoalParams{
+			CategoryID: categoryID,
+			Period:     period,
+		})
+	}
+	if _, err := s.db.GetCategory(ctx, categoryID); err == sql.ErrNoRows {
+		return fmt.Errorf("category %d: %w", categoryID, ErrNotFound)
+	} else if err != nil {
+		return err
+	}
+	return s.db.SetGoal(ctx, database.SetGoalParams{
+		CategoryID:    categoryID,
+		Period:        period,
+		TargetSeconds: int64(target / time.Second),
+	})
+}
+
+// ListGoals returns the goals set for period, ordered by category name.
+func (s *Service) ListGoals(ctx context.Context, period string) ([]database.ListGoalsForPeriodRow, error) {
+	return s.db.ListGoalsForPeriod(ctx, period)
+}
+
+// GetGoalProgress returns the progress of every category that has a goal for
+// the current period. The running timer counts towards its category.
+func (s *Service) GetGoalProgress(ctx context.Context, period string) ([]GoalProgress, error) {
+	if !goalPeriods[period] {
+		return nil, fmt.Errorf("%w: invalid goal period '%s'", ErrValidation, period)
+	}
+	goals, err := s.db.ListGoalsForPeriod(ctx, period)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := s.ReportPeriod(period)
+	report, err := s.GetReport(ctx, ReportFilter{
+		StartDate:      start,
+		EndDate:        end,
+		IncludeRunning: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	actual := make(map[int64]int64, len(report.CategoryBreakdown))
+	for _, b := range report.CategoryBreakdown {
+		actual[b.CategoryID] = b.TotalSeconds
+	}
+
+	progress := make([]GoalProgress, 0, len(goals))
+	for _, g := range goals {
+		p := GoalProgress{
+			CategoryID:    g.CategoryID,
+			CategoryName:  g.CategoryName,
+			Color:         g.CategoryColor,
+			Period:        g.Period,
+			TargetSeconds: g.TargetSeconds,
+			ActualSeconds: actual[g.CategoryID],
+		}
+		if p.TargetSeconds > 0 {
+			p.Percentage = float64(p.ActualSeconds) / float64(p.TargetSeconds) * 100
+		}
+		progress = append(progress, p)
+	}
+	return progress, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetGoalProgress(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	work, _ := svc.CreateCategory(ctx, "Work", "#ff0000")
+	personal, _ := svc.CreateCategory(ctx, "Personal", "#00ff00")
+	if _, err := svc.CreateCategory(ctx, "Errands", "#0000ff"); err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+
+	weekStart, _ := svc.ReportPeriod("week")
+	seedEntry(t, svc, "Coding", weekStart, weekStart.Add(90*time.Minute), &work.ID)
+	seedEntry(t, svc, "Last week", weekStart.Add(-2*time.Hour), weekStart.Add(-time.Hour), &work.ID)
+
+	if err := svc.SetGoal(ctx, work.ID, "week", 3*time.Hour); err != nil {
+		t.Fatalf("SetGoal failed: %v", err)
+	}
+	if err := svc.SetGoal(ctx, personal.ID, "week", time.Hour); err != nil {
+		t.Fatalf("SetGoal failed: %v", err)
+	}
+	if err := svc.SetGoal(ctx, personal.ID, "month", 10*time.Hour); err != nil {
+		t.Fatalf("SetGoal failed: %v", err)
+	}
+
+	progress, err := svc.GetGoalProgress(ctx, "week")
+	if err != nil {
+		t.Fatalf("GetGoalProgress failed: %v", err)
+	}
+	// Errands has no goal and is omitted; ordered by name
+	if len(progress) != 2 {
+		t.Fatalf("expected 2 goals, got %+v", progress)
+	}
+	if p := progress[0]; p.CategoryName != "Personal" || p.TargetSeconds != 3600 || p.ActualSeconds != 0 || p.Percentage != 0 {
+		t.Errorf("unexpected Personal progress: %+v", p)
+	}
+	if p := progress[1]; p.CategoryName != "Work" || p.TargetSeconds != 3*3600 || p.ActualSeconds != 90*60 || p.Percentage != 50 {
+		t.Errorf("unexpected Work progress: %+v", p)
+	}
+
+	// A zero target removes the goal
+	if err := svc.SetGoal(ctx, personal.ID, "week", 0); err != nil {
+		t.Fatalf("SetGoal failed: %v", err)
+	}
+	progress, _ = svc.GetGoalProgress(ctx, "week")
+	if len(progress) != 1 || progress[0].CategoryID != work.ID {
+		t.Errorf("expected only the Work goal, got %+v", progress)
+	}
+
+	if _, err := svc.GetGoalProgress(ctx, "decade"); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected ErrValidation for invalid period, got %v", err)
+	}
+	if err := svc.SetGoal(ctx, work.ID, "decade", time.Hour); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected ErrValidation for invalid period, got %v", err)
+	}
+	if err := svc.SetGoal(ctx, 999, "week", time.Hour); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing category, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
+)
+
+// MergeAdjacentEntries collapses stopped entries with the same description
+// and category into the earliest one, spanning from the earliest start to the
+// latest end. The other entries are deleted. Entries may overlap, but a gap
+// larger than the merge tolerance between consecutive entries is rejected.
+func (s *Service) MergeAdjacentEntries(ctx context.Context, ids []int64) (*database.GetTimeEntryRow, error) {
+	if len(ids) < 2 {
+		return nil, fmt.Errorf("%w: at least two entries are needed to merge", ErrValidation)
+	}
+
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
+
+	entries := make([]database.GetTimeEntryRow, 0, len(ids))
+	seen := make(map[int64]bool)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		e, err := qtx.GetTimeEntry(ctx, id)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("entry %d: %w", id, ErrNotFound)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !e.EndTime.Valid {
+			return nil, fmt.Errorf("%w: entry %d is still running", ErrValidation, id)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) < 2 {
+		return nil, fmt.Errorf("%w: at least two entries are needed to merge", ErrValidation)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].StartTime.Before(entries[j].StartTime)
+	})
+
+	first := entries[0]
+	end := first.EndTime.Time
+	for _, e := range entries[1:] {
+		if e.Description != first.Description {
+			return nil, fmt.Errorf("%w: entry %d has a different description", ErrValidation, e.ID)
+		}
+		if e.CategoryID != first.CategoryID {
+			return nil, fmt.Errorf("%w: entry %d has a different category", ErrValidation, e.ID)
+		}
+		if gap := e.StartTime.Sub(end); gap > s.mergeTolerance {
+			return nil, fmt.Errorf("%w: gap of %s before entry %d exceeds %s", ErrValidation, gap, e.ID, s.mergeTolerance)
+		}
+		if e.EndTime.Time.After(end) {
+			end = e.EndTime.Time
+		}
+	}
+
+	if _, err := qtx.UpdateTimeEntryFull(ctx, database.UpdateTimeEntryFullParams{
+		Description: first.Description,
+		StartTime:   first.StartTime,
+		EndTime:     sql.NullTime{Time: end, Valid: true},
+		CategoryID:  first.CategoryID,
+		ID:          first.ID,
+	}); err != nil {
+		return nil, err
+	}
+
+	requireLetter, err := tagsRequireLetter(ctx, qtx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.updateTags(ctx, qtx, first.ID, parseTags(first.Description, requireLetter, s.tagPattern)); err != nil {
+		return nil, fmt.Errorf("failed to update tags: %w", err)
+	}
+
+	for _, e := range entries[1:] {
+		if err := qtx.DeleteTimeEntry(ctx, e.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete entry %d: %w", e.ID, err)
+		}
+		if err := s.recordAudit(ctx, qtx, e.ID, AuditDelete, &e, nil); err != nil {
+			return nil, fmt.Errorf("failed to record history: %w", err)
+		}
+	}
+
+	merged, err := qtx.GetTimeEntry(ctx, first.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordAudit(ctx, qtx, first.ID, AuditUpdate, &first, &merged); err != nil {
+		return nil, fmt.Errorf("failed to record history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &merged, nil
+}
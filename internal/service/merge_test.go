@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMergeAdjacentEntries(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.Local)
+	a := seedEntry(t, svc, "Writing #docs", base, base.Add(time.Hour), nil)
+	// 30s gap, within the default tolerance
+	b := seedEntry(t, svc, "Writing #docs", base.Add(time.Hour+30*time.Second), base.Add(2*time.Hour), nil)
+	// Overlapping the previous one
+	c := seedEntry(t, svc, "Writing #docs", base.Add(90*time.Minute), base.Add(3*time.Hour), nil)
+
+	merged, err := svc.MergeAdjacentEntries(ctx, []int64{c.ID, a.ID, b.ID})
+	if err != nil {
+		t.Fatalf("MergeAdjacentEntries failed: %v", err)
+	}
+	if merged.ID != a.ID {
+		t.Errorf("expected the earliest entry %d to be kept, got %d", a.ID, merged.ID)
+	}
+	if !merged.StartTime.Equal(base) || !merged.EndTime.Time.Equal(base.Add(3*time.Hour)) {
+		t.Errorf("expected span %v-%v, got %v-%v", base, base.Add(3*time.Hour), merged.StartTime, merged.EndTime.Time)
+	}
+	if count, _ := svc.CountTimeEntries(ctx); count != 1 {
+		t.Errorf("expected absorbed entries to be deleted, got %d entries", count)
+	}
+	tags, _ := svc.db.ListTagsForTimeEntry(ctx, merged.ID)
+	if len(tags) != 1 || tags[0].Name != "docs" {
+		t.Errorf("expected tag 'docs' on merged entry, got %v", tags)
+	}
+}
+
+func TestMergeAdjacentEntriesRejectsGap(t *testing.T) {
+	svc := newTestService(t, WithMergeTolerance(5*time.Minute))
+	ctx := context.Background()
+
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.Local)
+	a := seedEntry(t, svc, "Calls", base, base.Add(time.Hour), nil)
+	b := seedEntry(t, svc, "Calls", base.Add(time.Hour+10*time.Minute), base.Add(2*time.Hour), nil)
+	other := seedEntry(t, svc, "Email", base.Add(2*time.Hour), base.Add(3*time.Hour), nil)
+
+	if _, err := svc.MergeAdjacentEntries(ctx, []int64{a.ID, b.ID}); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected a 10 minute gap to be rejected, got %v", err)
+	}
+	if _, err := svc.MergeAdjacentEntries(ctx, []int64{b.ID, other.ID}); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected different descriptions to be rejected, got %v", err)
+	}
+	if _, err := svc.MergeAdjacentEntries(ctx, []int64{a.ID}); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected a single entry to be rejected, got %v", err)
+	}
+	if count, _ := svc.CountTimeEntries(ctx); count != 3 {
+		t.Errorf("expected rejected merges to change nothing, got %d entries", count)
+	}
+}
@@ -1,36 +1,481 @@
 package service
 
 import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // CalculateReportPeriod returns the start and end times for a given period relative to 'now'.
-// end time is inclusive (e.g. 23:59:59).
+// end time is inclusive (e.g. 23:59:59). Boundaries are computed in now's location
+// and always fall on its local midnights, so periods spanning a DST change are
+// an hour shorter or longer rather than shifted. For "all" both are the zero
+// time, which reports treat as unbounded.
 func CalculateReportPeriod(period string, now time.Time) (time.Time, time.Time) {
 	var start, end time.Time
+	y, m, d := now.Date()
+	loc := now.Location()
 
 	switch period {
 	case "today":
-		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-		end = start.AddDate(0, 0, 1).Add(-time.Second)
+		start = midnight(y, m, d, loc)
+		end = midnight(y, m, d+1, loc).Add(-time.Second)
 	case "week":
 		// Assume week starts on Monday
 		weekday := int(now.Weekday())
 		if weekday == 0 {
 			weekday = 7
 		}
-		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -weekday+1)
-		end = start.AddDate(0, 0, 7).Add(-time.Second)
+		start = midnight(y, m, d-weekday+1, loc)
+		end = midnight(y, m, d-weekday+8, loc).Add(-time.Second)
 	case "month":
-		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-		end = start.AddDate(0, 1, 0).Add(-time.Second)
+		start = midnight(y, m, 1, loc)
+		end = midnight(y, m+1, 1, loc).Add(-time.Second)
+	case "quarter":
+		firstMonth := time.Month((int(m)-1)/3*3 + 1)
+		start = midnight(y, firstMonth, 1, loc)
+		end = midnight(y, firstMonth+3, 1, loc).Add(-time.Second)
 	case "year":
-		start = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
-		end = start.AddDate(1, 0, 0).Add(-time.Second)
-	default: // "all" or anything else
+		start = midnight(y, time.January, 1, loc)
+		end = midnight(y+1, time.January, 1, loc).Add(-time.Second)
+	case "last7", "last30", "last90":
+		// Rolling window: from the start of the day N days ago through the end of today
+		days, _ := strconv.Atoi(strings.TrimPrefix(period, "last"))
+		start = midnight(y, m, d-days, loc)
+		end = midnight(y, m, d+1, loc).Add(-time.Second)
+	default: // "all" or anything else: zero times leave the range unbounded
 		start = time.Time{}
-		end = now.AddDate(100, 0, 0) // Far future
+		end = time.Time{}
 	}
 
 	return start, end
 }
+
+// IsReportPeriod reports whether CalculateReportPeriod knows period rather
+// than falling back to "all" for it.
+func IsReportPeriod(period string) bool {
+	switch period {
+	case "today", "week", "month", "quarter", "year", "last7", "last30", "last90", "all":
+		return true
+	}
+	return false
+}
+
+// midnight returns the first instant of the given day in loc; out of range
+// values are normalized as by time.Date. Where a DST change skips midnight,
+// time.Date would pick an instant on the previous day, so the day starts at
+// the transition instead.
+func midnight(year int, month time.Month, day int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	want := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if y, m, d := t.Date(); y != want.Year() || m != want.Month() || d != want.Day() {
+		_, t = t.ZoneBounds()
+	}
+	return t
+}
+
+// ReportPeriod returns the bounds of period relative to the current time in
+// the service's configured location.
+func (s *Service) ReportPeriod(period string) (time.Time, time.Time) {
+	return CalculateReportPeriod(period, s.Now())
+}
+
+// RecentDailyTotals returns the seconds tracked on each of the last days
+// days, oldest first and ending with today. The running timer counts
+// towards today.
+func (s *Service) RecentDailyTotals(ctx context.Context, days int) ([]int64, error) {
+	now := s.Now()
+	y, m, d := now.Date()
+	loc := now.Location()
+	report, err := s.GetReport(ctx, ReportFilter{
+		StartDate:      midnight(y, m, d-days+1, loc),
+		EndDate:        midnight(y, m, d+1, loc).Add(-time.Second),
+		IncludeRunning: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]int64, len(report.DailyBreakdown))
+	for _, day := range report.DailyBreakdown {
+		byDate[day.Date] = day.TotalSeconds
+	}
+
+	totals := make([]int64, days)
+	for i := range totals {
+		totals[i] = byDate[midnight(y, m, d-days+1+i, loc).Format("2006-01-02")]
+	}
+	return totals, nil
+}
+
+// LastGapStart returns when the current stretch of work began: the start of
+// the earliest entry after the most recent pause longer than the break gap.
+// A running entry counts as ending now. Without entries it returns now.
+func (s *Service) LastGapStart(ctx context.Context) (time.Time, error) {
+	now := s.Now()
+	bounds, err := s.db.ListTimeEntryBounds(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(bounds) == 0 {
+		return now, nil
+	}
+
+	// Oldest first; an entry after a break opens a new stretch. Entries may
+	// overlap, so the stretch lasts until the latest end seen in it.
+	start, last := bounds[0].StartTime, bounds[0].StartTime
+	for _, b := range bounds {
+		if b.StartTime.Sub(last) > s.breakGap {
+			start = b.StartTime
+		}
+		end := now
+		if b.EndTime.Valid {
+			end = b.EndTime.Time
+		}
+		if end.After(last) {
+			last = end
+		}
+	}
+	return start, nil
+}
+
+// addSecondsByDay adds the span from start to end to totals, keyed by
+// YYYY-MM-DD in loc, splitting it at each midnight it crosses. The parts
+// always add up to the whole span's seconds.
+func addSecondsByDay(totals map[string]int64, start, end time.Time, loc *time.Location) {
+	start = start.In(loc)
+	end = end.In(loc)
+	var counted int64
+	for day := start; day.Before(end); {
+		next := midnight(day.Year(), day.Month(), day.Day()+1, loc)
+		if next.After(end) {
+			next = end
+		}
+		upTo := int64(next.Sub(start).Seconds())
+		totals[day.Format("2006-01-02")] += upTo - counted
+		counted = upTo
+		day = next
+	}
+}
+
+// weekdayBreakdown groups daily totals by day of the week, Monday first.
+func weekdayBreakdown(daily []DailyTotal) []WeekdayTotal {
+	totals := make([]WeekdayTotal, 7)
+	for i := range totals {
+		totals[i].Weekday = time.Weekday((i + 1) % 7)
+	}
+	for _, d := range daily {
+		day, err := time.Parse("2006-01-02", d.Date)
+		if err != nil {
+			continue
+		}
+		w := &totals[(int(day.Weekday())+6)%7]
+		w.TotalSeconds += d.TotalSeconds
+		w.Days++
+	}
+	for i := range totals {
+		if totals[i].Days > 0 {
+			totals[i].AvgSeconds = totals[i].TotalSeconds / int64(totals[i].Days)
+		}
+	}
+	return totals
+}
+
+// categoryColumn is a category as it appears in exports; -1 is for entries
+// without one.
+type categoryColumn struct {
+	id   int64
+	name string
+}
+
+// categoryDayTotals sums the report described by filter by day (YYYY-MM-DD)
+// and category. Categories are ordered by name, entries without one last.
+func (s *Service) categoryDayTotals(ctx context.Context, filter ReportFilter) ([]categoryColumn, map[string]map[int64]int64, error) {
+	report, err := s.GetReport(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var columns []categoryColumn
+	cells := make(map[string]map[int64]int64)
+	for _, g := range report.GroupedEntries {
+		columns = append(columns, categoryColumn{id: g.CategoryID, name: g.CategoryName})
+		for _, e := range g.Entries {
+			start, end := filter.countedSpan(e.StartTime, e.EndTime.Time)
+			if filter.SplitAtMidnight {
+				perDay := make(map[string]int64)
+				addSecondsByDay(perDay, start, end, s.loc)
+				for day, seconds := range perDay {
+					if cells[day] == nil {
+						cells[day] = make(map[int64]int64)
+					}
+					cells[day][g.CategoryID] += seconds
+				}
+				continue
+			}
+			day := start.In(s.loc).Format("2006-01-02")
+			if cells[day] == nil {
+				cells[day] = make(map[int64]int64)
+			}
+			cells[day][g.CategoryID] += int64(end.Sub(start).Seconds())
+		}
+	}
+	sort.SliceStable(columns, func(i, j int) bool {
+		if (columns[i].id == -1) != (columns[j].id == -1) {
+			return columns[j].id == -1
+		}
+		return columns[i].name < columns[j].name
+	})
+	return columns, cells, nil
+}
+
+// AnnualReport is a categories × months grid of tracked seconds for one
+// year.
+type AnnualReport struct {
+	Year         int                 `json:"year"`
+	Rows         []AnnualCategoryRow `json:"rows"`
+	MonthTotals  [12]int64           `json:"month_totals"` // January first
+	TotalSeconds int64               `json:"total_seconds"`
+}
+
+// AnnualCategoryRow is the time one category, or the entries without one,
+// got in each month of the year.
+type AnnualCategoryRow struct {
+	CategoryID   int64     `json:"category_id"` // -1 for entries without a category
+	CategoryName string    `json:"category_name"`
+	Color        string    `json:"color"`
+	Months       [12]int64 `json:"months"` // January first
+	TotalSeconds int64     `json:"total_seconds"`
+}
+
+// GetAnnualReport sums the finished entries of year by category and month,
+// in the configured location. Entries crossing midnight are split so that
+// each part counts towards its own month, and only the part inside the year
+// is counted. Categories without time in the year are left out; rows are
+// ordered by name, entries without a category last.
+func (s *Service) GetAnnualReport(ctx context.Context, year int) (AnnualReport, error) {
+	if year < 1 || year > 9999 {
+		return AnnualReport{}, fmt.Errorf("%w: invalid year %d", ErrValidation, year)
+	}
+	filter := ReportFilter{
+		StartDate:          midnight(year, time.January, 1, s.loc),
+		EndDate:            midnight(year+1, time.January, 1, s.loc).Add(-time.Second),
+		SplitAtMidnight:    true,
+		IncludeOverlapping: true,
+	}
+	report, err := s.GetReport(ctx, filter)
+	if err != nil {
+		return AnnualReport{}, err
+	}
+
+	annual := AnnualReport{Year: year, Rows: []AnnualCategoryRow{}}
+	for _, g := range report.GroupedEntries {
+		row := AnnualCategoryRow{CategoryID: g.CategoryID, CategoryName: g.CategoryName, Color: g.Color}
+		perDay := make(map[string]int64)
+		for _, e := range g.Entries {
+			start, end := filter.countedSpan(e.StartTime, e.EndTime.Time)
+			addSecondsByDay(perDay, start, end, s.loc)
+		}
+		for day, seconds := range perDay {
+			d, err := time.Parse("2006-01-02", day)
+			if err != nil || d.Year() != year {
+				continue
+			}
+			row.Months[d.Month()-1] += seconds
+			row.TotalSeconds += seconds
+			annual.MonthTotals[d.Month()-1] += seconds
+		}
+		if row.TotalSeconds == 0 {
+			continue
+		}
+		annual.TotalSeconds += row.TotalSeconds
+		annual.Rows = append(annual.Rows, row)
+	}
+	sort.SliceStable(annual.Rows, func(i, j int) bool {
+		a, b := annual.Rows[i], annual.Rows[j]
+		if (a.CategoryID == -1) != (b.CategoryID == -1) {
+			return b.CategoryID == -1
+		}
+		return a.CategoryName < b.CategoryName
+	})
+	return annual, nil
+}
+
+// ExportTimeseriesCSV writes the report described by filter in long format,
+// one date,category,seconds row per day and category with tracked time,
+// ordered by date. With DecimalHours the last column is hours instead.
+func (s *Service) ExportTimeseriesCSV(ctx context.Context, w io.Writer, filter ReportFilter) error {
+	columns, cells, err := s.categoryDayTotals(ctx, filter)
+	if err != nil {
+		return err
+	}
+	days := make([]string, 0, len(cells))
+	for day := range cells {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	unit := "seconds"
+	if filter.DecimalHours {
+		unit = "hours"
+	}
+	if err := writer.Write([]string{"date", "category", unit}); err != nil {
+		return err
+	}
+	for _, day := range days {
+		for _, c := range columns {
+			v, ok := cells[day][c.id]
+			if !ok {
+				continue
+			}
+			if err := writer.Write([]string{day, c.name, formatExportDuration(v, filter)}); err != nil {
+				return err
+			}
+		}
+	}
+	return writer.Error()
+}
+
+// FormatDecimalHours formats seconds as hours rounded to two decimals, such
+// as "1.50" for an hour and a half, as payroll systems expect.
+func FormatDecimalHours(seconds int64) string {
+	return strconv.FormatFloat(float64(seconds)/3600, 'f', 2, 64)
+}
+
+// currencyFormat describes how amounts in a currency are written.
+type currencyFormat struct {
+	symbol    string
+	thousands string
+	decimal   string
+	after     bool // Symbol after the amount, separated by a space
+}
+
+// currencyFormats are the currencies FormatCurrency knows by ISO 4217 code.
+var currencyFormats = map[string]currencyFormat{
+	"USD": {symbol: "$", thousands: ",", decimal: "."},
+	"CAD": {symbol: "CA$", thousands: ",", decimal: "."},
+	"AUD": {symbol: "A$", thousands: ",", decimal: "."},
+	"GBP": {symbol: "£", thousands: ",", decimal: "."},
+	"EUR": {symbol: "€", thousands: ".", decimal: ",", after: true},
+	"CHF": {symbol: "CHF ", thousands: "'", decimal: "."},
+	"SEK": {symbol: "kr", thousands: " ", decimal: ",", after: true},
+}
+
+// FormatCurrency formats an amount in cents, such as "$1,234.56" for 123456
+// in USD. currency is an ISO 4217 code, matched ignoring case; anything else
+// is taken as the symbol to put before a US-style amount.
+func FormatCurrency(cents int64, currency string) string {
+	f, ok := currencyFormats[strings.ToUpper(currency)]
+	if !ok {
+		f = currencyFormat{symbol: currency, thousands: ",", decimal: "."}
+	}
+
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	units := strconv.FormatInt(cents/100, 10)
+	var b strings.Builder
+	for i, d := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			b.WriteString(f.thousands)
+		}
+		b.WriteRune(d)
+	}
+	amount := fmt.Sprintf("%s%s%02d", b.String(), f.decimal, cents%100)
+
+	if f.after {
+		return sign + amount + " " + f.symbol
+	}
+	return sign + f.symbol + amount
+}
+
+// formatExportDuration formats seconds for a CSV export of filter.
+func formatExportDuration(seconds int64, filter ReportFilter) string {
+	if filter.DecimalHours {
+		return FormatDecimalHours(seconds)
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+// maxPivotDays caps how many calendar days the pivot export enumerates.
+// Longer ranges (e.g. "all") only list days that have data.
+const maxPivotDays = 366
+
+// ExportPivotCSV writes a days × categories matrix of tracked seconds, or
+// decimal hours with DecimalHours, for the report described by filter, with
+// a total per row and a totals row.
+func (s *Service) ExportPivotCSV(ctx context.Context, w io.Writer, filter ReportFilter) error {
+	columns, cells, err := s.categoryDayTotals(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	var days []string
+	start := filter.StartDate.In(s.loc)
+	end := filter.EndDate.In(s.loc)
+	if !filter.StartDate.IsZero() && !filter.EndDate.IsZero() && end.Sub(start) <= maxPivotDays*24*time.Hour {
+		y, m, dd := start.Date()
+		for i := 0; ; i++ {
+			d := midnight(y, m, dd+i, s.loc)
+			if d.After(end) {
+				break
+			}
+			days = append(days, d.Format("2006-01-02"))
+		}
+	} else {
+		for day := range cells {
+			days = append(days, day)
+		}
+		sort.Strings(days)
+	}
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	header := []string{"date"}
+	for _, c := range columns {
+		header = append(header, c.name)
+	}
+	header = append(header, "total")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	columnTotals := make([]int64, len(columns))
+	var grandTotal int64
+	for _, day := range days {
+		record := []string{day}
+		var dayTotal int64
+		for i, c := range columns {
+			v := cells[day][c.id]
+			columnTotals[i] += v
+			dayTotal += v
+			record = append(record, formatExportDuration(v, filter))
+		}
+		grandTotal += dayTotal
+		record = append(record, formatExportDuration(dayTotal, filter))
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	totals := []string{"total"}
+	for _, v := range columnTotals {
+		totals = append(totals, formatExportDuration(v, filter))
+	}
+	totals = append(totals, formatExportDuration(grandTotal, filter))
+	if err := writer.Write(totals); err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
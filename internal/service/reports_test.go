@@ -1,6 +1,11 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -54,6 +59,47 @@ func TestCalculateReportPeriod(t *testing.T) {
 	}
 }
 
+func TestQuarterPeriod(t *testing.T) {
+	// April 20, 2024 is in Q2
+	now := time.Date(2024, time.April, 20, 12, 0, 0, 0, time.UTC)
+
+	start, end := CalculateReportPeriod("quarter", now)
+	expectedStart := "2024-04-01T00:00:00Z"
+	expectedEnd := "2024-06-30T23:59:59Z"
+
+	if start.Format(time.RFC3339) != expectedStart {
+		t.Errorf("expected start %s, got %s", expectedStart, start.Format(time.RFC3339))
+	}
+	if end.Format(time.RFC3339) != expectedEnd {
+		t.Errorf("expected end %s, got %s", expectedEnd, end.Format(time.RFC3339))
+	}
+}
+
+func TestRollingPeriods(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		period        string
+		expectedStart string
+	}{
+		{"last7", "2024-03-03T00:00:00Z"},
+		{"last30", "2024-02-09T00:00:00Z"},
+		{"last90", "2023-12-11T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			start, end := CalculateReportPeriod(tt.period, now)
+			if start.Format(time.RFC3339) != tt.expectedStart {
+				t.Errorf("expected start %s, got %s", tt.expectedStart, start.Format(time.RFC3339))
+			}
+			if end.Format(time.RFC3339) != "2024-03-10T23:59:59Z" {
+				t.Errorf("expected end 2024-03-10T23:59:59Z, got %s", end.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
 func TestWeekBoundarySunday(t *testing.T) {
 	// Sunday Jan 14, 2024
 	now := time.Date(2024, time.January, 14, 12, 0, 0, 0, time.UTC)
@@ -70,3 +116,718 @@ func TestWeekBoundarySunday(t *testing.T) {
 		t.Errorf("expected end %s, got %s", expectedEnd, end.Format(time.RFC3339))
 	}
 }
+
+func TestReportPeriodSpringForward(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+	// Sunday Mar 10, 2024: clocks jump from 02:00 to 03:00
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, ny)
+
+	tests := []struct {
+		period        string
+		expectedStart string
+		expectedEnd   string
+		length        time.Duration
+	}{
+		{"today", "2024-03-10T00:00:00-05:00", "2024-03-10T23:59:59-04:00", 23 * time.Hour},
+		{"week", "2024-03-04T00:00:00-05:00", "2024-03-10T23:59:59-04:00", 7*24*time.Hour - time.Hour},
+		{"month", "2024-03-01T00:00:00-05:00", "2024-03-31T23:59:59-04:00", 31*24*time.Hour - time.Hour},
+		{"last7", "2024-03-03T00:00:00-05:00", "2024-03-10T23:59:59-04:00", 8*24*time.Hour - time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			start, end := CalculateReportPeriod(tt.period, now)
+			if start.Format(time.RFC3339) != tt.expectedStart {
+				t.Errorf("expected start %s, got %s", tt.expectedStart, start.Format(time.RFC3339))
+			}
+			if end.Format(time.RFC3339) != tt.expectedEnd {
+				t.Errorf("expected end %s, got %s", tt.expectedEnd, end.Format(time.RFC3339))
+			}
+			if got := end.Sub(start) + time.Second; got != tt.length {
+				t.Errorf("expected length %s, got %s", tt.length, got)
+			}
+		})
+	}
+}
+
+func TestReportPeriodSkippedMidnight(t *testing.T) {
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+	// Sep 8, 2024 has no midnight in Santiago: clocks jump from 00:00 to 01:00
+	now := time.Date(2024, time.September, 8, 12, 0, 0, 0, santiago)
+
+	start, end := CalculateReportPeriod("today", now)
+	if got := start.Format(time.RFC3339); got != "2024-09-08T01:00:00-03:00" {
+		t.Errorf("expected start 2024-09-08T01:00:00-03:00, got %s", got)
+	}
+	if got := end.Format(time.RFC3339); got != "2024-09-08T23:59:59-03:00" {
+		t.Errorf("expected end 2024-09-08T23:59:59-03:00, got %s", got)
+	}
+
+	// The previous day ends right before the transition
+	_, end = CalculateReportPeriod("today", now.AddDate(0, 0, -1))
+	if got := end.Format(time.RFC3339); got != "2024-09-07T23:59:59-04:00" {
+		t.Errorf("expected end 2024-09-07T23:59:59-04:00, got %s", got)
+	}
+
+	totals := map[string]int64{}
+	addSecondsByDay(totals, time.Date(2024, time.September, 7, 22, 0, 0, 0, santiago), now, santiago)
+	if totals["2024-09-07"] != 7200 || totals["2024-09-08"] != 11*3600 {
+		t.Errorf("unexpected daily split: %v", totals)
+	}
+}
+
+func TestAllPeriodUnbounded(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	start, end := svc.ReportPeriod("all")
+	if !start.IsZero() || !end.IsZero() {
+		t.Fatalf("expected zero bounds for all, got %s - %s", start, end)
+	}
+
+	// Further ahead than the old now+100 years bound
+	future := time.Now().AddDate(200, 0, 0)
+	seedEntry(t, svc, "Far future", future, future.Add(time.Hour), nil)
+	seedEntry(t, svc, "Ancient", time.Date(1900, time.January, 1, 9, 0, 0, 0, time.UTC), time.Date(1900, time.January, 1, 10, 0, 0, 0, time.UTC), nil)
+
+	report, err := svc.GetReport(ctx, ReportFilter{StartDate: start, EndDate: end})
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if len(report.Entries) != 2 || report.TotalSeconds != 7200 {
+		t.Errorf("expected both entries under all, got %d entries and %ds", len(report.Entries), report.TotalSeconds)
+	}
+
+	// An open end keeps the start bound
+	report, err = svc.GetReport(ctx, ReportFilter{StartDate: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if len(report.Entries) != 1 || report.Entries[0].Description != "Far future" {
+		t.Errorf("expected only the future entry, got %+v", report.Entries)
+	}
+}
+
+func TestExportPivotCSV(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	work, _ := svc.CreateCategory(ctx, "Work", "#ff0000")
+	// Monday Jan 15 and Wednesday Jan 17, 2024
+	mon := time.Date(2024, time.January, 15, 9, 0, 0, 0, time.UTC)
+	wed := time.Date(2024, time.January, 17, 9, 0, 0, 0, time.UTC)
+	seedEntry(t, svc, "Work Mon", mon, mon.Add(time.Hour), &work.ID)
+	seedEntry(t, svc, "Loose Mon", mon.Add(2*time.Hour), mon.Add(2*time.Hour+30*time.Minute), nil)
+	seedEntry(t, svc, "Work Wed", wed, wed.Add(2*time.Hour), &work.ID)
+
+	start, end := CalculateReportPeriod("week", mon)
+	var buf bytes.Buffer
+	if err := svc.ExportPivotCSV(ctx, &buf, ReportFilter{StartDate: start, EndDate: end}); err != nil {
+		t.Fatalf("ExportPivotCSV failed: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+
+	// Header + 7 days + totals
+	if len(records) != 9 {
+		t.Fatalf("expected 9 records, got %d", len(records))
+	}
+	expectedHeader := []string{"date", "Work", "No Category", "total"}
+	if strings.Join(records[0], ",") != strings.Join(expectedHeader, ",") {
+		t.Errorf("expected header %v, got %v", expectedHeader, records[0])
+	}
+	if got := strings.Join(records[1], ","); got != "2024-01-15,3600,1800,5400" {
+		t.Errorf("unexpected Monday row: %s", got)
+	}
+	if got := strings.Join(records[2], ","); got != "2024-01-16,0,0,0" {
+		t.Errorf("unexpected Tuesday row: %s", got)
+	}
+	if got := strings.Join(records[8], ","); got != "total,10800,1800,12600" {
+		t.Errorf("unexpected totals row: %s", got)
+	}
+}
+
+func TestExportTimeseriesCSV(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	work, _ := svc.CreateCategory(ctx, "Work", "#ff0000")
+	mon := time.Date(2024, time.January, 15, 9, 0, 0, 0, time.UTC)
+	wed := time.Date(2024, time.January, 17, 9, 0, 0, 0, time.UTC)
+	seedEntry(t, svc, "Work Mon", mon, mon.Add(time.Hour), &work.ID)
+	seedEntry(t, svc, "More Work Mon", mon.Add(3*time.Hour), mon.Add(4*time.Hour), &work.ID)
+	seedEntry(t, svc, "Loose Mon", mon.Add(2*time.Hour), mon.Add(2*time.Hour+30*time.Minute), nil)
+	seedEntry(t, svc, "Work Wed", wed, wed.Add(2*time.Hour), &work.ID)
+
+	start, end := CalculateReportPeriod("month", mon)
+	var buf bytes.Buffer
+	if err := svc.ExportTimeseriesCSV(ctx, &buf, ReportFilter{StartDate: start, EndDate: end}); err != nil {
+		t.Fatalf("ExportTimeseriesCSV failed: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+	want := []string{
+		"date,category,seconds",
+		"2024-01-15,Work,7200",
+		"2024-01-15,No Category,1800",
+		"2024-01-17,Work,7200",
+	}
+	if len(records) != len(want) {
+		t.Fatalf("expected %d records, got %d: %v", len(want), len(records), records)
+	}
+	for i, w := range want {
+		if got := strings.Join(records[i], ","); got != w {
+			t.Errorf("row %d: expected %s, got %s", i, w, got)
+		}
+	}
+}
+
+func TestFormatDecimalHours(t *testing.T) {
+	tests := []struct {
+		seconds int64
+		want    string
+	}{
+		{0, "0.00"},
+		{30 * 60, "0.50"},
+		{90 * 60, "1.50"},
+		{20 * 60, "0.33"},
+		{8*3600 + 45*60, "8.75"},
+	}
+	for _, tt := range tests {
+		if got := FormatDecimalHours(tt.seconds); got != tt.want {
+			t.Errorf("FormatDecimalHours(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCurrency(t *testing.T) {
+	tests := []struct {
+		cents    int64
+		currency string
+		want     string
+	}{
+		{0, "USD", "$0.00"},
+		{5, "USD", "$0.05"},
+		{123456, "USD", "$1,234.56"},
+		{123456789, "usd", "$1,234,567.89"},
+		{-99950, "USD", "-$999.50"},
+		{123456, "EUR", "1.234,56 €"},
+		{100000, "GBP", "£1,000.00"},
+		{1234567, "CHF", "CHF 12'345.67"},
+		{123456, "₹", "₹1,234.56"},
+	}
+	for _, tt := range tests {
+		if got := FormatCurrency(tt.cents, tt.currency); got != tt.want {
+			t.Errorf("FormatCurrency(%d, %q) = %q, want %q", tt.cents, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestExportDecimalHours(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	day := time.Date(2024, time.January, 15, 9, 0, 0, 0, time.UTC)
+	seedEntry(t, svc, "Standup", day, day.Add(30*time.Minute), nil)
+	seedEntry(t, svc, "Review", day.Add(time.Hour), day.Add(150*time.Minute), nil)
+	filter := ReportFilter{StartDate: day.Add(-9 * time.Hour), EndDate: day.Add(15*time.Hour - time.Second), DecimalHours: true}
+
+	var buf bytes.Buffer
+	if err := svc.ExportTimeseriesCSV(ctx, &buf, filter); err != nil {
+		t.Fatalf("ExportTimeseriesCSV failed: %v", err)
+	}
+	if got, want := buf.String(), "date,category,hours\n2024-01-15,No Category,2.00\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	buf.Reset()
+	if err := svc.ExportPivotCSV(ctx, &buf, filter); err != nil {
+		t.Fatalf("ExportPivotCSV failed: %v", err)
+	}
+	if got, want := buf.String(), "date,No Category,total\n2024-01-15,2.00,2.00\ntotal,2.00,2.00\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	report, err := svc.GetReport(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	want := map[string]string{"Standup": "0.50", "Review": "1.50"}
+	for _, e := range report.Entries {
+		if got := FormatDecimalHours(e.Seconds); got != want[e.Description] {
+			t.Errorf("%s: expected %s hours, got %s", e.Description, want[e.Description], got)
+		}
+	}
+}
+
+func TestGetReportAttachesTags(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	tagged := seedEntry(t, svc, "Review #work #urgent", now.Add(-2*time.Hour), now.Add(-time.Hour), nil)
+	plain := seedEntry(t, svc, "Lunch", now.Add(-time.Hour), now, nil)
+
+	report, err := svc.GetReport(ctx, ReportFilter{
+		StartDate: now.Add(-24 * time.Hour),
+		EndDate:   now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if len(report.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(report.Entries))
+	}
+
+	tags := make(map[int64][]string)
+	for _, e := range report.Entries {
+		for _, tag := range e.Tags {
+			tags[e.ID] = append(tags[e.ID], tag.Name)
+		}
+	}
+	if got := strings.Join(tags[tagged.ID], ","); got != "urgent,work" {
+		t.Errorf("expected tags 'urgent,work', got %q", got)
+	}
+	if len(tags[plain.ID]) != 0 {
+		t.Errorf("expected no tags on untagged entry, got %v", tags[plain.ID])
+	}
+
+	// Grouped entries carry the same tags
+	if len(report.GroupedEntries) != 1 || len(report.GroupedEntries[0].Entries) != 2 {
+		t.Fatalf("unexpected groups: %+v", report.GroupedEntries)
+	}
+	for _, e := range report.GroupedEntries[0].Entries {
+		if len(e.Tags) != len(tags[e.ID]) {
+			t.Errorf("entry %d: grouped tags differ from flat tags", e.ID)
+		}
+	}
+}
+
+func TestLastGapStart(t *testing.T) {
+	ctx := context.Background()
+
+	empty := newTestService(t)
+	before := empty.Now()
+	if start, err := empty.LastGapStart(ctx); err != nil || start.Before(before) {
+		t.Errorf("expected now without entries, got %v (%v)", start, err)
+	}
+
+	svc := newTestService(t)
+	now := svc.Now().Truncate(time.Second)
+	seedEntry(t, svc, "Morning", now.Add(-5*time.Hour), now.Add(-4*time.Hour), nil)
+	// An hour-long lunch break, then work with only short pauses
+	seedEntry(t, svc, "Afternoon", now.Add(-3*time.Hour), now.Add(-2*time.Hour-55*time.Minute), nil)
+	seedEntry(t, svc, "Meeting", now.Add(-2*time.Hour-50*time.Minute), now.Add(-2*time.Hour), nil)
+	seedEntry(t, svc, "Call during the meeting", now.Add(-2*time.Hour-30*time.Minute), now.Add(-2*time.Hour-20*time.Minute), nil)
+	if _, err := svc.StartTimerAt(ctx, now.Add(-110*time.Minute), "Running", nil); err != nil {
+		t.Fatalf("StartTimerAt failed: %v", err)
+	}
+
+	start, err := svc.LastGapStart(ctx)
+	if err != nil {
+		t.Fatalf("LastGapStart failed: %v", err)
+	}
+	if want := now.Add(-3 * time.Hour); !start.Equal(want) {
+		t.Errorf("expected the stretch to start after lunch at %v, got %v", want, start)
+	}
+
+	// With a shorter break gap the 10-minute pause before the timer counts
+	svc.breakGap = 5 * time.Minute
+	start, err = svc.LastGapStart(ctx)
+	if err != nil {
+		t.Fatalf("LastGapStart failed: %v", err)
+	}
+	if want := now.Add(-110 * time.Minute); !start.Equal(want) {
+		t.Errorf("expected the stretch to start with the running timer at %v, got %v", want, start)
+	}
+}
+
+func TestGetReportMinDuration(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	seedEntry(t, svc, "Oops", now.Add(-3*time.Hour), now.Add(-3*time.Hour+2*time.Second), nil)
+	long := seedEntry(t, svc, "Deep work", now.Add(-2*time.Hour), now, nil)
+
+	filter := ReportFilter{
+		StartDate: now.Add(-24 * time.Hour),
+		EndDate:   now.Add(time.Hour),
+	}
+	report, err := svc.GetReport(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if len(report.Entries) != 2 {
+		t.Errorf("expected both entries without a minimum, got %d", len(report.Entries))
+	}
+
+	filter.MinDuration = time.Minute
+	report, err = svc.GetReport(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if len(report.Entries) != 1 || report.Entries[0].ID != long.ID {
+		t.Fatalf("expected only the 2-hour entry, got %d entries", len(report.Entries))
+	}
+	if report.TotalSeconds != 7200 {
+		t.Errorf("expected total 7200s, got %d", report.TotalSeconds)
+	}
+	if len(report.CategoryBreakdown) != 1 || report.CategoryBreakdown[0].TotalSeconds != 7200 {
+		t.Errorf("expected breakdown to exclude the short entry, got %+v", report.CategoryBreakdown)
+	}
+}
+
+func TestGetReportDistinctDaysAndStreak(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	now := svc.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	// Active today, yesterday and two days ago; nothing three days ago
+	for _, daysAgo := range []int{0, 0, 1, 2, 4, 5} {
+		start := today.AddDate(0, 0, -daysAgo).Add(time.Minute)
+		seedEntry(t, svc, "Work", start, start.Add(time.Minute), nil)
+	}
+
+	report, err := svc.GetReport(ctx, ReportFilter{StartDate: time.Time{}, EndDate: today.AddDate(0, 0, 1)})
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if report.DistinctDays != 5 {
+		t.Errorf("expected 5 distinct days, got %d", report.DistinctDays)
+	}
+	if report.CurrentStreak != 3 {
+		t.Errorf("expected the gap to end the streak at 3, got %d", report.CurrentStreak)
+	}
+}
+
+func TestCurrentStreak(t *testing.T) {
+	today := time.Date(2024, time.March, 10, 15, 0, 0, 0, time.UTC)
+	days := map[string]bool{"2024-03-09": true, "2024-03-08": true, "2024-03-06": true}
+
+	// Nothing yet today: the streak ending yesterday still counts
+	if got := currentStreak(days, today); got != 2 {
+		t.Errorf("expected streak of 2, got %d", got)
+	}
+	days["2024-03-10"] = true
+	if got := currentStreak(days, today); got != 3 {
+		t.Errorf("expected streak of 3, got %d", got)
+	}
+	if got := currentStreak(map[string]bool{"2024-03-07": true}, today); got != 0 {
+		t.Errorf("expected no streak, got %d", got)
+	}
+}
+
+func TestGetReportWeekdayBreakdown(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	// Two Mondays and one Friday
+	mon1 := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)
+	mon2 := time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC)
+	fri := time.Date(2024, time.March, 8, 9, 0, 0, 0, time.UTC)
+	seedEntry(t, svc, "Mon 1", mon1, mon1.Add(4*time.Hour), nil)
+	seedEntry(t, svc, "Mon 2", mon2, mon2.Add(2*time.Hour), nil)
+	seedEntry(t, svc, "Fri", fri, fri.Add(time.Hour), nil)
+
+	report, err := svc.GetReport(ctx, ReportFilter{
+		StartDate: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, time.March, 31, 23, 59, 59, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if len(report.WeekdayBreakdown) != 7 {
+		t.Fatalf("expected 7 weekdays, got %d", len(report.WeekdayBreakdown))
+	}
+
+	monday := report.WeekdayBreakdown[0]
+	want := WeekdayTotal{Weekday: time.Monday, TotalSeconds: 6 * 3600, Days: 2, AvgSeconds: 3 * 3600}
+	if monday != want {
+		t.Errorf("expected %+v, got %+v", want, monday)
+	}
+	friday := report.WeekdayBreakdown[4]
+	want = WeekdayTotal{Weekday: time.Friday, TotalSeconds: 3600, Days: 1, AvgSeconds: 3600}
+	if friday != want {
+		t.Errorf("expected %+v, got %+v", want, friday)
+	}
+	sunday := report.WeekdayBreakdown[6]
+	if sunday.Weekday != time.Sunday || sunday.TotalSeconds != 0 || sunday.AvgSeconds != 0 {
+		t.Errorf("expected an empty Sunday, got %+v", sunday)
+	}
+}
+
+func TestGetReportSplitAtMidnight(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	start := time.Date(2024, time.March, 4, 23, 30, 0, 0, time.UTC)
+	seedEntry(t, svc, "Late night", start, start.Add(time.Hour), nil)
+	filter := ReportFilter{
+		StartDate: time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, time.March, 6, 0, 0, 0, 0, time.UTC),
+	}
+
+	// Default: the whole entry counts towards its start day
+	report, err := svc.GetReport(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	want := []DailyTotal{{Date: "2024-03-04", TotalSeconds: 3600}}
+	if len(report.DailyBreakdown) != 1 || report.DailyBreakdown[0] != want[0] {
+		t.Errorf("expected %+v, got %+v", want, report.DailyBreakdown)
+	}
+
+	filter.SplitAtMidnight = true
+	report, err = svc.GetReport(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	want = []DailyTotal{
+		{Date: "2024-03-04", TotalSeconds: 1800},
+		{Date: "2024-03-05", TotalSeconds: 1800},
+	}
+	if len(report.DailyBreakdown) != 2 || report.DailyBreakdown[0] != want[0] || report.DailyBreakdown[1] != want[1] {
+		t.Errorf("expected %+v, got %+v", want, report.DailyBreakdown)
+	}
+	if report.TotalSeconds != 3600 {
+		t.Errorf("expected the total to be unchanged, got %d", report.TotalSeconds)
+	}
+
+	var buf bytes.Buffer
+	if err := svc.ExportPivotCSV(ctx, &buf, filter); err != nil {
+		t.Fatalf("ExportPivotCSV failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "2024-03-04,1800,1800") || !strings.Contains(buf.String(), "2024-03-05,1800,1800") {
+		t.Errorf("expected the pivot to split the entry too, got:\n%s", buf.String())
+	}
+}
+
+func TestGetReportIncludeOverlapping(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	day := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	seedEntry(t, svc, "Before", day.Add(-14*time.Hour), day.Add(-13*time.Hour), nil)
+	straddling := seedEntry(t, svc, "Overnight", day.Add(-2*time.Hour), day.Add(2*time.Hour), nil)
+	seedEntry(t, svc, "Morning", day.Add(9*time.Hour), day.Add(10*time.Hour), nil)
+	filter := ReportFilter{StartDate: day, EndDate: day.Add(24*time.Hour - time.Second)}
+
+	// Default: only entries starting inside the range
+	report, err := svc.GetReport(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if len(report.Entries) != 1 || report.TotalSeconds != 3600 {
+		t.Errorf("expected only the morning entry, got %d entries and %ds", len(report.Entries), report.TotalSeconds)
+	}
+
+	filter.IncludeOverlapping = true
+	report, err = svc.GetReport(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if len(report.Entries) != 2 {
+		t.Fatalf("expected the overnight entry to be included, got %+v", report.Entries)
+	}
+	for _, e := range report.Entries {
+		if e.ID == straddling.ID && e.Seconds != 7200 {
+			t.Errorf("expected only the 2h inside the range to count, got %ds", e.Seconds)
+		}
+	}
+	if report.TotalSeconds != 10800 {
+		t.Errorf("expected 10800s, got %d", report.TotalSeconds)
+	}
+	want := DailyTotal{Date: "2024-03-05", TotalSeconds: 10800}
+	if len(report.DailyBreakdown) != 1 || report.DailyBreakdown[0] != want {
+		t.Errorf("expected %+v, got %+v", want, report.DailyBreakdown)
+	}
+
+	var buf bytes.Buffer
+	if err := svc.ExportPivotCSV(ctx, &buf, filter); err != nil {
+		t.Fatalf("ExportPivotCSV failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "2024-03-05,10800,10800") || strings.Contains(buf.String(), "2024-03-04") {
+		t.Errorf("expected the pivot to clamp the entry too, got:\n%s", buf.String())
+	}
+}
+
+func TestGetReportBillable(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	day := time.Date(2024, time.March, 5, 9, 0, 0, 0, time.UTC)
+	billable := seedEntry(t, svc, "Client work", day, day.Add(2*time.Hour), nil)
+	seedEntry(t, svc, "Internal meeting", day.Add(3*time.Hour), day.Add(4*time.Hour), nil)
+	if err := svc.SetTimeEntryBillable(ctx, billable.ID, true); err != nil {
+		t.Fatalf("SetTimeEntryBillable failed: %v", err)
+	}
+	filter := ReportFilter{StartDate: day.Add(-9 * time.Hour), EndDate: day.Add(15 * time.Hour)}
+
+	report, err := svc.GetReport(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if report.TotalSeconds != 10800 || report.BillableSeconds != 7200 || report.NonBillableSeconds() != 3600 {
+		t.Errorf("expected 3h total with 2h billable, got %ds total, %ds billable, %ds non-billable",
+			report.TotalSeconds, report.BillableSeconds, report.NonBillableSeconds())
+	}
+
+	filter.BillableOnly = true
+	report, err = svc.GetReport(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if len(report.Entries) != 1 || report.Entries[0].ID != billable.ID {
+		t.Fatalf("expected only the billable entry, got %+v", report.Entries)
+	}
+	if report.TotalSeconds != 7200 || report.NonBillableSeconds() != 0 {
+		t.Errorf("expected 2h, all billable, got %ds total, %ds non-billable", report.TotalSeconds, report.NonBillableSeconds())
+	}
+}
+
+func TestGetReportDailyTargets(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	for key, value := range map[string]string{
+		SettingDailyTargetPrefix + "monday":    "6",
+		SettingDailyTargetPrefix + "tuesday":   "2.5",
+		SettingDailyTargetPrefix + "wednesday": "1",
+	} {
+		if err := svc.SetSetting(ctx, key, value); err != nil {
+			t.Fatalf("SetSetting failed: %v", err)
+		}
+	}
+
+	monday := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)
+	seedEntry(t, svc, "Short day", monday, monday.Add(4*time.Hour), nil)
+	tuesday := monday.AddDate(0, 0, 1)
+	seedEntry(t, svc, "Long day", tuesday, tuesday.Add(5*time.Hour), nil)
+	wednesday := monday.AddDate(0, 0, 2)
+	seedEntry(t, svc, "On target", wednesday, wednesday.Add(70*time.Minute), nil)
+	thursday := monday.AddDate(0, 0, 3)
+	seedEntry(t, svc, "No target", thursday, thursday.Add(time.Hour), nil)
+
+	report, err := svc.GetReport(ctx, ReportFilter{
+		StartDate: time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, time.March, 8, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	want := []DailyTotal{
+		{Date: "2024-03-04", TotalSeconds: 14400, TargetSeconds: 21600, Target: TargetUnder},
+		{Date: "2024-03-05", TotalSeconds: 18000, TargetSeconds: 9000, Target: TargetOver},
+		{Date: "2024-03-06", TotalSeconds: 4200, TargetSeconds: 3600, Target: TargetMet},
+		{Date: "2024-03-07", TotalSeconds: 3600},
+	}
+	if len(report.DailyBreakdown) != len(want) {
+		t.Fatalf("expected %d days, got %+v", len(want), report.DailyBreakdown)
+	}
+	for i, d := range want {
+		if report.DailyBreakdown[i] != d {
+			t.Errorf("day %d: expected %+v, got %+v", i, d, report.DailyBreakdown[i])
+		}
+	}
+
+	if err := svc.SetSetting(ctx, SettingDailyTargetPrefix+"funday", "1"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	if _, err := svc.DailyTargets(ctx); err == nil {
+		t.Error("expected an error for an unknown weekday")
+	}
+}
+
+func TestRecentDailyTotals(t *testing.T) {
+	svc := newTestService(t, WithLocation(time.UTC))
+	ctx := context.Background()
+
+	now := svc.Now()
+	if now.Hour() == 0 && now.Minute() < 45 {
+		t.Skip("too close to midnight for entries to fall on today")
+	}
+	seedEntry(t, svc, "Today", now.Add(-30*time.Minute), now.Add(-10*time.Minute), nil)
+	seedEntry(t, svc, "Two days ago", now.AddDate(0, 0, -2), now.AddDate(0, 0, -2).Add(time.Hour), nil)
+	seedEntry(t, svc, "Too old", now.AddDate(0, 0, -7), now.AddDate(0, 0, -7).Add(time.Hour), nil)
+
+	totals, err := svc.RecentDailyTotals(ctx, 7)
+	if err != nil {
+		t.Fatalf("RecentDailyTotals failed: %v", err)
+	}
+	if len(totals) != 7 {
+		t.Fatalf("expected 7 days, got %d", len(totals))
+	}
+	if totals[6] != 1200 {
+		t.Errorf("expected today's entry in the last day, got %d", totals[6])
+	}
+	if totals[4] != 3600 {
+		t.Errorf("expected 3600s two days ago, got %d", totals[4])
+	}
+	var sum int64
+	for _, seconds := range totals {
+		sum += seconds
+	}
+	if sum != 4800 {
+		t.Errorf("expected entries older than a week to be left out, got %v", totals)
+	}
+}
+
+func TestGetAnnualReport(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	work, _ := svc.CreateCategory(ctx, "Work", "#ff0000")
+	march := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	seedEntry(t, svc, "Planning", march, march.Add(2*time.Hour), &work.ID)
+	// Split at midnight between January and February
+	jan31 := time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC)
+	seedEntry(t, svc, "Late night", jan31, jan31.Add(2*time.Hour), &work.ID)
+	july := time.Date(2024, 7, 4, 14, 0, 0, 0, time.UTC)
+	seedEntry(t, svc, "Errands", july, july.Add(time.Hour), nil)
+	// Only the hour after New Year counts towards 2024
+	newYear := time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC)
+	seedEntry(t, svc, "Countdown", newYear, newYear.Add(2*time.Hour), nil)
+	seedEntry(t, svc, "Next year", time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC), time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC), &work.ID)
+
+	report, err := svc.GetAnnualReport(ctx, 2024)
+	if err != nil {
+		t.Fatalf("GetAnnualReport failed: %v", err)
+	}
+	if len(report.Rows) != 2 {
+		t.Fatalf("expected Work and uncategorized rows, got %+v", report.Rows)
+	}
+
+	w := report.Rows[0]
+	if w.CategoryID != work.ID || w.Months[time.March-1] != 7200 || w.Months[time.January-1] != 3600 || w.Months[time.February-1] != 3600 || w.TotalSeconds != 14400 {
+		t.Errorf("unexpected Work row: %+v", w)
+	}
+	loose := report.Rows[1]
+	if loose.CategoryID != -1 || loose.CategoryName != DefaultNoCategoryLabel || loose.Months[time.July-1] != 3600 || loose.Months[time.January-1] != 3600 {
+		t.Errorf("unexpected uncategorized row: %+v", loose)
+	}
+	if report.MonthTotals[time.January-1] != 7200 || report.TotalSeconds != 21600 {
+		t.Errorf("unexpected totals: months %v, total %d", report.MonthTotals, report.TotalSeconds)
+	}
+
+	empty, err := svc.GetAnnualReport(ctx, 2020)
+	if err != nil || len(empty.Rows) != 0 || empty.TotalSeconds != 0 {
+		t.Errorf("expected an empty year, got %+v, %v", empty, err)
+	}
+	if _, err := svc.GetAnnualReport(ctx, 0); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected ErrValidation for year 0, got %v", err)
+	}
+}
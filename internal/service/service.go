@@ -4,10 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -15,27 +19,232 @@ import (
 	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
 )
 
+// Default limits applied to CSV imports and previews.
+const (
+	DefaultImportMaxBytes = 10 << 20 // 10 MiB
+	DefaultImportMaxRows  = 100000
+)
+
+// DefaultMergeTolerance is the gap MergeAdjacentEntries allows between
+// entries unless configured otherwise.
+const DefaultMergeTolerance = time.Minute
+
+// DefaultBreakGap is the shortest pause between entries that LastGapStart
+// treats as a break, unless configured otherwise.
+const DefaultBreakGap = 15 * time.Minute
+
+// ErrImportTooLarge is returned when a CSV exceeds the configured import
+// limits.
+var ErrImportTooLarge = errors.New("import too large")
+
+// ErrInvalidCSV is returned when a CSV file lacks the structure needed to
+// import it.
+var ErrInvalidCSV = errors.New("invalid CSV")
+
+// ErrInvalidJSON is returned when a JSON import is malformed or holds an
+// invalid entry.
+var ErrInvalidJSON = errors.New("invalid JSON")
+
+// ErrNotFound is returned when the requested record does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrNoActiveTimer is returned when an operation needs a running timer and
+// none is.
+var ErrNoActiveTimer = errors.New("no timer is running")
+
+// ErrValidation is wrapped by every error caused by invalid input, so callers
+// can tell them from failures of the service itself.
+var ErrValidation = errors.New("invalid input")
+
+// ErrOverlap is returned when a change would make entries overlap.
+var ErrOverlap = errors.New("overlapping entries")
+
+// ErrInvalidStopTime is returned when a timer would end before it started
+// or in the future.
+var ErrInvalidStopTime = fmt.Errorf("%w: stop time must be after the start time and not in the future", ErrValidation)
+
+// ErrActiveEntryChanged is returned when an edit meant for the running timer
+// arrives after that timer was stopped or replaced.
+var ErrActiveEntryChanged = errors.New("the running timer has changed")
+
+// ErrFocusActive is returned when starting a timer would interrupt a focus
+// session.
+var ErrFocusActive = errors.New("a focus session is running")
+
+// ErrNoFocusSession is returned when ending a focus session while none is
+// running.
+var ErrNoFocusSession = errors.New("no focus session is running")
+
+// ErrInvalidStartTime is returned when a backdated timer start is out of
+// range.
+var ErrInvalidStartTime = fmt.Errorf("%w: invalid start time", ErrValidation)
+
+// ErrEndBeforeStart is returned when an entry would end before it starts.
+var ErrEndBeforeStart = fmt.Errorf("%w: end time must not be before the start time", ErrValidation)
+
+// ErrInvalidColor is returned when a color override is not #RRGGBB.
+var ErrInvalidColor = fmt.Errorf("%w: invalid color", ErrValidation)
+
+// MaxStartOffset is how far in the past StartTimerAt may start a timer.
+const MaxStartOffset = 24 * time.Hour
+
+// NoCategory, given as the category of a new timer, leaves it uncategorized
+// instead of applying the default category.
+const NoCategory int64 = -1
+
 type Service struct {
 	db    *database.Queries
 	rawDB *sql.DB
+	loc   *time.Location
+
+	importMaxBytes int64
+	importMaxRows  int
+
+	mergeTolerance time.Duration
+
+	tagPattern *regexp.Regexp
+
+	minEntryDuration time.Duration
+
+	breakGap time.Duration
+
+	multipleTimers bool
 }
 
-func New(db *database.Queries, rawDB *sql.DB) *Service {
-	return &Service{
-		db:    db,
-		rawDB: rawDB,
+// Option configures optional Service behaviour.
+type Option func(*Service)
+
+// WithLocation sets the time zone used to interpret "today", report
+// boundaries and zone-less timestamps. Defaults to time.Local.
+func WithLocation(loc *time.Location) Option {
+	return func(s *Service) {
+		if loc != nil {
+			s.loc = loc
+		}
 	}
 }
 
-var tagRegex = regexp.MustCompile(`#([a-zA-Z0-9_]+)`)
+// WithImportLimits caps the size in bytes and the number of data rows or
+// records of imports and CSV previews. Non-positive values keep the defaults.
+func WithImportLimits(maxBytes int64, maxRows int) Option {
+	return func(s *Service) {
+		if maxBytes > 0 {
+			s.importMaxBytes = maxBytes
+		}
+		if maxRows > 0 {
+			s.importMaxRows = maxRows
+		}
+	}
+}
+
+// WithMergeTolerance sets the largest gap between two entries that
+// MergeAdjacentEntries still treats as contiguous. Negative values are
+// ignored.
+func WithMergeTolerance(d time.Duration) Option {
+	return func(s *Service) {
+		if d >= 0 {
+			s.mergeTolerance = d
+		}
+	}
+}
+
+// WithTagPattern replaces the pattern tags are extracted from descriptions
+// with. Its first capture group is the tag name, so `[#@]([a-zA-Z0-9_]+)`
+// turns both #tags and @mentions into tags. A nil pattern or one without a
+// capture group keeps the default.
+func WithTagPattern(re *regexp.Regexp) Option {
+	return func(s *Service) {
+		if re != nil && re.NumSubexp() > 0 {
+			s.tagPattern = re
+		}
+	}
+}
+
+// WithMinEntryDuration makes StopTimer discard entries shorter than d, such
+// as a timer started and stopped by accident. Zero, the default, keeps all.
+func WithMinEntryDuration(d time.Duration) Option {
+	return func(s *Service) {
+		if d >= 0 {
+			s.minEntryDuration = d
+		}
+	}
+}
+
+// WithBreakGap sets how long a pause between entries must be for
+// LastGapStart to count it as a break. Non-positive values are ignored.
+func WithBreakGap(d time.Duration) Option {
+	return func(s *Service) {
+		if d > 0 {
+			s.breakGap = d
+		}
+	}
+}
+
+// WithMultipleTimers lets several timers run at once: starting one no longer
+// stops the others. GetActiveTimeEntry then returns the most recently
+// started, the primary timer.
+func WithMultipleTimers(enabled bool) Option {
+	return func(s *Service) {
+		s.multipleTimers = enabled
+	}
+}
+
+func New(db *database.Queries, rawDB *sql.DB, opts ...Option) *Service {
+	s := &Service{
+		db:             db,
+		rawDB:          rawDB,
+		loc:            time.Local,
+		importMaxBytes: DefaultImportMaxBytes,
+		importMaxRows:  DefaultImportMaxRows,
+		mergeTolerance: DefaultMergeTolerance,
+		tagPattern:     DefaultTagPattern,
+		breakGap:       DefaultBreakGap,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Close closes the database the service was created with. Closing it again
+// does nothing, and any later call on the service fails.
+func (s *Service) Close() error {
+	return s.rawDB.Close()
+}
+
+// Location returns the configured time zone of the service.
+func (s *Service) Location() *time.Location {
+	return s.loc
+}
+
+// MultipleTimers reports whether several timers may run at once.
+func (s *Service) MultipleTimers() bool {
+	return s.multipleTimers
+}
 
-func parseTags(description string) []string {
-	matches := tagRegex.FindAllStringSubmatch(description, -1)
+// Now returns the current time in the configured time zone.
+func (s *Service) Now() time.Time {
+	return time.Now().In(s.loc)
+}
+
+// DefaultTagPattern matches #tags.
+var DefaultTagPattern = regexp.MustCompile(`#([a-zA-Z0-9_]+)`)
+
+var letterRegex = regexp.MustCompile(`[a-zA-Z]`)
+
+// parseTags extracts the lowercased tags matched by pattern from
+// description. With requireLetter, all-numeric tags such as issue numbers
+// (#123) are skipped.
+func parseTags(description string, requireLetter bool, pattern *regexp.Regexp) []string {
+	matches := pattern.FindAllStringSubmatch(description, -1)
 	var tags []string
 	seen := make(map[string]bool)
 	for _, match := range matches {
 		if len(match) > 1 {
 			tag := strings.ToLower(match[1])
+			if requireLetter && !letterRegex.MatchString(tag) {
+				continue
+			}
 			if !seen[tag] {
 				tags = append(tags, tag)
 				seen[tag] = true
@@ -68,7 +277,7 @@ func (s *Service) updateTags(ctx context.Context, qxt *database.Queries, entryID
 	}
 
 	// Clean up any orphaned tags
-	if err := qxt.DeleteOrphanedTags(ctx); err != nil {
+	if _, err := qxt.DeleteOrphanedTags(ctx); err != nil {
 		return err
 	}
 	return nil
@@ -78,65 +287,357 @@ func (s *Service) ListTimeEntries(ctx context.Context) ([]database.ListTimeEntri
 	return s.db.ListTimeEntries(ctx)
 }
 
+// ListUncategorizedTimeEntries returns the stopped entries without a
+// category, newest first.
+func (s *Service) ListUncategorizedTimeEntries(ctx context.Context) ([]database.ListUncategorizedTimeEntriesRow, error) {
+	return s.db.ListUncategorizedTimeEntries(ctx)
+}
+
+// CountUncategorizedTimeEntries returns the number of stopped entries
+// without a category.
+func (s *Service) CountUncategorizedTimeEntries(ctx context.Context) (int64, error) {
+	return s.db.CountUncategorizedTimeEntries(ctx)
+}
+
+// CountTimeEntries returns the number of time entries, running or not.
+func (s *Service) CountTimeEntries(ctx context.Context) (int64, error) {
+	return s.db.CountTimeEntries(ctx)
+}
+
+// TotalTrackedSeconds returns the time tracked across all completed entries.
+// The running entry is excluded. The sum is done here rather than with
+// strftime in SQL because the driver stores times in a text format SQLite's
+// date functions cannot read.
+func (s *Service) TotalTrackedSeconds(ctx context.Context) (int64, error) {
+	spans, err := s.db.ListCompletedEntrySpans(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, span := range spans {
+		total += int64(span.EndTime.Time.Sub(span.StartTime).Seconds())
+	}
+	return total, nil
+}
+
+// GetActiveTimeEntry returns the running timer. When several are running it
+// returns the primary one, the most recently started.
 func (s *Service) GetActiveTimeEntry(ctx context.Context) (database.GetActiveTimeEntryRow, error) {
 	return s.db.GetActiveTimeEntry(ctx)
 }
 
+// ListActiveTimeEntries returns every running timer, the primary one first.
+func (s *Service) ListActiveTimeEntries(ctx context.Context) ([]database.ListActiveTimeEntriesRow, error) {
+	return s.db.ListActiveTimeEntries(ctx)
+}
+
+// GetTimeEntry returns an entry, or ErrNotFound when it does not exist.
 func (s *Service) GetTimeEntry(ctx context.Context, id int64) (database.GetTimeEntryRow, error) {
-	return s.db.GetTimeEntry(ctx, id)
+	entry, err := s.db.GetTimeEntry(ctx, id)
+	if err == sql.ErrNoRows {
+		return entry, fmt.Errorf("entry %d: %w", id, ErrNotFound)
+	}
+	return entry, err
 }
 
 func (s *Service) ListTags(ctx context.Context) ([]database.Tag, error) {
 	return s.db.ListTags(ctx)
 }
 
+// ListEntriesByTag returns the entries tagged tagName, newest first. The
+// name is matched ignoring case and a leading #, as tags are stored
+// lowercase.
+func (s *Service) ListEntriesByTag(ctx context.Context, tagName string) ([]database.ListTimeEntriesByTagRow, error) {
+	return s.db.ListTimeEntriesByTag(ctx, normalizeTagName(tagName))
+}
+
+// GetTagByName returns the tag named name, matched like ListEntriesByTag,
+// or ErrNotFound.
+func (s *Service) GetTagByName(ctx context.Context, name string) (database.Tag, error) {
+	tag, err := s.db.GetTagByName(ctx, normalizeTagName(name))
+	if err == sql.ErrNoRows {
+		return tag, fmt.Errorf("tag %q: %w", name, ErrNotFound)
+	}
+	return tag, err
+}
+
+// TagCooccurrence returns the other tags that share entries with the tag,
+// with the number of entries they share, most frequent first.
+func (s *Service) TagCooccurrence(ctx context.Context, tagID int64) ([]database.ListTagCooccurrencesRow, error) {
+	return s.db.ListTagCooccurrences(ctx, tagID)
+}
+
+// CleanupOrphanedTags deletes every tag no entry uses, including those
+// created explicitly that the cleanup after entry changes keeps, and returns
+// how many were removed.
+func (s *Service) CleanupOrphanedTags(ctx context.Context) (int, error) {
+	n, err := s.db.DeleteAllOrphanedTags(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// NormalizeTags merges tags whose names differ only by case, such as "Work"
+// and "work", into the lowercase one, as parseTags would name it. Entries
+// keep every tag they had and the canonical tag keeps its color. A group
+// without a lowercase tag has its oldest tag renamed. It returns how many
+// tags were merged away.
+func (s *Service) NormalizeTags(ctx context.Context) (int, error) {
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
+
+	tags, err := qtx.ListTags(ctx)
+	if err != nil {
+		return 0, err
+	}
+	groups := make(map[string][]database.Tag)
+	var names []string
+	for _, t := range tags {
+		name := strings.ToLower(t.Name)
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], t)
+	}
+
+	merged := 0
+	for _, name := range names {
+		group := groups[name]
+		if len(group) == 1 && group[0].Name == name {
+			continue
+		}
+		canonical := group[0]
+		for _, t := range group {
+			if t.Name == name || (canonical.Name != name && t.ID < canonical.ID) {
+				canonical = t
+			}
+		}
+
+		for _, t := range group {
+			if t.ID == canonical.ID {
+				continue
+			}
+			if err := qtx.MoveTimeEntryTags(ctx, database.MoveTimeEntryTagsParams{
+				ToTagID:   canonical.ID,
+				FromTagID: t.ID,
+			}); err != nil {
+				return 0, fmt.Errorf("failed to merge tag '%s': %w", t.Name, err)
+			}
+			if err := qtx.DeleteTagLinks(ctx, t.ID); err != nil {
+				return 0, err
+			}
+			if err := qtx.DeleteTag(ctx, t.ID); err != nil {
+				return 0, err
+			}
+			merged++
+		}
+		// Renamed only once the lowercase name is free
+		if canonical.Name != name {
+			if err := qtx.UpdateTagName(ctx, database.UpdateTagNameParams{ID: canonical.ID, Name: name}); err != nil {
+				return 0, fmt.Errorf("failed to rename tag '%s': %w", canonical.Name, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return merged, nil
+}
+
 func (s *Service) ListCategories(ctx context.Context) ([]database.Category, error) {
 	return s.db.ListCategories(ctx)
 }
 
+// CreateCategory adds a category. It returns ErrDuplicateCategory when
+// another category has the name, whatever its case, and
+// ErrReservedCategoryName when name is the label shown for entries without
+// a category.
 func (s *Service) CreateCategory(ctx context.Context, name, color string) (database.Category, error) {
-	return s.db.CreateCategory(ctx, database.CreateCategoryParams{
+	if err := checkCategoryName(ctx, s.db, name); err != nil {
+		return database.Category{}, err
+	}
+	cat, err := s.db.CreateCategory(ctx, database.CreateCategoryParams{
 		Name:  name,
 		Color: color,
 	})
+	return cat, categoryNameError(err, name)
 }
 
-func (s *Service) UpdateCategory(ctx context.Context, id int64, name, color string) (database.Category, error) {
-	return s.db.UpdateCategory(ctx, database.UpdateCategoryParams{
-		ID:    id,
-		Name:  name,
-		Color: color,
+// UpdateCategory renames and recolors a category and nests it inside
+// parentID, or makes it top-level when parentID is nil. It returns
+// ErrNotFound when no category has the id, ErrCategoryCycle when the parent
+// is the category itself or one of its subcategories, ErrDuplicateCategory
+// when another category has the name and ErrReservedCategoryName when name
+// is the no-category label.
+func (s *Service) UpdateCategory(ctx context.Context, id int64, name, color string, parentID *int64) (database.Category, error) {
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Category{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
+
+	if err := checkCategoryName(ctx, qtx, name); err != nil {
+		return database.Category{}, err
+	}
+
+	var parent sql.NullInt64
+	if parentID != nil {
+		cats, err := qtx.ListCategories(ctx)
+		if err != nil {
+			return database.Category{}, err
+		}
+		if err := checkCategoryParent(cats, id, *parentID); err != nil {
+			return database.Category{}, err
+		}
+		parent = sql.NullInt64{Int64: *parentID, Valid: true}
+	}
+
+	cat, err := qtx.UpdateCategory(ctx, database.UpdateCategoryParams{
+		ID:       id,
+		Name:     name,
+		Color:    color,
+		ParentID: parent,
 	})
+	if err == sql.ErrNoRows {
+		return database.Category{}, fmt.Errorf("category %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return database.Category{}, categoryNameError(err, name)
+	}
+	return cat, tx.Commit()
 }
 
+// DeleteCategory removes a category. It returns ErrNotFound when no category
+// has the id.
 func (s *Service) DeleteCategory(ctx context.Context, id int64) error {
-	return s.db.DeleteCategory(ctx, id)
+	n, err := s.db.DeleteCategory(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("category %d: %w", id, ErrNotFound)
+	}
+	return nil
 }
 
 func (s *Service) GetCategory(ctx context.Context, id int64) (database.Category, error) {
 	return s.db.GetCategory(ctx, id)
 }
 
-func (s *Service) StartTimer(ctx context.Context, description string, categoryID *int64) (*database.GetTimeEntryRow, error) {
-	if description == "" {
-		description = "No description"
+// ReorderCategories puts the categories in ids first, in that order.
+// Categories not listed keep their relative order after them. It returns
+// ErrNotFound when an id does not exist.
+func (s *Service) ReorderCategories(ctx context.Context, ids []int64) error {
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
 
-	tx, err := s.rawDB.Begin()
+	current, err := qtx.ListCategories(ctx)
+	if err != nil {
+		return err
+	}
+	order := make([]int64, 0, len(current))
+	listed := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if !listed[id] {
+			listed[id] = true
+			order = append(order, id)
+		}
+	}
+	for _, c := range current {
+		if !listed[c.ID] {
+			order = append(order, c.ID)
+		}
+	}
+
+	for i, id := range order {
+		n, err := qtx.UpdateCategorySortOrder(ctx, database.UpdateCategorySortOrderParams{
+			SortOrder: int64(i),
+			ID:        id,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("category %d: %w", id, ErrNotFound)
+		}
+	}
+	return tx.Commit()
+}
+
+// StartTimer stops any running timer, unless multiple timers are enabled,
+// and starts a new one. A nil categoryID applies the default category, and
+// NoCategory none. Tags are parsed from the description; tagIDs lists
+// existing tags to attach in addition.
+func (s *Service) StartTimer(ctx context.Context, description string, categoryID *int64, tagIDs ...int64) (*database.GetTimeEntryRow, error) {
+	return s.StartTimerAt(ctx, s.Now(), description, categoryID, tagIDs...)
+}
+
+// StartTimerAt is StartTimer for a timer that was actually started earlier,
+// at start. start may be at most MaxStartOffset in the past; a running timer
+// is stopped at start so the two do not overlap, unless multiple timers are
+// enabled. It returns ErrFocusActive while a focus session runs.
+func (s *Service) StartTimerAt(ctx context.Context, start time.Time, description string, categoryID *int64, tagIDs ...int64) (*database.GetTimeEntryRow, error) {
+	now := s.Now()
+	if start.After(now) || now.Sub(start) > MaxStartOffset {
+		return nil, fmt.Errorf("%w: must be within %s before now", ErrInvalidStartTime, MaxStartOffset)
+	}
+
+	tx, err := s.rawDB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start transaction: %w", err)
 	}
 	defer func() { _ = tx.Rollback() }()
-	qtx := s.db.WithTx(tx)
 
-	// Stop any currently active timer
-	active, err := qtx.GetActiveTimeEntry(ctx)
-	if err == nil {
-		if _, err := qtx.UpdateTimeEntry(ctx, database.UpdateTimeEntryParams{
-			EndTime: sql.NullTime{Time: time.Now(), Valid: true},
-			ID:      active.ID,
-		}); err != nil {
-			log.Printf("Failed to stop previous active timer (ID %d): %v", active.ID, err)
+	entry, err := s.startTimer(ctx, s.db.WithTx(tx), start, description, categoryID, tagIDs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return entry, nil
+}
+
+// startTimer is StartTimerAt within the transaction of qtx.
+func (s *Service) startTimer(ctx context.Context, qtx *database.Queries, start time.Time, description string, categoryID *int64, tagIDs ...int64) (*database.GetTimeEntryRow, error) {
+	// Switching is what a focus session is meant to prevent
+	if _, err := qtx.GetActiveFocusSession(ctx); err == nil {
+		return nil, ErrFocusActive
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if description == "" {
+		var err error
+		description, err = label(ctx, qtx, SettingNoDescriptionLabel, DefaultNoDescriptionLabel)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if categoryID != nil && *categoryID == NoCategory {
+		categoryID = nil
+	} else if categoryID == nil {
+		defaultID, err := defaultCategoryID(ctx, qtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load default category: %w", err)
+		}
+		// Ignore a default pointing at a category that has since been deleted.
+		if defaultID != nil {
+			if _, err := qtx.GetCategory(ctx, *defaultID); err == nil {
+				categoryID = defaultID
+			}
 		}
 	}
 
@@ -145,50 +646,419 @@ func (s *Service) StartTimer(ctx context.Context, description string, categoryID
 		catID = sql.NullInt64{Int64: *categoryID, Valid: true}
 	}
 
+	if s.multipleTimers {
+		// Other timers keep running; only an identical one is reused
+		keep, err := boolSetting(ctx, qtx, SettingKeepIdenticalTimer)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			actives, err := qtx.ListActiveTimeEntries(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, active := range actives {
+				if active.Description == description && active.CategoryID == catID {
+					existing := database.GetTimeEntryRow(active)
+					return &existing, nil
+				}
+			}
+		}
+	} else if active, err := qtx.GetActiveTimeEntry(ctx); err == nil {
+		// Stop the currently active timer
+		keep, err := boolSetting(ctx, qtx, SettingKeepIdenticalTimer)
+		if err != nil {
+			return nil, err
+		}
+		if keep && active.Description == description && active.CategoryID == catID {
+			existing := database.GetTimeEntryRow(active)
+			return &existing, nil
+		}
+		if start.Before(active.StartTime) {
+			return nil, fmt.Errorf("%w: %w: the running timer started later", ErrInvalidStartTime, ErrOverlap)
+		}
+		if err := s.stopEntry(ctx, qtx, database.GetTimeEntryRow(active), start); err != nil {
+			log.Printf("Failed to stop previous active timer (ID %d): %v", active.ID, err)
+		}
+	}
+
 	entry, err := qtx.CreateTimeEntry(ctx, database.CreateTimeEntryParams{
 		Description: description,
-		StartTime:   time.Now(),
+		StartTime:   start,
 		CategoryID:  catID,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create entry: %w", err)
 	}
 
-	tags := parseTags(description)
+	requireLetter, err := tagsRequireLetter(ctx, qtx)
+	if err != nil {
+		return nil, err
+	}
+	tags := parseTags(description, requireLetter, s.tagPattern)
+	for _, tagID := range tagIDs {
+		tag, err := qtx.GetTag(ctx, tagID)
+		if err != nil {
+			return nil, fmt.Errorf("tag %d not found: %w", tagID, err)
+		}
+		if !slices.Contains(tags, tag.Name) {
+			tags = append(tags, tag.Name)
+		}
+	}
 	if err := s.updateTags(ctx, qtx, entry.ID, tags); err != nil {
 		return nil, fmt.Errorf("failed to update tags: %w", err)
 	}
 
+	// Fetch the full entry with category info
+	fullEntry, err := qtx.GetTimeEntry(ctx, entry.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordAudit(ctx, qtx, entry.ID, AuditCreate, nil, &fullEntry); err != nil {
+		return nil, fmt.Errorf("failed to record history: %w", err)
+	}
+	return &fullEntry, nil
+}
+
+// StopTimer stops the running entry id, or the primary timer when id is
+// nil.
+func (s *Service) StopTimer(ctx context.Context, id *int64) error {
+	return s.StopTimerAt(ctx, id, s.Now())
+}
+
+// StopTimerAt ends the running entry id, or the primary timer when id is
+// nil, at end, for when it was stopped late. end must be after the timer's
+// start and not in the future. A timer that ran for less than the configured minimum entry
+// duration is deleted instead. It returns ErrNoActiveTimer when nothing is
+// running or entry id has already stopped.
+func (s *Service) StopTimerAt(ctx context.Context, id *int64, end time.Time) error {
+	if end.After(s.Now()) {
+		return ErrInvalidStopTime
+	}
+
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
+
+	active, err := runningEntry(ctx, qtx, id)
+	if err != nil {
+		return err
+	}
+	if !end.After(active.StartTime) {
+		return ErrInvalidStopTime
+	}
+	if end.Sub(active.StartTime) < s.minEntryDuration {
+		if err := s.discardEntry(ctx, qtx, active); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	if err := s.stopEntry(ctx, qtx, active, end); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runningEntry returns the running entry id, or the primary timer when id
+// is nil.
+func runningEntry(ctx context.Context, q *database.Queries, id *int64) (database.GetTimeEntryRow, error) {
+	if id == nil {
+		active, err := q.GetActiveTimeEntry(ctx)
+		if err == sql.ErrNoRows {
+			return database.GetTimeEntryRow{}, ErrNoActiveTimer
+		}
+		return database.GetTimeEntryRow(active), err
+	}
+	entry, err := q.GetTimeEntry(ctx, *id)
+	if err == sql.ErrNoRows {
+		return entry, fmt.Errorf("entry %d: %w", *id, ErrNotFound)
+	}
+	if err != nil {
+		return entry, err
+	}
+	if entry.EndTime.Valid {
+		return entry, fmt.Errorf("entry %d: %w", *id, ErrNoActiveTimer)
+	}
+	return entry, nil
+}
+
+// discardEntry deletes an entry together with its tags and records the
+// deletion.
+func (s *Service) discardEntry(ctx context.Context, q *database.Queries, before database.GetTimeEntryRow) error {
+	if err := q.DeleteTimeEntryTags(ctx, before.ID); err != nil {
+		return err
+	}
+	if err := q.DeleteTimeEntry(ctx, before.ID); err != nil {
+		return err
+	}
+	if _, err := q.DeleteOrphanedTags(ctx); err != nil {
+		return err
+	}
+	if err := s.recordAudit(ctx, q, before.ID, AuditDelete, &before, nil); err != nil {
+		return fmt.Errorf("failed to record history: %w", err)
+	}
+	return nil
+}
+
+// stopEntry ends the running entry at end and records the change.
+func (s *Service) stopEntry(ctx context.Context, q *database.Queries, before database.GetTimeEntryRow, end time.Time) error {
+	if _, err := q.UpdateTimeEntry(ctx, database.UpdateTimeEntryParams{
+		EndTime: sql.NullTime{Time: end, Valid: true},
+		ID:      before.ID,
+	}); err != nil {
+		return err
+	}
+	after, err := q.GetTimeEntry(ctx, before.ID)
+	if err != nil {
+		return err
+	}
+	return s.recordAudit(ctx, q, before.ID, AuditUpdate, &before, &after)
+}
+
+// SetEntriesCategory assigns categoryID to every entry in ids, or clears
+// their category when it is nil, in one transaction. It returns the updated
+// entries in the order given, or ErrNotFound when an entry or the category
+// does not exist.
+func (s *Service) SetEntriesCategory(ctx context.Context, ids []int64, categoryID *int64) ([]database.GetTimeEntryRow, error) {
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
+
+	var catID sql.NullInt64
+	if categoryID != nil {
+		if _, err := qtx.GetCategory(ctx, *categoryID); err == sql.ErrNoRows {
+			return nil, fmt.Errorf("category %d: %w", *categoryID, ErrNotFound)
+		} else if err != nil {
+			return nil, err
+		}
+		catID = sql.NullInt64{Int64: *categoryID, Valid: true}
+	}
+
+	updated := make([]database.GetTimeEntryRow, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		before, err := qtx.GetTimeEntry(ctx, id)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("entry %d: %w", id, ErrNotFound)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := qtx.UpdateTimeEntryFull(ctx, database.UpdateTimeEntryFullParams{
+			Description: before.Description,
+			StartTime:   before.StartTime,
+			EndTime:     before.EndTime,
+			CategoryID:  catID,
+			ID:          id,
+		}); err != nil {
+			return nil, err
+		}
+		after, err := qtx.GetTimeEntry(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.recordAudit(ctx, qtx, id, AuditUpdate, &before, &after); err != nil {
+			return nil, fmt.Errorf("failed to record history: %w", err)
+		}
+		updated = append(updated, after)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	return updated, nil
+}
 
-	// Fetch the full entry with category info
-	fullEntry, err := s.db.GetTimeEntry(ctx, entry.ID)
-	return &fullEntry, err
+// StaleTimerPolicy controls what ReconcileActiveOnStartup does with a timer
+// that has been running longer than the threshold.
+type StaleTimerPolicy int
+
+const (
+	StaleTimerWarn StaleTimerPolicy = iota // Leave the timer running
+	StaleTimerStop                         // Stop it at start + threshold
+)
+
+// ReconcileActiveOnStartup looks for timers left running by a previous
+// process, e.g. after a crash. Every running entry that started more than
+// maxAge ago is returned, newest first, and with StaleTimerStop each is ended
+// at its start + maxAge so it stops accruing time. It returns nil when there
+// is no stale timer or maxAge is not positive.
+func (s *Service) ReconcileActiveOnStartup(ctx context.Context, maxAge time.Duration, policy StaleTimerPolicy) ([]database.ListActiveTimeEntriesRow, error) {
+	if maxAge <= 0 {
+		return nil, nil
+	}
+
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
+
+	// With multiple timers an old background timer can sit behind a fresh one
+	actives, err := qtx.ListActiveTimeEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var stale []database.ListActiveTimeEntriesRow
+	for _, active := range actives {
+		if s.Now().Sub(active.StartTime) <= maxAge {
+			continue
+		}
+		if policy == StaleTimerStop {
+			end := active.StartTime.Add(maxAge)
+			if _, err := qtx.UpdateTimeEntry(ctx, database.UpdateTimeEntryParams{
+				EndTime: sql.NullTime{Time: end, Valid: true},
+				ID:      active.ID,
+			}); err != nil {
+				return nil, fmt.Errorf("failed to stop stale timer %d: %w", active.ID, err)
+			}
+			before := database.GetTimeEntryRow(active)
+			after, err := qtx.GetTimeEntry(ctx, active.ID)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.recordAudit(ctx, qtx, active.ID, AuditUpdate, &before, &after); err != nil {
+				return nil, fmt.Errorf("failed to record history: %w", err)
+			}
+			active.EndTime = after.EndTime
+		}
+		stale = append(stale, active)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return stale, nil
+}
+
+// EntryChange is an optional change UpdateTimeEntry makes in the same
+// transaction as the main fields.
+type EntryChange func(*entryChanges)
+
+type entryChanges struct {
+	color    *string
+	billable *bool
 }
 
-func (s *Service) StopTimer(ctx context.Context) error {
-	active, err := s.db.GetActiveTimeEntry(ctx)
+// WithEntryColor overrides the display color of the entry; an empty color
+// clears the override.
+func WithEntryColor(color string) EntryChange {
+	return func(c *entryChanges) {
+		c.color = &color
+	}
+}
+
+// WithEntryBillable sets whether the time of the entry can be billed.
+func WithEntryBillable(billable bool) EntryChange {
+	return func(c *entryChanges) {
+		c.billable = &billable
+	}
+}
+
+// UpdateTimeEntry replaces the fields of an entry, together with those the
+// given changes set, and records the edit. It returns ErrNotFound when the
+// entry does not exist and an ErrValidation error when it would end before it
+// starts or has an invalid color.
+func (s *Service) UpdateTimeEntry(ctx context.Context, id int64, description string, start time.Time, end sql.NullTime, categoryID *int64, changes ...EntryChange) (*database.GetTimeEntryRow, error) {
+	var extra entryChanges
+	for _, change := range changes {
+		change(&extra)
+	}
+	if extra.color != nil {
+		if err := checkEntryColor(*extra.color); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.rawDB.BeginTx(ctx, nil)
 	if err != nil {
-		return nil // Nothing to stop
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
 
-	_, err = s.db.UpdateTimeEntry(ctx, database.UpdateTimeEntryParams{
-		EndTime: sql.NullTime{Time: time.Now(), Valid: true},
-		ID:      active.ID,
-	})
-	return err
+	before, err := qtx.GetTimeEntry(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("entry %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry, err := s.updateEntry(ctx, qtx, before, description, start, end, categoryID, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return entry, nil
 }
 
-func (s *Service) UpdateTimeEntry(ctx context.Context, id int64, description string, start time.Time, end sql.NullTime, categoryID *int64) (*database.GetTimeEntryRow, error) {
-	tx, err := s.rawDB.Begin()
+// UpdateActiveEntry changes the description and category of the running
+// timer, and its start unless start is zero. The timer is looked up in the
+// same transaction as the update, so a timer stopped in the meantime is never
+// edited, or worse restarted. A non-zero entryID is the timer the caller saw;
+// if another one is running by now, ErrActiveEntryChanged is returned. A
+// start after now gives ErrInvalidStartTime.
+func (s *Service) UpdateActiveEntry(ctx context.Context, entryID int64, description string, categoryID *int64, start time.Time) (*database.GetTimeEntryRow, error) {
+	if start.After(s.Now()) {
+		return nil, fmt.Errorf("%w: the timer cannot start in the future", ErrInvalidStartTime)
+	}
+
+	tx, err := s.rawDB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start transaction: %w", err)
 	}
 	defer func() { _ = tx.Rollback() }()
 	qtx := s.db.WithTx(tx)
 
+	active, err := qtx.GetActiveTimeEntry(ctx)
+	if err == sql.ErrNoRows {
+		if entryID != 0 {
+			return nil, ErrActiveEntryChanged
+		}
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entryID != 0 && active.ID != entryID {
+		return nil, ErrActiveEntryChanged
+	}
+
+	if start.IsZero() {
+		start = active.StartTime
+	}
+	entry, err := s.updateEntry(ctx, qtx, database.GetTimeEntryRow(active), description, start, active.EndTime, categoryID, entryChanges{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return entry, nil
+}
+
+// updateEntry replaces the fields of before, applies extra, re-parses its
+// tags and records the change.
+func (s *Service) updateEntry(ctx context.Context, qtx *database.Queries, before database.GetTimeEntryRow, description string, start time.Time, end sql.NullTime, categoryID *int64, extra entryChanges) (*database.GetTimeEntryRow, error) {
+	if err := checkTimeOrder(start, end); err != nil {
+		return nil, err
+	}
+	id := before.ID
 	var catID sql.NullInt64
 	if categoryID != nil {
 		catID = sql.NullInt64{Int64: *categoryID, Valid: true}
@@ -204,49 +1074,315 @@ func (s *Service) UpdateTimeEntry(ctx context.Context, id int64, description str
 	if err != nil {
 		return nil, err
 	}
+	if extra.color != nil {
+		if err := qtx.UpdateTimeEntryColor(ctx, database.UpdateTimeEntryColorParams{
+			Color: sql.NullString{String: *extra.color, Valid: *extra.color != ""},
+			ID:    id,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if extra.billable != nil {
+		if err := qtx.UpdateTimeEntryBillable(ctx, database.UpdateTimeEntryBillableParams{
+			Billable: *extra.billable,
+			ID:       id,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	requireLetter, err := tagsRequireLetter(ctx, qtx)
+	if err != nil {
+		return nil, err
+	}
+	tags := parseTags(description, requireLetter, s.tagPattern)
+	if err := s.updateTags(ctx, qtx, entry.ID, tags); err != nil {
+		return nil, fmt.Errorf("failed to update tags: %w", err)
+	}
+
+	fullEntry, err := qtx.GetTimeEntry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordAudit(ctx, qtx, id, AuditUpdate, &before, &fullEntry); err != nil {
+		return nil, fmt.Errorf("failed to record history: %w", err)
+	}
+	return &fullEntry, nil
+}
+
+var colorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// checkEntryColor validates the color override of an entry; empty means
+// none.
+func checkEntryColor(color string) error {
+	if color != "" && !colorRegex.MatchString(color) {
+		return fmt.Errorf("%w %q: expected #RRGGBB", ErrInvalidColor, color)
+	}
+	return nil
+}
+
+// SetTimeEntryColor overrides the display color of an entry. An empty color
+// clears the override so the entry inherits its category's color again. It
+// returns ErrNotFound when the entry does not exist.
+func (s *Service) SetTimeEntryColor(ctx context.Context, id int64, color string) error {
+	if err := checkEntryColor(color); err != nil {
+		return err
+	}
+	return s.changeEntry(ctx, id, func(q *database.Queries) error {
+		return q.UpdateTimeEntryColor(ctx, database.UpdateTimeEntryColorParams{
+			Color: sql.NullString{String: color, Valid: color != ""},
+			ID:    id,
+		})
+	})
+}
+
+// SetTimeEntryBillable marks whether the time of an entry can be billed. It
+// returns ErrNotFound when the entry does not exist.
+func (s *Service) SetTimeEntryBillable(ctx context.Context, id int64, billable bool) error {
+	return s.changeEntry(ctx, id, func(q *database.Queries) error {
+		return q.UpdateTimeEntryBillable(ctx, database.UpdateTimeEntryBillableParams{
+			Billable: billable,
+			ID:       id,
+		})
+	})
+}
+
+// changeEntry applies change to entry id in a transaction and records the
+// edit.
+func (s *Service) changeEntry(ctx context.Context, id int64, change func(q *database.Queries) error) error {
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
+
+	before, err := qtx.GetTimeEntry(ctx, id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("entry %d: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if err := change(qtx); err != nil {
+		return err
+	}
+	after, err := qtx.GetTimeEntry(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.recordAudit(ctx, qtx, id, AuditUpdate, &before, &after); err != nil {
+		return fmt.Errorf("failed to record history: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (s *Service) DeleteTimeEntry(ctx context.Context, id int64) error {
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
+
+	before, err := qtx.GetTimeEntry(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil // Already gone
+	}
+	if err != nil {
+		return err
+	}
+	if err := qtx.DeleteTimeEntry(ctx, id); err != nil {
+		return err
+	}
+	if err := s.recordAudit(ctx, qtx, id, AuditDelete, &before, nil); err != nil {
+		return fmt.Errorf("failed to record history: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Best effort cleanup
+	_, _ = s.db.DeleteOrphanedTags(ctx)
+	return nil
+}
+
+// OverlapPair is two entries whose time ranges intersect. First always
+// starts no later than Second.
+type OverlapPair struct {
+	First  database.ListAllTimeEntriesRow
+	Second database.ListAllTimeEntriesRow
+}
+
+// FindOverlappingEntries returns every pair of entries whose time ranges
+// intersect. A running entry is treated as ending now.
+func (s *Service) FindOverlappingEntries(ctx context.Context) ([]OverlapPair, error) {
+	entries, err := s.db.ListAllTimeEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartTime.Before(entries[j].StartTime)
+	})
+
+	now := s.Now()
+	endOf := func(e database.ListAllTimeEntriesRow) time.Time {
+		if e.EndTime.Valid {
+			return e.EndTime.Time
+		}
+		return now
+	}
+
+	var pairs []OverlapPair
+	for i, a := range entries {
+		aEnd := endOf(a)
+		for _, b := range entries[i+1:] {
+			// Entries are sorted by start, so nothing further can overlap a.
+			if !b.StartTime.Before(aEnd) {
+				break
+			}
+			if endOf(b).After(a.StartTime) {
+				pairs = append(pairs, OverlapPair{First: a, Second: b})
+			}
+		}
+	}
+	return pairs, nil
+}
+
+type ReportFilter struct {
+	StartDate      time.Time     // Zero leaves the range open at the start
+	EndDate        time.Time     // Zero leaves the range open at the end
+	CategoryFilter int64         // 0: All, -1: No Category, >0: Specific Category
+	TagIDs         []int64       // AND filter
+	MinDuration    time.Duration // Entries shorter than this are dropped; 0 keeps all
+	IncludeRunning bool          // Count the running timer up to now
+	// SplitAtMidnight divides entries crossing midnight between the days
+	// they span in DailyBreakdown. By default an entry counts entirely
+	// towards the day it started.
+	SplitAtMidnight bool
+	// RollupSubcategories counts the time of subcategories towards their
+	// top-level category in CategoryBreakdown and GroupedEntries.
+	RollupSubcategories bool
+	// DecimalHours makes exports write durations as hours with two
+	// decimals, see FormatDecimalHours, instead of seconds.
+	DecimalHours bool
+	// IncludeOverlapping also reports entries that started before StartDate
+	// but were still running after it. Only the part of every entry that
+	// falls between StartDate and EndDate is then counted.
+	IncludeOverlapping bool
+	// BillableOnly drops entries that are not marked billable.
+	BillableOnly bool
+	// OldestFirst lists entries by ascending start time instead of the
+	// default newest first.
+	OldestFirst bool
+}
+
+// countedSpan returns the part of an entry from start to end that the
+// report counts: all of it, or with IncludeOverlapping the part inside the
+// range.
+func (f ReportFilter) countedSpan(start, end time.Time) (time.Time, time.Time) {
+	if !f.IncludeOverlapping {
+		return start, end
+	}
+	if !f.StartDate.IsZero() && start.Before(f.StartDate) {
+		start = f.StartDate
+	}
+	if !f.EndDate.IsZero() && end.After(f.EndDate) {
+		end = f.EndDate
+	}
+	if end.Before(start) {
+		end = start
+	}
+	return start, end
+}
+
+type CategoryBreakdown struct {
+	CategoryID   int64
+	CategoryName string
+	Color        string
+	TotalSeconds int64
+	Percentage   float64
+}
+
+// DailyTotal is the time tracked on one calendar day.
+type DailyTotal struct {
+	Date         string // YYYY-MM-DD
+	TotalSeconds int64
+	// TargetSeconds is the daily target set for the weekday, and Target
+	// how the day compares to it; both are zero without a target.
+	TargetSeconds int64
+	Target        TargetStatus
+}
+
+// TargetStatus compares the time tracked on a day with its target.
+type TargetStatus string
 
-	tags := parseTags(description)
-	if err := s.updateTags(ctx, qtx, entry.ID, tags); err != nil {
-		return nil, fmt.Errorf("failed to update tags: %w", err)
-	}
+const (
+	TargetUnder TargetStatus = "under"
+	TargetMet   TargetStatus = "met"
+	TargetOver  TargetStatus = "over"
+)
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+// targetTolerance is how far a day may be off its target and still count as
+// having met it.
+const targetTolerance = 15 * time.Minute
+
+// targetStatus compares tracked with target, which must be positive.
+func targetStatus(tracked, target time.Duration) TargetStatus {
+	switch {
+	case tracked < target-targetTolerance:
+		return TargetUnder
+	case tracked > target+targetTolerance:
+		return TargetOver
 	}
-
-	fullEntry, err := s.db.GetTimeEntry(ctx, id)
-	return &fullEntry, err
+	return TargetMet
 }
 
-func (s *Service) DeleteTimeEntry(ctx context.Context, id int64) error {
-	if err := s.db.DeleteTimeEntry(ctx, id); err != nil {
-		return err
-	}
-	// Best effort cleanup
-	_ = s.db.DeleteOrphanedTags(ctx)
-	return nil
+// WeekdayTotal is the time tracked on one day of the week across a report.
+// AvgSeconds averages over the Days of that weekday that have entries.
+type WeekdayTotal struct {
+	Weekday      time.Weekday
+	TotalSeconds int64
+	Days         int
+	AvgSeconds   int64
 }
 
-type ReportFilter struct {
-	StartDate      time.Time
-	EndDate        time.Time
-	CategoryFilter int64   // 0: All, -1: No Category, >0: Specific Category
-	TagIDs         []int64 // AND filter
+// ReportEntry is a report row together with its tags and the seconds it
+// counts for in the report.
+type ReportEntry struct {
+	database.ListTimeEntriesReportRow
+	Tags    []database.Tag
+	Seconds int64
 }
 
-type CategoryBreakdown struct {
-	CategoryID   int64
+// CategoryGroup holds the report entries belonging to one category.
+type CategoryGroup struct {
+	CategoryID   int64 // -1 for entries without a category
 	CategoryName string
 	Color        string
+	Entries      []ReportEntry
 	TotalSeconds int64
-	Percentage   float64
 }
 
 type ReportData struct {
-	Entries           []database.ListTimeEntriesReportRow
+	Entries           []ReportEntry
+	GroupedEntries    []CategoryGroup
 	TotalSeconds      int64
+	BillableSeconds   int64 // Part of TotalSeconds in billable entries
 	CategoryBreakdown []CategoryBreakdown
+	DailyBreakdown    []DailyTotal   // Ordered by date
+	WeekdayBreakdown  []WeekdayTotal // Monday first, always seven entries
 	Filter            ReportFilter
+	NoCategoryLabel   string
+	DistinctDays      int // Days with at least one entry
+	CurrentStreak     int // Consecutive active days ending today, see currentStreak
+}
+
+// NonBillableSeconds is the part of TotalSeconds in entries not marked
+// billable.
+func (r ReportData) NonBillableSeconds() int64 {
+	return r.TotalSeconds - r.BillableSeconds
 }
 
 type CSVPreviewEntry struct {
@@ -255,44 +1391,96 @@ type CSVPreviewEntry struct {
 	StartTime   time.Time
 	EndTime     sql.NullTime
 	Category    string
-	Status      string // "New" or "Updated"
+	Notes       string
+	Billable    bool
+	Status      string // "New", "Updated" or "Error"
 
 	DescriptionChanged bool
 	StartTimeChanged   bool
 	EndTimeChanged     bool
 	CategoryChanged    bool
+	NotesChanged       bool
+	BillableChanged    bool
+
+	// Warning flags rows that import but look suspicious, such as an
+	// end_time that disagrees with the duration column.
+	Warning string
+	// Error says why a row with Status "Error" would fail the import.
+	Error string
 }
 
 func (s *Service) GetReport(ctx context.Context, filter ReportFilter) (ReportData, error) {
 	rows, err := s.db.ListTimeEntriesReport(ctx, database.ListTimeEntriesReportParams{
-		StartTime:      filter.StartDate,
-		StartTime_2:    filter.EndDate,
-		CategoryFilter: filter.CategoryFilter,
+		HasStart:           !filter.StartDate.IsZero(),
+		StartDate:          filter.StartDate,
+		HasEnd:             !filter.EndDate.IsZero(),
+		EndDate:            filter.EndDate,
+		IncludeOverlapping: filter.IncludeOverlapping,
+		CategoryFilter:     filter.CategoryFilter,
+		IncludeRunning:     filter.IncludeRunning,
+		OldestFirst:        filter.OldestFirst,
 	})
 	if err != nil {
 		return ReportData{}, err
 	}
+	now := s.Now()
+
+	// Load the tags of every row in one query rather than one per entry
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	tagRows, err := s.db.ListTagsForTimeEntries(ctx, ids)
+	if err != nil {
+		return ReportData{}, err
+	}
+	entryTags := make(map[int64][]database.Tag)
+	for _, t := range tagRows {
+		entryTags[t.TimeEntryID] = append(entryTags[t.TimeEntryID], database.Tag{
+			ID:    t.ID,
+			Name:  t.Name,
+			Color: t.Color,
+		})
+	}
 
-	var filteredRows []database.ListTimeEntriesReportRow
+	var filteredRows []ReportEntry
 	categoryTotals := make(map[int64]*CategoryBreakdown)
-	var totalSeconds int64
+	groups := make(map[int64]*CategoryGroup)
+	var totalSeconds, billableSeconds int64
+	activeDays := make(map[string]bool)
+	dailyTotals := make(map[string]int64)
+
+	noCategoryLabel, err := label(ctx, s.db, SettingNoCategoryLabel, DefaultNoCategoryLabel)
+	if err != nil {
+		return ReportData{}, err
+	}
+
+	var roots map[int64]database.Category
+	if filter.RollupSubcategories {
+		cats, err := s.db.ListCategories(ctx)
+		if err != nil {
+			return ReportData{}, err
+		}
+		roots = categoryRoots(cats)
+	}
 
-	// Initialize "No Category" breakdown
+	// Initialize "No Category" breakdown. Entries without a category are
+	// keyed by -1; the label is only for display.
 	noCategory := &CategoryBreakdown{
 		CategoryID:   -1,
-		CategoryName: "No Category",
+		CategoryName: noCategoryLabel,
 		Color:        "#888888",
 	}
 
 	for _, row := range rows {
+		if filter.BillableOnly && !row.Billable {
+			continue
+		}
+
 		// Filter by tags (AND logic)
 		if len(filter.TagIDs) > 0 {
-			entryTags, err := s.db.ListTagsForTimeEntry(ctx, row.ID)
-			if err != nil {
-				continue
-			}
 			tagMap := make(map[int64]bool)
-			for _, t := range entryTags {
+			for _, t := range entryTags[row.ID] {
 				tagMap[t.ID] = true
 			}
 			matchAll := true
@@ -307,17 +1495,41 @@ func (s *Service) GetReport(ctx context.Context, filter ReportFilter) (ReportDat
 			}
 		}
 
-		duration := row.EndTime.Time.Sub(row.StartTime)
-		seconds := int64(duration.Seconds())
+		end := now
+		if row.EndTime.Valid {
+			end = row.EndTime.Time
+		}
+		if end.Sub(row.StartTime) < filter.MinDuration {
+			continue
+		}
+
+		start, end := filter.countedSpan(row.StartTime, end)
+		seconds := int64(end.Sub(start).Seconds())
+		entry := ReportEntry{ListTimeEntriesReportRow: row, Tags: entryTags[row.ID], Seconds: seconds}
 		totalSeconds += seconds
+		if row.Billable {
+			billableSeconds += seconds
+		}
+		activeDays[start.In(s.loc).Format("2006-01-02")] = true
+		if filter.SplitAtMidnight {
+			addSecondsByDay(dailyTotals, start, end, s.loc)
+		} else {
+			dailyTotals[start.In(s.loc).Format("2006-01-02")] += seconds
+		}
+
+		// The category the entry counts towards, its top-level one when
+		// rolling up
+		catID, catName, catColor := row.CategoryID.Int64, row.CategoryName.String, row.CategoryColor.String
+		if root, ok := roots[catID]; ok && row.CategoryID.Valid {
+			catID, catName, catColor = root.ID, root.Name, root.Color
+		}
 
 		if row.CategoryID.Valid {
-			catID := row.CategoryID.Int64
 			if _, ok := categoryTotals[catID]; !ok {
 				categoryTotals[catID] = &CategoryBreakdown{
 					CategoryID:   catID,
-					CategoryName: row.CategoryName.String,
-					Color:        row.CategoryColor.String,
+					CategoryName: catName,
+					Color:        catColor,
 				}
 			}
 			categoryTotals[catID].TotalSeconds += seconds
@@ -325,8 +1537,43 @@ func (s *Service) GetReport(ctx context.Context, filter ReportFilter) (ReportDat
 			noCategory.TotalSeconds += seconds
 		}
 
-		filteredRows = append(filteredRows, row)
+		groupID := int64(-1)
+		if row.CategoryID.Valid {
+			groupID = catID
+		}
+		group, ok := groups[groupID]
+		if !ok {
+			group = &CategoryGroup{
+				CategoryID:   groupID,
+				CategoryName: noCategory.CategoryName,
+				Color:        noCategory.Color,
+			}
+			if row.CategoryID.Valid {
+				group.CategoryName = catName
+				group.Color = catColor
+			}
+			groups[groupID] = group
+		}
+		group.Entries = append(group.Entries, entry)
+		group.TotalSeconds += seconds
+
+		filteredRows = append(filteredRows, entry)
+	}
+
+	// Largest groups first, entries without a category last
+	grouped := make([]CategoryGroup, 0, len(groups))
+	for _, g := range groups {
+		grouped = append(grouped, *g)
 	}
+	sort.Slice(grouped, func(i, j int) bool {
+		if (grouped[i].CategoryID == -1) != (grouped[j].CategoryID == -1) {
+			return grouped[j].CategoryID == -1
+		}
+		if grouped[i].TotalSeconds != grouped[j].TotalSeconds {
+			return grouped[i].TotalSeconds > grouped[j].TotalSeconds
+		}
+		return grouped[i].CategoryName < grouped[j].CategoryName
+	})
 
 	var breakdown []CategoryBreakdown
 	if totalSeconds > 0 {
@@ -346,25 +1593,81 @@ func (s *Service) GetReport(ctx context.Context, filter ReportFilter) (ReportDat
 		breakdown = append(breakdown, *noCategory)
 	}
 
+	targets, err := dailyTargets(ctx, s.db)
+	if err != nil {
+		return ReportData{}, err
+	}
+	daily := make([]DailyTotal, 0, len(dailyTotals))
+	for day, seconds := range dailyTotals {
+		d := DailyTotal{Date: day, TotalSeconds: seconds}
+		if date, err := time.Parse("2006-01-02", day); err == nil && targets[date.Weekday()] > 0 {
+			target := targets[date.Weekday()]
+			d.TargetSeconds = int64(target / time.Second)
+			d.Target = targetStatus(time.Duration(seconds)*time.Second, target)
+		}
+		daily = append(daily, d)
+	}
+	sort.Slice(daily, func(i, j int) bool { return daily[i].Date < daily[j].Date })
+
 	return ReportData{
 		Entries:           filteredRows,
+		GroupedEntries:    grouped,
 		TotalSeconds:      totalSeconds,
+		BillableSeconds:   billableSeconds,
 		CategoryBreakdown: breakdown,
+		DailyBreakdown:    daily,
+		WeekdayBreakdown:  weekdayBreakdown(daily),
 		Filter:            filter,
+		NoCategoryLabel:   noCategoryLabel,
+		DistinctDays:      len(activeDays),
+		CurrentStreak:     currentStreak(activeDays, now),
 	}, nil
 }
 
+// currentStreak counts the consecutive days in activeDays (keyed by
+// YYYY-MM-DD) ending today. A day without entries yet today does not break
+// the streak, which then ends yesterday.
+func currentStreak(activeDays map[string]bool, today time.Time) int {
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	if !activeDays[day.Format("2006-01-02")] {
+		day = day.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for activeDays[day.Format("2006-01-02")] {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
+
 func (s *Service) ExportCSV(ctx context.Context, w io.Writer) error {
 	entries, err := s.db.ListAllTimeEntries(ctx)
 	if err != nil {
 		return err
 	}
+	return writeEntriesCSV(w, entries)
+}
+
+// ExportFilteredCSV exports the entries of the report for filter, in the
+// same format as ExportCSV.
+func (s *Service) ExportFilteredCSV(ctx context.Context, w io.Writer, filter ReportFilter) error {
+	report, err := s.GetReport(ctx, filter)
+	if err != nil {
+		return err
+	}
+	entries := make([]database.ListAllTimeEntriesRow, len(report.Entries))
+	for i, e := range report.Entries {
+		entries[i] = database.ListAllTimeEntriesRow(e.ListTimeEntriesReportRow)
+	}
+	return writeEntriesCSV(w, entries)
+}
 
+func writeEntriesCSV(w io.Writer, entries []database.ListAllTimeEntriesRow) error {
 	writer := csv.NewWriter(w)
 	defer writer.Flush()
 
 	// Header
-	if err := writer.Write([]string{"id", "description", "start_time", "end_time", "category"}); err != nil {
+	if err := writer.Write([]string{"id", "description", "start_time", "end_time", "category", "color", "external_id", "billable", "notes"}); err != nil {
 		return err
 	}
 
@@ -385,6 +1688,10 @@ func (s *Service) ExportCSV(ctx context.Context, w io.Writer) error {
 			startTime,
 			endTime,
 			category,
+			e.Color.String,
+			e.ExternalID.String,
+			strconv.FormatBool(e.Billable),
+			e.Notes,
 		}); err != nil {
 			return err
 		}
@@ -393,31 +1700,110 @@ func (s *Service) ExportCSV(ctx context.Context, w io.Writer) error {
 	return nil
 }
 
+// readImportCSV reads all CSV records from r, failing with ErrImportTooLarge
+// once the input exceeds the configured byte or row limit.
+func (s *Service) readImportCSV(r io.Reader) ([][]string, error) {
+	// One byte over the limit tells a file of exactly maxBytes from a
+	// larger one
+	lr := &io.LimitedReader{R: r, N: s.importMaxBytes + 1}
+	reader := csv.NewReader(lr)
+
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if lr.N <= 0 {
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrImportTooLarge, s.importMaxBytes)
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+		if len(records) > s.importMaxRows+1 { // +1 for the header
+			return nil, fmt.Errorf("%w: more than %d rows", ErrImportTooLarge, s.importMaxRows)
+		}
+	}
+}
+
+// ImportMode says what an import does with the entries already stored.
+type ImportMode string
+
+const (
+	// ImportMerge updates the entries the file matches and adds the rest,
+	// keeping those it does not mention.
+	ImportMerge ImportMode = "merge"
+	// ImportReplace deletes every entry first, so that afterwards the
+	// entries exactly mirror the file.
+	ImportReplace ImportMode = "replace"
+)
+
+// CSVMapping names, for an import column such as "description", the header
+// that column has in the file, overriding the default header name.
+type CSVMapping map[string]string
+
+// ImportCSV merges the entries of a CSV file into the stored ones.
 func (s *Service) ImportCSV(ctx context.Context, r io.Reader) error {
-	reader := csv.NewReader(r)
-	records, err := reader.ReadAll()
+	return s.ImportCSVWithProgress(ctx, r, ImportMerge, nil, nil)
+}
+
+// ImportCSVWithProgress is ImportCSV in the given mode and with the given
+// column mapping, which may be nil, with a callback invoked once per data
+// row, after the row is handled, with the number of rows processed so far
+// and the total. The last call happens only once the import is committed.
+func (s *Service) ImportCSVWithProgress(ctx context.Context, r io.Reader, mode ImportMode, mapping CSVMapping, progress func(processed, total int)) error {
+	if mode != ImportMerge && mode != ImportReplace {
+		return fmt.Errorf("%w: unknown import mode %q", ErrValidation, mode)
+	}
+
+	records, err := s.readImportCSV(r)
 	if err != nil {
 		return err
 	}
 
-	if len(records) < 2 {
+	if len(records) < 2 && mode == ImportMerge {
 		return nil // Only header or empty
 	}
+	// A header-only file empties the store when replacing, but a file
+	// without even a header is more likely a mistake
+	if len(records) == 0 {
+		return fmt.Errorf("%w: missing header", ErrInvalidCSV)
+	}
 
-	header := records[0]
-	colMap := make(map[string]int)
-	for i, h := range header {
-		colMap[strings.ToLower(strings.TrimSpace(h))] = i
+	colMap, err := csvColumns(records[0], mapping)
+	if err != nil {
+		return err
 	}
+	// Without these columns, existing entries keep their notes and flag
+	_, hasBillable := colMap["billable"]
+	_, hasNotes := colMap["notes"]
 
-	tx, err := s.rawDB.Begin()
+	tx, err := s.rawDB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = tx.Rollback() }()
 	qtx := s.db.WithTx(tx)
 
-	for _, record := range records[1:] {
+	if mode == ImportReplace {
+		if err := s.deleteAllEntries(ctx, qtx); err != nil {
+			return fmt.Errorf("failed to delete entries: %w", err)
+		}
+	}
+
+	requireLetter, err := tagsRequireLetter(ctx, qtx)
+	if err != nil {
+		return err
+	}
+
+	total := len(records) - 1
+	for i, record := range records[1:] {
+		// Report the previous row here so skipped rows are counted too
+		if progress != nil && i > 0 {
+			progress(i, total)
+		}
+
 		// Helper to get col value
 		getVal := func(name string) string {
 			if idx, ok := colMap[name]; ok && idx < len(record) {
@@ -430,80 +1816,246 @@ func (s *Service) ImportCSV(ctx context.Context, r io.Reader) error {
 		description := getVal("description")
 		startTimeStr := getVal("start_time")
 		endTimeStr := getVal("end_time")
+		durationStr := getVal("duration")
 		categoryName := getVal("category")
+		color := getVal("color")
+		externalID := getVal("external_id")
+		billableStr := getVal("billable")
 
 		if description == "" && startTimeStr == "" {
 			continue // Skip empty rows
 		}
 
-		startTime, err := parseFlexTime(startTimeStr)
+		billable, err := parseCSVBool(billableStr)
+		if err != nil {
+			return fmt.Errorf("invalid billable '%s': %w", billableStr, err)
+		}
+
+		if color != "" && !colorRegex.MatchString(color) {
+			return fmt.Errorf("invalid color '%s': expected #RRGGBB", color)
+		}
+		// An empty color keeps an existing override (see UpsertTimeEntry)
+		entryColor := sql.NullString{String: color, Valid: color != ""}
+
+		startTime, err := parseFlexTime(startTimeStr, s.loc)
 		if err != nil {
 			return fmt.Errorf("invalid start_time '%s': %w", startTimeStr, err)
 		}
 
 		var endTime sql.NullTime
 		if endTimeStr != "" {
-			et, err := parseFlexTime(endTimeStr)
+			et, err := parseFlexTime(endTimeStr, s.loc)
 			if err != nil {
 				return fmt.Errorf("invalid end_time '%s': %w", endTimeStr, err)
 			}
 			endTime = sql.NullTime{Time: et, Valid: true}
 		}
-
-		var catID sql.NullInt64
-		if categoryName != "" {
-			cat, err := qtx.GetCategoryByName(ctx, categoryName)
-			if err == sql.ErrNoRows {
-				// Create category
-				cat, err = qtx.CreateCategory(ctx, database.CreateCategoryParams{
-					Name:  categoryName,
-					Color: "#cccccc",
-				})
-				if err != nil {
-					return fmt.Errorf("failed to create category '%s': %w", categoryName, err)
-				}
-			} else if err != nil {
-				return err
+		if durationStr != "" {
+			d, err := parseImportDuration(durationStr)
+			if err != nil {
+				return fmt.Errorf("invalid duration '%s': %w", durationStr, err)
+			}
+			if !endTime.Valid {
+				endTime = sql.NullTime{Time: startTime.Add(d), Valid: true}
+			} else if warning := durationMismatch(startTime, endTime.Time, d); warning != "" {
+				log.Printf("Import of %q: %s", description, warning)
 			}
-			catID = sql.NullInt64{Int64: cat.ID, Valid: true}
 		}
 
-		var entry database.TimeEntry
-		id, _ := strconv.ParseInt(idStr, 10, 64)
-		if id > 0 {
-			entry, err = qtx.UpsertTimeEntry(ctx, database.UpsertTimeEntryParams{
-				ID:          id,
-				Description: description,
-				StartTime:   startTime,
-				EndTime:     endTime,
-				CategoryID:  catID,
-			})
-		} else {
-			entry, err = qtx.CreateTimeEntryFull(ctx, database.CreateTimeEntryFullParams{
-				Description: description,
-				StartTime:   startTime,
-				EndTime:     endTime,
-				CategoryID:  catID,
-			})
+		if endTime.Valid && endTime.Time.Before(startTime) {
+			// Data rows start on line 2, after the header
+			return fmt.Errorf("%w: row %d: %s", ErrInvalidCSV, i+2, reversedTimes(startTimeStr, endTimeStr))
 		}
 
+		id, _ := strconv.ParseInt(idStr, 10, 64)
+		entry, err := saveImportedEntry(ctx, qtx, importedEntry{
+			ID:          id,
+			ExternalID:  externalID,
+			Description: description,
+			StartTime:   startTime,
+			EndTime:     endTime,
+			Category:    categoryName,
+			Color:       entryColor,
+		})
 		if err != nil {
 			return fmt.Errorf("failed to save entry: %w", err)
 		}
 
+		if hasNotes {
+			if err := qtx.UpdateTimeEntryNotes(ctx, database.UpdateTimeEntryNotesParams{
+				Notes: getVal("notes"),
+				ID:    entry.ID,
+			}); err != nil {
+				return fmt.Errorf("failed to save notes for entry %d: %w", entry.ID, err)
+			}
+		}
+		if hasBillable {
+			if err := qtx.UpdateTimeEntryBillable(ctx, database.UpdateTimeEntryBillableParams{
+				Billable: billable,
+				ID:       entry.ID,
+			}); err != nil {
+				return fmt.Errorf("failed to save billable flag for entry %d: %w", entry.ID, err)
+			}
+		}
+
 		// Update tags
-		tags := parseTags(description)
+		tags := parseTags(description, requireLetter, s.tagPattern)
 		if err := s.updateTags(ctx, qtx, entry.ID, tags); err != nil {
 			return fmt.Errorf("failed to update tags for entry %d: %w", entry.ID, err)
 		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if progress != nil {
+		progress(total, total)
+	}
+	return nil
+}
+
+// deleteAllEntries deletes every entry with its tags, recording each
+// deletion, ahead of an import that replaces them or a reset.
+func (s *Service) deleteAllEntries(ctx context.Context, q *database.Queries) error {
+	entries, err := q.ListAllTimeEntries(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		before := database.GetTimeEntryRow(e)
+		if err := q.DeleteTimeEntryTags(ctx, e.ID); err != nil {
+			return err
+		}
+		if err := q.DeleteTimeEntry(ctx, e.ID); err != nil {
+			return err
+		}
+		if err := s.recordAudit(ctx, q, e.ID, AuditDelete, &before, nil); err != nil {
+			return fmt.Errorf("failed to record history: %w", err)
+		}
+	}
+	_, err = q.DeleteOrphanedTags(ctx)
+	return err
+}
+
+// ResetData deletes every entry and tag, and with includeCategories every
+// category together with its goals, in one transaction. Settings other than
+// the default category are kept.
+func (s *Service) ResetData(ctx context.Context, includeCategories bool) error {
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
+
+	if err := s.deleteAllEntries(ctx, qtx); err != nil {
+		return fmt.Errorf("failed to delete entries: %w", err)
+	}
+	if includeCategories {
+		if err := qtx.DeleteAllCategories(ctx); err != nil {
+			return fmt.Errorf("failed to delete categories: %w", err)
+		}
+		if err := qtx.DeleteSetting(ctx, settingDefaultCategoryID); err != nil {
+			return err
+		}
+	}
 	return tx.Commit()
 }
 
-func (s *Service) PreviewCSV(ctx context.Context, r io.Reader) ([]CSVPreviewEntry, error) {
-	reader := csv.NewReader(r)
-	records, err := reader.ReadAll()
+// importedEntry is one entry read by an import, before it is saved.
+type importedEntry struct {
+	ID            int64  // Our own ID, 0 for a new entry
+	ExternalID    string // Takes precedence over ID when set
+	Description   string
+	StartTime     time.Time
+	EndTime       sql.NullTime
+	Category      string // Created when missing
+	CategoryColor string // Color of a newly created category, default #cccccc
+	Color         sql.NullString
+}
+
+// saveImportedEntry creates or updates e, matching on its external ID, then
+// its ID, creating its category by name, matched ignoring case, if needed.
+// A category named like the no-category label, as found in report exports,
+// leaves the entry without one. Tags are left to the caller.
+func saveImportedEntry(ctx context.Context, qtx *database.Queries, e importedEntry) (database.TimeEntry, error) {
+	if err := checkTimeOrder(e.StartTime, e.EndTime); err != nil {
+		return database.TimeEntry{}, err
+	}
+	noCategoryLabel, err := label(ctx, qtx, SettingNoCategoryLabel, DefaultNoCategoryLabel)
+	if err != nil {
+		return database.TimeEntry{}, err
+	}
+	var catID sql.NullInt64
+	if e.Category != "" && !isNoCategoryLabel(e.Category, noCategoryLabel) {
+		cat, err := qtx.GetCategoryByName(ctx, e.Category)
+		if err == sql.ErrNoRows {
+			color := e.CategoryColor
+			if color == "" {
+				color = "#cccccc"
+			}
+			cat, err = qtx.CreateCategory(ctx, database.CreateCategoryParams{
+				Name:  e.Category,
+				Color: color,
+			})
+			if err != nil {
+				return database.TimeEntry{}, fmt.Errorf("failed to create category '%s': %w", e.Category, categoryNameError(err, e.Category))
+			}
+		} else if err != nil {
+			return database.TimeEntry{}, err
+		}
+		catID = sql.NullInt64{Int64: cat.ID, Valid: true}
+	}
+
+	if e.ExternalID != "" {
+		// A source-system ID takes precedence over our own IDs so that
+		// repeated syncs never collide with local entries.
+		return qtx.UpsertTimeEntryByExternalID(ctx, database.UpsertTimeEntryByExternalIDParams{
+			ExternalID:  sql.NullString{String: e.ExternalID, Valid: true},
+			Description: e.Description,
+			StartTime:   e.StartTime,
+			EndTime:     e.EndTime,
+			CategoryID:  catID,
+			Color:       e.Color,
+		})
+	}
+	if e.ID > 0 {
+		return qtx.UpsertTimeEntry(ctx, database.UpsertTimeEntryParams{
+			ID:          e.ID,
+			Description: e.Description,
+			StartTime:   e.StartTime,
+			EndTime:     e.EndTime,
+			CategoryID:  catID,
+			Color:       e.Color,
+		})
+	}
+	return qtx.CreateTimeEntryFull(ctx, database.CreateTimeEntryFullParams{
+		Description: e.Description,
+		StartTime:   e.StartTime,
+		EndTime:     e.EndTime,
+		CategoryID:  catID,
+		Color:       e.Color,
+	})
+}
+
+// reversedTimes describes a row whose end_time is before its start_time.
+func reversedTimes(startStr, endStr string) string {
+	return fmt.Sprintf("end_time %s is before start_time %s", endStr, startStr)
+}
+
+// checkTimeOrder returns ErrEndBeforeStart when end is set and before start.
+// The database triggers of migration 010 only back this up.
+func checkTimeOrder(start time.Time, end sql.NullTime) error {
+	if end.Valid && end.Time.Before(start) {
+		return ErrEndBeforeStart
+	}
+	return nil
+}
+
+// PreviewCSV lists the changes importing the file with the given column
+// mapping, which may be nil, would make.
+func (s *Service) PreviewCSV(ctx context.Context, r io.Reader, mapping CSVMapping) ([]CSVPreviewEntry, error) {
+	records, err := s.readImportCSV(r)
 	if err != nil {
 		return nil, err
 	}
@@ -512,11 +2064,12 @@ func (s *Service) PreviewCSV(ctx context.Context, r io.Reader) ([]CSVPreviewEntr
 		return nil, nil
 	}
 
-	header := records[0]
-	colMap := make(map[string]int)
-	for i, h := range header {
-		colMap[strings.ToLower(strings.TrimSpace(h))] = i
+	colMap, err := csvColumns(records[0], mapping)
+	if err != nil {
+		return nil, err
 	}
+	_, hasBillable := colMap["billable"]
+	_, hasNotes := colMap["notes"]
 
 	var preview []CSVPreviewEntry
 
@@ -532,28 +2085,69 @@ func (s *Service) PreviewCSV(ctx context.Context, r io.Reader) ([]CSVPreviewEntr
 		description := getVal("description")
 		startTimeStr := getVal("start_time")
 		endTimeStr := getVal("end_time")
+		durationStr := getVal("duration")
 		categoryName := getVal("category")
+		externalID := getVal("external_id")
+		notes := getVal("notes")
+		billableStr := getVal("billable")
 
 		if description == "" && startTimeStr == "" {
 			continue
 		}
 
-		startTime, err := parseFlexTime(startTimeStr)
+		startTime, err := parseFlexTime(startTimeStr, s.loc)
 		if err != nil {
 			continue // Skip invalid rows for preview or handle error
 		}
 
 		var endTime sql.NullTime
 		if endTimeStr != "" {
-			et, err := parseFlexTime(endTimeStr)
+			et, err := parseFlexTime(endTimeStr, s.loc)
 			if err == nil {
 				endTime = sql.NullTime{Time: et, Valid: true}
 			}
 		}
+		var warning string
+		billable, err := parseCSVBool(billableStr)
+		if err != nil {
+			warning = fmt.Sprintf("invalid billable '%s'", billableStr)
+		}
+		if durationStr != "" {
+			if d, err := parseImportDuration(durationStr); err != nil {
+				warning = fmt.Sprintf("invalid duration '%s'", durationStr)
+			} else if !endTime.Valid {
+				endTime = sql.NullTime{Time: startTime.Add(d), Valid: true}
+			} else {
+				warning = durationMismatch(startTime, endTime.Time, d)
+			}
+		}
 
 		id, _ := strconv.ParseInt(idStr, 10, 64)
+		// Such a row fails the whole import, so it is listed rather than
+		// compared with the stored entry
+		if endTime.Valid && endTime.Time.Before(startTime) {
+			preview = append(preview, CSVPreviewEntry{
+				ID:          id,
+				Description: description,
+				StartTime:   startTime,
+				EndTime:     endTime,
+				Category:    categoryName,
+				Notes:       notes,
+				Billable:    billable,
+				Status:      "Error",
+				Error:       reversedTimes(startTimeStr, endTimeStr),
+			})
+			continue
+		}
+		if externalID != "" {
+			// Match on the external ID, mirroring ImportCSV
+			id, err = s.db.GetTimeEntryIDByExternalID(ctx, sql.NullString{String: externalID, Valid: true})
+			if err != nil {
+				id = 0
+			}
+		}
 		status := "New"
-		var descChanged, startChanged, endChanged, catChanged bool
+		var descChanged, startChanged, endChanged, catChanged, notesChanged, billableChanged bool
 
 		if id > 0 {
 			existing, err := s.db.GetTimeEntry(ctx, id)
@@ -574,8 +2168,11 @@ func (s *Service) PreviewCSV(ctx context.Context, r io.Reader) ([]CSVPreviewEntr
 				}
 				catChanged = (!existing.CategoryName.Valid || existing.CategoryName.String != categoryName) &&
 					(existing.CategoryName.Valid || categoryName != "")
+				// Absent columns leave the stored values alone
+				notesChanged = hasNotes && strings.TrimSpace(existing.Notes) != notes
+				billableChanged = hasBillable && existing.Billable != billable
 
-				if !descChanged && !startChanged && !endChanged && !catChanged {
+				if !descChanged && !startChanged && !endChanged && !catChanged && !notesChanged && !billableChanged {
 					continue // No changes, skip from preview
 				}
 				status = "Updated"
@@ -588,33 +2185,149 @@ func (s *Service) PreviewCSV(ctx context.Context, r io.Reader) ([]CSVPreviewEntr
 			StartTime:          startTime,
 			EndTime:            endTime,
 			Category:           categoryName,
+			Notes:              notes,
+			Billable:           billable,
 			Status:             status,
 			DescriptionChanged: descChanged,
 			StartTimeChanged:   startChanged,
 			EndTimeChanged:     endChanged,
 			CategoryChanged:    catChanged,
+			NotesChanged:       notesChanged,
+			BillableChanged:    billableChanged,
+			Warning:            warning,
 		})
 	}
 
 	return preview, nil
 }
 
-func parseFlexTime(s string) (time.Time, error) {
+// requiredCSVColumns must be present in every imported CSV header.
+var requiredCSVColumns = []string{"description", "start_time"}
+
+// csvImportColumns are the columns an import reads.
+var csvImportColumns = []string{"id", "description", "start_time", "end_time", "duration", "category", "color", "external_id", "billable", "notes"}
+
+// csvColumns maps the lowercased header names to their column index, with
+// the columns named in mapping moved to the header given for them, and
+// checks that the required columns are present.
+func csvColumns(header []string, mapping CSVMapping) (map[string]int, error) {
+	colMap := make(map[string]int)
+	for i, h := range header {
+		colMap[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	// Look up every mapped header before moving any, so that two columns
+	// can swap names
+	mapped := make(map[string]int, len(mapping))
+	for col, name := range mapping {
+		if !slices.Contains(csvImportColumns, col) {
+			return nil, fmt.Errorf("%w: unknown import column %q", ErrValidation, col)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		idx, ok := colMap[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: missing column %q mapped to %s", ErrInvalidCSV, name, col)
+		}
+		mapped[col] = idx
+	}
+	maps.Copy(colMap, mapped)
+	for _, col := range requiredCSVColumns {
+		if _, ok := colMap[col]; !ok {
+			return nil, fmt.Errorf("%w: missing column: %s", ErrInvalidCSV, col)
+		}
+	}
+	return colMap, nil
+}
+
+// parseCSVBool parses the billable column, written as true/false or 1/0. An
+// empty value is false.
+func parseCSVBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true", "1":
+		return true, nil
+	case "false", "0", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected true, false, 1 or 0")
+}
+
+// parseImportDuration parses a duration column given either as whole
+// seconds or as HH:MM[:SS].
+func parseImportDuration(s string) (time.Duration, error) {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, fmt.Errorf("negative duration")
+		}
+		return time.Duration(secs) * time.Second, nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return 0, fmt.Errorf("unsupported duration format")
+	}
+	var d time.Duration
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || (i > 0 && n > 59) {
+			return 0, fmt.Errorf("unsupported duration format")
+		}
+		d += time.Duration(n) * units[i]
+	}
+	return d, nil
+}
+
+// durationMismatch describes how end - start differs from d, or returns ""
+// when they agree to the second.
+func durationMismatch(start, end time.Time, d time.Duration) string {
+	actual := end.Sub(start)
+	if diff := actual - d; diff > time.Second || diff < -time.Second {
+		return fmt.Sprintf("end_time gives %s but duration is %s; using end_time", actual, d)
+	}
+	return ""
+}
+
+// ParseUnixTime parses s as a Unix timestamp in seconds (10 digits) or
+// milliseconds (13 digits), returned in loc. Other lengths are rejected so
+// that compact dates such as 20240101 are never taken for timestamps.
+func ParseUnixTime(s string, loc *time.Location) (time.Time, bool) {
+	if len(s) != 10 && len(s) != 13 {
+		return time.Time{}, false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return time.Time{}, false
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if len(s) == 13 {
+		return time.UnixMilli(n).In(loc), true
+	}
+	return time.Unix(n, 0).In(loc), true
+}
+
+// parseFlexTime parses s in one of the supported layouts or as a Unix
+// timestamp. Layouts without an explicit offset are interpreted in loc.
+func parseFlexTime(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, ok := ParseUnixTime(s, loc); ok {
+		return t, nil
+	}
 	formats := []string{
-		time.RFC3339,
 		"2006-01-02 15:04:05",
 		"2006-01-02T15:04:05",
 		"2006-01-02 15:04",
 		"2006-01-02",
 	}
 	for _, f := range formats {
-		t, err := time.Parse(f, s)
-		if err == nil {
-			return t, nil
-		}
-		// Try with local location
-		t, err = time.ParseInLocation(f, s, time.Local)
-		if err == nil {
+		if t, err := time.ParseInLocation(f, s, loc); err == nil {
 			return t, nil
 		}
 	}
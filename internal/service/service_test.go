@@ -3,6 +3,10 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,7 +17,7 @@ import (
 	_ "modernc.org/sqlite"
 )
 
-func newTestService(t *testing.T) *Service {
+func newTestService(t *testing.T, opts ...Option) *Service {
 	db, err := sql.Open("sqlite", ":memory:")
 	if err != nil {
 		t.Fatalf("failed to open db: %v", err)
@@ -33,7 +37,7 @@ func newTestService(t *testing.T) *Service {
 	}
 
 	dbQueries := database.New(db)
-	return New(dbQueries, db)
+	return New(dbQueries, db, opts...)
 }
 
 func TestStartAndStopTimer(t *testing.T) {
@@ -77,7 +81,7 @@ func TestStartAndStopTimer(t *testing.T) {
 	}
 
 	// 3. Stop running timer
-	err = svc.StopTimer(ctx)
+	err = svc.StopTimer(ctx, nil)
 	if err != nil {
 		t.Fatalf("StopTimer failed: %v", err)
 	}
@@ -91,6 +95,235 @@ func TestStartAndStopTimer(t *testing.T) {
 	}
 }
 
+func TestStartTimerAt(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	running, err := svc.StartTimer(ctx, "Earlier", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	if _, err := svc.UpdateTimeEntry(ctx, running.ID, running.Description, svc.Now().Add(-time.Hour), sql.NullTime{}, nil); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+
+	start := svc.Now().Add(-30 * time.Minute)
+	entry, err := svc.StartTimerAt(ctx, start, "Backdated", nil)
+	if err != nil {
+		t.Fatalf("StartTimerAt failed: %v", err)
+	}
+	if !entry.StartTime.Equal(start) {
+		t.Errorf("expected start %v, got %v", start, entry.StartTime)
+	}
+	// The previous timer ends where the new one begins
+	previous, _ := svc.GetTimeEntry(ctx, running.ID)
+	if !previous.EndTime.Valid || !previous.EndTime.Time.Equal(start) {
+		t.Errorf("expected previous timer to stop at %v, got %v", start, previous.EndTime)
+	}
+
+	for name, bad := range map[string]time.Time{
+		"future":             svc.Now().Add(time.Minute),
+		"too far back":       svc.Now().Add(-MaxStartOffset - time.Minute),
+		"before running one": start.Add(-time.Minute),
+	} {
+		if _, err := svc.StartTimerAt(ctx, bad, "Bad", nil); !errors.Is(err, ErrInvalidStartTime) {
+			t.Errorf("%s: expected ErrInvalidStartTime, got %v", name, err)
+		}
+	}
+	if active, _ := svc.GetActiveTimeEntry(ctx); active.ID != entry.ID {
+		t.Errorf("expected rejected starts to leave %d running, got %d", entry.ID, active.ID)
+	}
+}
+
+func TestMultipleTimers(t *testing.T) {
+	svc := newTestService(t, WithMultipleTimers(true))
+	ctx := context.Background()
+
+	background, err := svc.StartTimerAt(ctx, svc.Now().Add(-time.Hour), "Long build", nil)
+	if err != nil {
+		t.Fatalf("StartTimerAt failed: %v", err)
+	}
+	foreground, err := svc.StartTimer(ctx, "Code review", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	actives, err := svc.ListActiveTimeEntries(ctx)
+	if err != nil {
+		t.Fatalf("ListActiveTimeEntries failed: %v", err)
+	}
+	if len(actives) != 2 || actives[0].ID != foreground.ID || actives[1].ID != background.ID {
+		t.Fatalf("expected both timers running, newest first, got %+v", actives)
+	}
+	primary, err := svc.GetActiveTimeEntry(ctx)
+	if err != nil || primary.ID != foreground.ID {
+		t.Errorf("expected the newest timer as primary, got %+v, %v", primary, err)
+	}
+
+	// Stopping by id leaves the primary running
+	if err := svc.StopTimer(ctx, &background.ID); err != nil {
+		t.Fatalf("StopTimer failed: %v", err)
+	}
+	if got, _ := svc.GetTimeEntry(ctx, background.ID); !got.EndTime.Valid {
+		t.Error("expected the background timer to be stopped")
+	}
+	if primary, err := svc.GetActiveTimeEntry(ctx); err != nil || primary.ID != foreground.ID {
+		t.Errorf("expected the primary timer to keep running, got %+v, %v", primary, err)
+	}
+	if err := svc.StopTimer(ctx, &background.ID); !errors.Is(err, ErrNoActiveTimer) {
+		t.Errorf("expected ErrNoActiveTimer for a stopped entry, got %v", err)
+	}
+	missing := int64(9999)
+	if err := svc.StopTimer(ctx, &missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	// Without an id the primary timer is stopped
+	if err := svc.StopTimer(ctx, nil); err != nil {
+		t.Fatalf("StopTimer failed: %v", err)
+	}
+	if actives, _ := svc.ListActiveTimeEntries(ctx); len(actives) != 0 {
+		t.Errorf("expected no running timers, got %+v", actives)
+	}
+}
+
+func TestSingleTimerByDefault(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	first, _ := svc.StartTimerAt(ctx, svc.Now().Add(-time.Hour), "First", nil)
+	second, err := svc.StartTimer(ctx, "Second", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	actives, err := svc.ListActiveTimeEntries(ctx)
+	if err != nil {
+		t.Fatalf("ListActiveTimeEntries failed: %v", err)
+	}
+	if len(actives) != 1 || actives[0].ID != second.ID {
+		t.Errorf("expected only the new timer running, got %+v", actives)
+	}
+	if got, _ := svc.GetTimeEntry(ctx, first.ID); !got.EndTime.Valid {
+		t.Error("expected the first timer to be stopped")
+	}
+}
+
+func TestStopTimerAt(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	entry, err := svc.StartTimer(ctx, "Forgot to stop", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	start := svc.Now().Add(-3 * time.Hour).Truncate(time.Second)
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, entry.Description, start, sql.NullTime{}, nil); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+
+	// Ending before the start is rejected and leaves the timer running
+	if err := svc.StopTimerAt(ctx, nil, start.Add(-time.Minute)); !errors.Is(err, ErrInvalidStopTime) {
+		t.Fatalf("expected ErrInvalidStopTime, got %v", err)
+	}
+	if _, err := svc.GetActiveTimeEntry(ctx); err != nil {
+		t.Fatalf("expected timer to keep running: %v", err)
+	}
+	if err := svc.StopTimerAt(ctx, nil, svc.Now().Add(time.Hour)); !errors.Is(err, ErrInvalidStopTime) {
+		t.Fatalf("expected ErrInvalidStopTime for a future stop, got %v", err)
+	}
+
+	end := start.Add(time.Hour)
+	if err := svc.StopTimerAt(ctx, nil, end); err != nil {
+		t.Fatalf("StopTimerAt failed: %v", err)
+	}
+	stopped, err := svc.GetTimeEntry(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("GetTimeEntry failed: %v", err)
+	}
+	if !stopped.EndTime.Valid || !stopped.EndTime.Time.Equal(end) {
+		t.Errorf("expected end time %v, got %v", end, stopped.EndTime)
+	}
+}
+
+func TestServiceErrors(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := svc.GetTimeEntry(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTimeEntry: expected ErrNotFound, got %v", err)
+	}
+	if _, err := svc.UpdateTimeEntry(ctx, 999, "Missing", svc.Now(), sql.NullTime{}, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateTimeEntry: expected ErrNotFound, got %v", err)
+	}
+	if err := svc.StopTimer(ctx, nil); !errors.Is(err, ErrNoActiveTimer) {
+		t.Errorf("StopTimer: expected ErrNoActiveTimer, got %v", err)
+	}
+
+	now := svc.Now().Truncate(time.Second)
+	entry := seedEntry(t, svc, "Done", now.Add(-2*time.Hour), now.Add(-time.Hour), nil)
+	backwards := sql.NullTime{Time: now.Add(-3 * time.Hour), Valid: true}
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, "Done", now.Add(-2*time.Hour), backwards, nil); !errors.Is(err, ErrValidation) {
+		t.Errorf("UpdateTimeEntry: expected ErrValidation, got %v", err)
+	}
+
+	if _, err := svc.StartTimerAt(ctx, now.Add(-time.Minute), "Running", nil); err != nil {
+		t.Fatalf("StartTimerAt failed: %v", err)
+	}
+	if err := svc.StopTimerAt(ctx, nil, now.Add(-time.Hour)); !errors.Is(err, ErrValidation) {
+		t.Errorf("StopTimerAt: expected ErrValidation, got %v", err)
+	}
+	_, err := svc.StartTimerAt(ctx, now.Add(-30*time.Minute), "Earlier", nil)
+	if !errors.Is(err, ErrOverlap) || !errors.Is(err, ErrValidation) {
+		t.Errorf("StartTimerAt: expected ErrOverlap, got %v", err)
+	}
+}
+
+func TestStopTimerDiscardsShortEntries(t *testing.T) {
+	svc := newTestService(t, WithMinEntryDuration(5*time.Second))
+	ctx := context.Background()
+
+	seedEntry(t, svc, "Kept #shared", time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour), nil)
+	oops, err := svc.StartTimerAt(ctx, svc.Now().Add(-time.Minute), "Oops #accident #shared", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	if err := svc.StopTimerAt(ctx, nil, oops.StartTime.Add(2*time.Second)); err != nil {
+		t.Fatalf("StopTimerAt failed: %v", err)
+	}
+
+	if _, err := svc.GetTimeEntry(ctx, oops.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the short entry to be deleted, got %v", err)
+	}
+	tags, _ := svc.ListTags(ctx)
+	if len(tags) != 1 || tags[0].Name != "shared" {
+		t.Errorf("expected only the shared tag to remain, got %+v", tags)
+	}
+	history, _ := svc.EntryHistory(ctx, oops.ID)
+	if len(history) == 0 || history[len(history)-1].Action != AuditDelete {
+		t.Errorf("expected the deletion to be recorded, got %+v", history)
+	}
+
+	// Long enough entries are stopped as usual
+	kept, _ := svc.StartTimerAt(ctx, svc.Now().Add(-time.Minute), "Real work", nil)
+	if err := svc.StopTimerAt(ctx, nil, kept.StartTime.Add(5*time.Second)); err != nil {
+		t.Fatalf("StopTimerAt failed: %v", err)
+	}
+	stopped, err := svc.GetTimeEntry(ctx, kept.ID)
+	if err != nil || !stopped.EndTime.Valid {
+		t.Errorf("expected the entry to be kept and stopped, got %+v (%v)", stopped, err)
+	}
+
+	// The default keeps everything
+	plain := newTestService(t)
+	short, _ := plain.StartTimerAt(ctx, plain.Now().Add(-time.Minute), "Short", nil)
+	if err := plain.StopTimerAt(ctx, nil, short.StartTime.Add(time.Second)); err != nil {
+		t.Fatalf("StopTimerAt failed: %v", err)
+	}
+	if _, err := plain.GetTimeEntry(ctx, short.ID); err != nil {
+		t.Errorf("expected the entry to be kept by default, got %v", err)
+	}
+}
+
 func TestUpdateTimeEntry(t *testing.T) {
 	svc := newTestService(t)
 	ctx := context.Background()
@@ -178,7 +411,7 @@ func TestCategoryCRUD(t *testing.T) {
 	}
 
 	// Update
-	updated, err := svc.UpdateCategory(ctx, cat.ID, "Personal", "#00ff00")
+	updated, err := svc.UpdateCategory(ctx, cat.ID, "Personal", "#00ff00", nil)
 	if err != nil {
 		t.Fatalf("UpdateCategory failed: %v", err)
 	}
@@ -195,6 +428,68 @@ func TestCategoryCRUD(t *testing.T) {
 	if len(cats) != 0 {
 		t.Errorf("expected 0 categories, got %v", cats)
 	}
+
+	// Missing ids are reported as not found
+	if _, err := svc.UpdateCategory(ctx, 9999, "Ghost", "#000000", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound updating missing category, got %v", err)
+	}
+	if err := svc.DeleteCategory(ctx, cat.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting missing category, got %v", err)
+	}
+}
+
+func TestReorderCategories(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	names := func() string {
+		t.Helper()
+		cats, err := svc.ListCategories(ctx)
+		if err != nil {
+			t.Fatalf("ListCategories failed: %v", err)
+		}
+		var out []string
+		for _, c := range cats {
+			out = append(out, c.Name)
+		}
+		return strings.Join(out, ",")
+	}
+
+	b, _ := svc.CreateCategory(ctx, "B", "#000000")
+	a, _ := svc.CreateCategory(ctx, "A", "#000000")
+	c, _ := svc.CreateCategory(ctx, "C", "#000000")
+	// New categories go to the end
+	if got := names(); got != "B,A,C" {
+		t.Errorf("expected creation order, got %s", got)
+	}
+
+	if err := svc.ReorderCategories(ctx, []int64{c.ID, a.ID, b.ID}); err != nil {
+		t.Fatalf("ReorderCategories failed: %v", err)
+	}
+	if got := names(); got != "C,A,B" {
+		t.Errorf("expected reordered categories, got %s", got)
+	}
+
+	// Unlisted categories keep their order after the listed ones
+	if err := svc.ReorderCategories(ctx, []int64{b.ID}); err != nil {
+		t.Fatalf("ReorderCategories failed: %v", err)
+	}
+	if got := names(); got != "B,C,A" {
+		t.Errorf("expected B first, got %s", got)
+	}
+	if _, err := svc.CreateCategory(ctx, "D", "#000000"); err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	if got := names(); got != "B,C,A,D" {
+		t.Errorf("expected new category last, got %s", got)
+	}
+
+	if err := svc.ReorderCategories(ctx, []int64{a.ID, 9999}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if got := names(); got != "B,C,A,D" {
+		t.Errorf("expected failed reorder to be rolled back, got %s", got)
+	}
 }
 
 func TestTimeEntryWithCategory(t *testing.T) {
@@ -213,7 +508,7 @@ func TestTimeEntryWithCategory(t *testing.T) {
 	}
 
 	// Stop it so it appears in ListTimeEntries
-	err = svc.StopTimer(ctx)
+	err = svc.StopTimer(ctx, nil)
 	if err != nil {
 		t.Fatalf("StopTimer failed: %v", err)
 	}
@@ -237,20 +532,23 @@ func TestTimeEntryWithCategory(t *testing.T) {
 
 func TestParseTags(t *testing.T) {
 	tests := []struct {
-		desc     string
-		input    string
-		expected []string
+		desc          string
+		input         string
+		requireLetter bool
+		expected      []string
 	}{
-		{"no tags", "hello world", nil},
-		{"one tag", "hello #world", []string{"world"}},
-		{"multiple tags", "#a #b #c", []string{"a", "b", "c"}},
-		{"case insensitive", "#Tag #tag", []string{"tag"}},
-		{"special characters", "#tag_123 #not-a-tag", []string{"tag_123", "not"}},
+		{"no tags", "hello world", false, nil},
+		{"one tag", "hello #world", false, []string{"world"}},
+		{"multiple tags", "#a #b #c", false, []string{"a", "b", "c"}},
+		{"case insensitive", "#Tag #tag", false, []string{"tag"}},
+		{"special characters", "#tag_123 #not-a-tag", false, []string{"tag_123", "not"}},
+		{"numeric tag", "fix #123", false, []string{"123"}},
+		{"numeric tag with letter required", "fix #123 #v2 #_1", true, []string{"v2"}},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
-			got := parseTags(tt.input)
+			got := parseTags(tt.input, tt.requireLetter, DefaultTagPattern)
 			if len(got) != len(tt.expected) {
 				t.Errorf("expected %v, got %v", tt.expected, got)
 				return
@@ -264,6 +562,270 @@ func TestParseTags(t *testing.T) {
 	}
 }
 
+func TestCustomTagPattern(t *testing.T) {
+	mentions := regexp.MustCompile(`[#@]([a-zA-Z0-9_]+)`)
+	got := parseTags("Sync with @Alice and @bob #planning, mail a@", false, mentions)
+	if strings.Join(got, ",") != "alice,bob,planning" {
+		t.Errorf("expected [alice bob planning], got %v", got)
+	}
+
+	svc := newTestService(t, WithTagPattern(mentions))
+	ctx := context.Background()
+	entry, err := svc.StartTimer(ctx, "Pairing with @carol #review", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	tags, _ := svc.db.ListTagsForTimeEntry(ctx, entry.ID)
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "carol,review" {
+		t.Errorf("expected tags [carol review], got %v", names)
+	}
+
+	// The default only knows #tags, and a pattern without a group is ignored
+	for _, svc := range []*Service{newTestService(t), newTestService(t, WithTagPattern(regexp.MustCompile(`@\w+`)))} {
+		entry, err := svc.StartTimer(ctx, "Pairing with @carol #review", nil)
+		if err != nil {
+			t.Fatalf("StartTimer failed: %v", err)
+		}
+		tags, _ := svc.db.ListTagsForTimeEntry(ctx, entry.ID)
+		if len(tags) != 1 || tags[0].Name != "review" {
+			t.Errorf("expected only #review, got %+v", tags)
+		}
+	}
+}
+
+func TestListEntriesByTag(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	older := seedEntry(t, svc, "Coding #GoLang", now.Add(-3*time.Hour), now.Add(-2*time.Hour), nil)
+	newer := seedEntry(t, svc, "Review #golang #review", now.Add(-2*time.Hour), now.Add(-time.Hour), nil)
+	seedEntry(t, svc, "Lunch", now.Add(-time.Hour), now, nil)
+
+	for _, name := range []string{"golang", "GoLang", "#GOLANG"} {
+		entries, err := svc.ListEntriesByTag(ctx, name)
+		if err != nil {
+			t.Fatalf("ListEntriesByTag(%q) failed: %v", name, err)
+		}
+		if len(entries) != 2 || entries[0].ID != newer.ID || entries[1].ID != older.ID {
+			t.Errorf("ListEntriesByTag(%q): expected entries %d and %d, got %+v", name, newer.ID, older.ID, entries)
+		}
+	}
+
+	entries, err := svc.ListEntriesByTag(ctx, "missing")
+	if err != nil {
+		t.Fatalf("ListEntriesByTag failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries for unknown tag, got %d", len(entries))
+	}
+}
+
+func TestResetData(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	work, err := svc.CreateCategory(ctx, "Work", "#ff0000")
+	if err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	if err := svc.SetGoal(ctx, work.ID, "week", 10*time.Hour); err != nil {
+		t.Fatalf("SetGoal failed: %v", err)
+	}
+	if err := svc.SetDefaultCategoryID(ctx, &work.ID); err != nil {
+		t.Fatalf("SetDefaultCategoryID failed: %v", err)
+	}
+	now := time.Now()
+	seedEntry(t, svc, "Coding #golang", now.Add(-2*time.Hour), now.Add(-time.Hour), &work.ID)
+	if _, err := svc.StartTimer(ctx, "Running #review", nil); err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	assertEmpty := func() {
+		t.Helper()
+		entries, _ := svc.db.ListAllTimeEntries(ctx)
+		tags, _ := svc.ListTags(ctx)
+		if len(entries) != 0 || len(tags) != 0 {
+			t.Errorf("expected no entries or tags, got %d entries and %d tags", len(entries), len(tags))
+		}
+	}
+
+	if err := svc.ResetData(ctx, false); err != nil {
+		t.Fatalf("ResetData failed: %v", err)
+	}
+	assertEmpty()
+	cats, _ := svc.ListCategories(ctx)
+	goals, _ := svc.ListGoals(ctx, "week")
+	if len(cats) != 1 || len(goals) != 1 {
+		t.Errorf("expected categories and goals to be kept, got %d and %d", len(cats), len(goals))
+	}
+
+	if err := svc.ResetData(ctx, true); err != nil {
+		t.Fatalf("ResetData failed: %v", err)
+	}
+	assertEmpty()
+	cats, _ = svc.ListCategories(ctx)
+	goals, _ = svc.ListGoals(ctx, "week")
+	if len(cats) != 0 || len(goals) != 0 {
+		t.Errorf("expected categories and goals to be deleted, got %d and %d", len(cats), len(goals))
+	}
+	if id, err := svc.DefaultCategoryID(ctx); err != nil || id != nil {
+		t.Errorf("expected the default category to be cleared, got %v (%v)", id, err)
+	}
+}
+
+func TestMultiLineDescription(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	desc := "Planning #roadmap\n\n- review #Budget\r\n- hiring #team"
+	entry, err := svc.StartTimer(ctx, desc, nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	got, err := svc.GetTimeEntry(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("GetTimeEntry failed: %v", err)
+	}
+	if got.Description != desc {
+		t.Errorf("expected the description to be stored as-is, got %q", got.Description)
+	}
+
+	tags, _ := svc.db.ListTagsForTimeEntry(ctx, entry.ID)
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "budget,roadmap,team" {
+		t.Errorf("expected tags from every line, got %v", names)
+	}
+}
+
+func TestTagCooccurrence(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	seedEntry(t, svc, "First #a #b", now.Add(-3*time.Hour), now.Add(-2*time.Hour), nil)
+	seedEntry(t, svc, "Second #a #c", now.Add(-2*time.Hour), now.Add(-time.Hour), nil)
+	seedEntry(t, svc, "Unrelated #b #d", now.Add(-time.Hour), now, nil)
+
+	a, err := svc.GetTagByName(ctx, "#A")
+	if err != nil {
+		t.Fatalf("GetTagByName failed: %v", err)
+	}
+	related, err := svc.TagCooccurrence(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("TagCooccurrence failed: %v", err)
+	}
+	if len(related) != 2 {
+		t.Fatalf("expected b and c, got %+v", related)
+	}
+	for i, name := range []string{"b", "c"} {
+		if related[i].Name != name || related[i].EntryCount != 1 {
+			t.Errorf("expected %s to co-occur once with a, got %+v", name, related[i])
+		}
+	}
+
+	if _, err := svc.GetTagByName(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCleanupOrphanedTags(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	seedEntry(t, svc, "Coding #golang", now.Add(-time.Hour), now, nil)
+	if _, err := svc.db.CreateTag(ctx, "orphan"); err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+
+	removed, err := svc.CleanupOrphanedTags(ctx)
+	if err != nil {
+		t.Fatalf("CleanupOrphanedTags failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 tag removed, got %d", removed)
+	}
+	tags, _ := svc.ListTags(ctx)
+	if len(tags) != 1 || tags[0].Name != "golang" {
+		t.Errorf("expected only golang to remain, got %+v", tags)
+	}
+
+	if removed, _ := svc.CleanupOrphanedTags(ctx); removed != 0 {
+		t.Errorf("expected nothing left to remove, got %d", removed)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	// Legacy data with mixed-case tags, which parseTags no longer creates
+	now := time.Now()
+	lower := seedEntry(t, svc, "Lowercase #work", now.Add(-4*time.Hour), now.Add(-3*time.Hour), nil)
+	upper := seedEntry(t, svc, "Legacy", now.Add(-3*time.Hour), now.Add(-2*time.Hour), nil)
+	both := seedEntry(t, svc, "Both #work", now.Add(-2*time.Hour), now.Add(-time.Hour), nil)
+	onlyMixed := seedEntry(t, svc, "Mixed", now.Add(-time.Hour), now, nil)
+	link := func(entryID int64, name string) {
+		t.Helper()
+		tag, err := svc.db.CreateTag(ctx, name)
+		if err != nil {
+			t.Fatalf("CreateTag failed: %v", err)
+		}
+		if err := svc.db.CreateTimeEntryTag(ctx, database.CreateTimeEntryTagParams{TimeEntryID: entryID, TagID: tag.ID}); err != nil {
+			t.Fatalf("CreateTimeEntryTag failed: %v", err)
+		}
+	}
+	link(upper.ID, "Work")
+	link(both.ID, "Work")
+	link(both.ID, "WORK")
+	link(onlyMixed.ID, "Api")
+	link(onlyMixed.ID, "API")
+
+	merged, err := svc.NormalizeTags(ctx)
+	if err != nil {
+		t.Fatalf("NormalizeTags failed: %v", err)
+	}
+	if merged != 3 {
+		t.Errorf("expected 3 tags merged, got %d", merged)
+	}
+
+	tags, _ := svc.ListTags(ctx)
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	if got := strings.Join(names, ","); got != "api,work" {
+		t.Errorf("expected only api and work to remain, got %s", got)
+	}
+	for _, e := range []struct {
+		id   int64
+		want string
+	}{
+		{lower.ID, "work"},
+		{upper.ID, "work"},
+		{both.ID, "work"},
+		{onlyMixed.ID, "api"},
+	} {
+		if got := entryTagNames(t, svc, e.id); got != e.want {
+			t.Errorf("entry %d: expected tags %q, got %q", e.id, e.want, got)
+		}
+	}
+
+	if merged, _ := svc.NormalizeTags(ctx); merged != 0 {
+		t.Errorf("expected nothing left to merge, got %d", merged)
+	}
+}
+
 func TestGetReport(t *testing.T) {
 	svc := newTestService(t)
 	ctx := context.Background()
@@ -397,3 +959,312 @@ func TestGetReport(t *testing.T) {
 		t.Errorf("No Category not found in breakdown")
 	}
 }
+
+func TestFindOverlappingEntries(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	// Forgotten timer 09:00-12:00 and a manual entry 10:00-11:00 overlap.
+	e1, _ := svc.StartTimer(ctx, "Forgotten timer", nil)
+	if _, err := svc.UpdateTimeEntry(ctx, e1.ID, e1.Description, base, sql.NullTime{Time: base.Add(3 * time.Hour), Valid: true}, nil); err != nil {
+		t.Fatalf("failed to update e1: %v", err)
+	}
+	e2, _ := svc.StartTimer(ctx, "Manual entry", nil)
+	if _, err := svc.UpdateTimeEntry(ctx, e2.ID, e2.Description, base.Add(time.Hour), sql.NullTime{Time: base.Add(2 * time.Hour), Valid: true}, nil); err != nil {
+		t.Fatalf("failed to update e2: %v", err)
+	}
+	// Touching but not overlapping: 12:00-13:00.
+	e3, _ := svc.StartTimer(ctx, "Afterwards", nil)
+	if _, err := svc.UpdateTimeEntry(ctx, e3.ID, e3.Description, base.Add(3*time.Hour), sql.NullTime{Time: base.Add(4 * time.Hour), Valid: true}, nil); err != nil {
+		t.Fatalf("failed to update e3: %v", err)
+	}
+
+	pairs, err := svc.FindOverlappingEntries(ctx)
+	if err != nil {
+		t.Fatalf("FindOverlappingEntries failed: %v", err)
+	}
+	if len(pairs) != 1 {
+		t.Fatalf("expected 1 overlapping pair, got %d", len(pairs))
+	}
+	if pairs[0].First.ID != e1.ID || pairs[0].Second.ID != e2.ID {
+		t.Errorf("expected pair (%d, %d), got (%d, %d)", e1.ID, e2.ID, pairs[0].First.ID, pairs[0].Second.ID)
+	}
+}
+
+func TestStartTimerDefaultCategory(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	work, _ := svc.CreateCategory(ctx, "Work", "#ff0000")
+	personal, _ := svc.CreateCategory(ctx, "Personal", "#00ff00")
+
+	if err := svc.SetDefaultCategoryID(ctx, &work.ID); err != nil {
+		t.Fatalf("SetDefaultCategoryID failed: %v", err)
+	}
+
+	// No category given: default applies
+	entry, err := svc.StartTimer(ctx, "Defaulted", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	if !entry.CategoryID.Valid || entry.CategoryID.Int64 != work.ID {
+		t.Errorf("expected default category %d, got %v", work.ID, entry.CategoryID)
+	}
+
+	// Explicit category wins over the default
+	entry, err = svc.StartTimer(ctx, "Explicit", &personal.ID)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	if !entry.CategoryID.Valid || entry.CategoryID.Int64 != personal.ID {
+		t.Errorf("expected explicit category %d, got %v", personal.ID, entry.CategoryID)
+	}
+
+	// Clearing the default leaves new timers uncategorized
+	if err := svc.SetDefaultCategoryID(ctx, nil); err != nil {
+		t.Fatalf("SetDefaultCategoryID(nil) failed: %v", err)
+	}
+	entry, _ = svc.StartTimer(ctx, "Cleared", nil)
+	if entry.CategoryID.Valid {
+		t.Errorf("expected no category after clearing default, got %v", entry.CategoryID)
+	}
+}
+
+// seedEntry creates a completed entry with the given range.
+func seedEntry(t *testing.T, svc *Service, desc string, start, end time.Time, catID *int64) *database.GetTimeEntryRow {
+	t.Helper()
+	ctx := context.Background()
+	entry, err := svc.StartTimer(ctx, desc, catID)
+	if err != nil {
+		t.Fatalf("failed to start %q: %v", desc, err)
+	}
+	updated, err := svc.UpdateTimeEntry(ctx, entry.ID, desc, start, sql.NullTime{Time: end, Valid: true}, catID)
+	if err != nil {
+		t.Fatalf("failed to update %q: %v", desc, err)
+	}
+	return updated
+}
+
+func TestGetReportGroupedEntries(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	work, _ := svc.CreateCategory(ctx, "Work", "#ff0000")
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	seedEntry(t, svc, "Work A", base, base.Add(time.Hour), &work.ID)
+	seedEntry(t, svc, "Work B", base.Add(2*time.Hour), base.Add(3*time.Hour), &work.ID)
+	seedEntry(t, svc, "Loose", base.Add(4*time.Hour), base.Add(4*time.Hour+30*time.Minute), nil)
+
+	report, err := svc.GetReport(ctx, ReportFilter{
+		StartDate: base.Add(-time.Hour),
+		EndDate:   base.Add(24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+
+	if len(report.GroupedEntries) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(report.GroupedEntries))
+	}
+	first := report.GroupedEntries[0]
+	if first.CategoryID != work.ID || len(first.Entries) != 2 || first.TotalSeconds != 7200 {
+		t.Errorf("unexpected Work group: id=%d entries=%d total=%d", first.CategoryID, len(first.Entries), first.TotalSeconds)
+	}
+	last := report.GroupedEntries[1]
+	if last.CategoryID != -1 || last.CategoryName != "No Category" || last.TotalSeconds != 1800 {
+		t.Errorf("unexpected No Category group: %+v", last)
+	}
+}
+
+func TestStartTimerWithTagIDs(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	// Create tags by using them on an existing entry
+	seedEntry(t, svc, "Seed #alpha #beta", time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour), nil)
+	tags, _ := svc.ListTags(ctx)
+	var alphaID, betaID int64
+	for _, tg := range tags {
+		switch tg.Name {
+		case "alpha":
+			alphaID = tg.ID
+		case "beta":
+			betaID = tg.ID
+		}
+	}
+
+	entry, err := svc.StartTimer(ctx, "Picked #gamma #alpha", nil, alphaID, betaID)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	entryTags, err := svc.db.ListTagsForTimeEntry(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("ListTagsForTimeEntry failed: %v", err)
+	}
+	got := make(map[string]bool)
+	for _, tg := range entryTags {
+		got[tg.Name] = true
+	}
+	if len(entryTags) != 3 || !got["alpha"] || !got["beta"] || !got["gamma"] {
+		t.Errorf("expected tags alpha, beta, gamma, got %v", entryTags)
+	}
+
+	// Unknown tag IDs abort the whole start
+	if _, err := svc.StartTimer(ctx, "Bad tag", nil, 9999); err == nil {
+		t.Errorf("expected error for unknown tag id")
+	}
+	active, _ := svc.GetActiveTimeEntry(ctx)
+	if active.ID != entry.ID {
+		t.Errorf("expected failed start to leave entry %d running, got %d", entry.ID, active.ID)
+	}
+}
+
+func TestReconcileActiveOnStartup(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	// No running timer
+	stale, err := svc.ReconcileActiveOnStartup(ctx, time.Hour, StaleTimerStop)
+	if err != nil || len(stale) != 0 {
+		t.Fatalf("expected nothing to reconcile, got %v (err=%v)", stale, err)
+	}
+
+	entry, err := svc.StartTimer(ctx, "Forgotten", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	// A recent timer is left alone
+	stale, _ = svc.ReconcileActiveOnStartup(ctx, time.Hour, StaleTimerStop)
+	if len(stale) != 0 {
+		t.Errorf("expected fresh timer not to be stale")
+	}
+
+	start := svc.Now().Add(-20 * time.Hour)
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, entry.Description, start, sql.NullTime{}, nil); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+
+	// Warn reports the entry but keeps it running
+	stale, err = svc.ReconcileActiveOnStartup(ctx, 12*time.Hour, StaleTimerWarn)
+	if err != nil {
+		t.Fatalf("ReconcileActiveOnStartup failed: %v", err)
+	}
+	if len(stale) != 1 || stale[0].ID != entry.ID || stale[0].EndTime.Valid {
+		t.Fatalf("expected running stale entry %d, got %+v", entry.ID, stale)
+	}
+	if _, err := svc.GetActiveTimeEntry(ctx); err != nil {
+		t.Errorf("expected timer to still be running: %v", err)
+	}
+
+	// Stop ends it at start + threshold
+	stale, err = svc.ReconcileActiveOnStartup(ctx, 12*time.Hour, StaleTimerStop)
+	if err != nil {
+		t.Fatalf("ReconcileActiveOnStartup failed: %v", err)
+	}
+	if len(stale) != 1 {
+		t.Fatal("expected stale entry to be stopped")
+	}
+	got, _ := svc.GetTimeEntry(ctx, entry.ID)
+	if !got.EndTime.Valid || !got.EndTime.Time.Equal(start.Add(12*time.Hour)) {
+		t.Errorf("expected end at %v, got %v", start.Add(12*time.Hour), got.EndTime)
+	}
+	if _, err := svc.GetActiveTimeEntry(ctx); err == nil {
+		t.Errorf("expected no running timer after stop")
+	}
+}
+
+func TestReconcileActiveOnStartupMultipleTimers(t *testing.T) {
+	svc := newTestService(t, WithMultipleTimers(true))
+	ctx := context.Background()
+
+	old, err := svc.StartTimer(ctx, "Background", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	start := svc.Now().Add(-20 * time.Hour)
+	if _, err := svc.UpdateTimeEntry(ctx, old.ID, old.Description, start, sql.NullTime{}, nil); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+	fresh, err := svc.StartTimer(ctx, "Foreground", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	// The fresh primary timer does not hide the stale background one
+	stale, err := svc.ReconcileActiveOnStartup(ctx, 12*time.Hour, StaleTimerStop)
+	if err != nil {
+		t.Fatalf("ReconcileActiveOnStartup failed: %v", err)
+	}
+	if len(stale) != 1 || stale[0].ID != old.ID {
+		t.Fatalf("expected only entry %d to be stale, got %+v", old.ID, stale)
+	}
+	if got, _ := svc.GetTimeEntry(ctx, old.ID); !got.EndTime.Valid || !got.EndTime.Time.Equal(start.Add(12*time.Hour)) {
+		t.Errorf("expected the stale timer to end at %v, got %v", start.Add(12*time.Hour), got.EndTime)
+	}
+	if got, _ := svc.GetTimeEntry(ctx, fresh.ID); got.EndTime.Valid {
+		t.Errorf("expected the fresh timer to keep running")
+	}
+}
+
+func TestListTimeEntriesIncludesCategoryColor(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	cat, _ := svc.CreateCategory(ctx, "Work", "#123abc")
+	now := time.Now()
+	seedEntry(t, svc, "Categorized", now.Add(-time.Hour), now, &cat.ID)
+
+	entries, err := svc.ListTimeEntries(ctx)
+	if err != nil {
+		t.Fatalf("ListTimeEntries failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if !entries[0].CategoryColor.Valid || entries[0].CategoryColor.String != "#123abc" {
+		t.Errorf("expected category color #123abc, got %v", entries[0].CategoryColor)
+	}
+}
+
+func TestTotalTrackedSeconds(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	seedEntry(t, svc, "First", now.Add(-5*time.Hour), now.Add(-4*time.Hour), nil)
+	seedEntry(t, svc, "Second", now.Add(-3*time.Hour), now.Add(-150*time.Minute), nil)
+	if _, err := svc.StartTimer(ctx, "Running", nil); err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+
+	total, err := svc.TotalTrackedSeconds(ctx)
+	if err != nil {
+		t.Fatalf("TotalTrackedSeconds failed: %v", err)
+	}
+	if total != 90*60 {
+		t.Errorf("expected 5400 seconds, got %d", total)
+	}
+}
+
+func TestClose(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	if err := svc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := svc.Close(); err != nil {
+		t.Errorf("expected a second Close to do nothing, got %v", err)
+	}
+	if _, err := svc.ListTimeEntries(ctx); err == nil {
+		t.Error("expected queries to fail after Close")
+	}
+	if _, err := svc.StartTimer(ctx, "After close", nil); err == nil {
+		t.Error("expected StartTimer to fail after Close")
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
+)
+
+const (
+	settingDefaultCategoryID = "default_category_id"
+
+	// SettingNoDescriptionLabel overrides the description given to timers
+	// started without one.
+	SettingNoDescriptionLabel = "label_no_description"
+	// SettingNoCategoryLabel overrides the name shown for entries without a
+	// category in reports.
+	SettingNoCategoryLabel = "label_no_category"
+	// SettingTagsRequireLetter, when "true", ignores all-numeric #tags such
+	// as issue numbers.
+	SettingTagsRequireLetter = "tags_require_letter"
+	// SettingKeepIdenticalTimer, when "true", makes starting a timer with
+	// the same description and category as the running one keep that timer
+	// going instead of replacing it.
+	SettingKeepIdenticalTimer = "keep_identical_timer"
+	// SettingCurrency is the currency amounts are shown in: an ISO 4217
+	// code known to FormatCurrency, or any other symbol to put before them.
+	SettingCurrency = "currency"
+	// SettingDailyTargetPrefix, followed by a lowercase weekday name such
+	// as "monday", sets the hours aimed for on that day, e.g. "6" or "7.5".
+	SettingDailyTargetPrefix = "daily_target_"
+)
+
+// Labels used when the corresponding setting is not stored.
+const (
+	DefaultNoDescriptionLabel = "No description"
+	DefaultNoCategoryLabel    = "No Category"
+	DefaultCurrency           = "USD"
+)
+
+// GetSetting returns the stored value for key. ok is false when the key has
+// never been set.
+func (s *Service) GetSetting(ctx context.Context, key string) (value string, ok bool, err error) {
+	value, err = s.db.GetSetting(ctx, key)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// SetSetting stores value under key, overwriting any previous value.
+func (s *Service) SetSetting(ctx context.Context, key, value string) error {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return fmt.Errorf("setting key is required")
+	}
+	return s.db.SetSetting(ctx, database.SetSettingParams{
+		Key:   key,
+		Value: value,
+	})
+}
+
+// DeleteSetting removes key. Deleting a missing key is not an error.
+func (s *Service) DeleteSetting(ctx context.Context, key string) error {
+	return s.db.DeleteSetting(ctx, key)
+}
+
+// ListSettings returns all stored settings ordered by key.
+func (s *Service) ListSettings(ctx context.Context) ([]database.Setting, error) {
+	return s.db.ListSettings(ctx)
+}
+
+// DefaultCategoryID returns the category applied to timers started without
+// one, or nil when no default is configured.
+func (s *Service) DefaultCategoryID(ctx context.Context) (*int64, error) {
+	return defaultCategoryID(ctx, s.db)
+}
+
+// SetDefaultCategoryID stores the default category for new timers. A nil id
+// clears the default.
+func (s *Service) SetDefaultCategoryID(ctx context.Context, id *int64) error {
+	if id == nil {
+		return s.db.DeleteSetting(ctx, settingDefaultCategoryID)
+	}
+	if _, err := s.db.GetCategory(ctx, *id); err != nil {
+		return fmt.Errorf("category %d not found: %w", *id, err)
+	}
+	return s.db.SetSetting(ctx, database.SetSettingParams{
+		Key:   settingDefaultCategoryID,
+		Value: strconv.FormatInt(*id, 10),
+	})
+}
+
+func defaultCategoryID(ctx context.Context, q *database.Queries) (*int64, error) {
+	value, err := q.GetSetting(ctx, settingDefaultCategoryID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s setting %q: %w", settingDefaultCategoryID, value, err)
+	}
+	return &id, nil
+}
+
+// label returns the stored label for key, or fallback when none is set.
+func label(ctx context.Context, q *database.Queries, key, fallback string) (string, error) {
+	value, err := q.GetSetting(ctx, key)
+	if err == sql.ErrNoRows || (err == nil && strings.TrimSpace(value) == "") {
+		return fallback, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// Currency returns the configured currency, DefaultCurrency unless set.
+func (s *Service) Currency(ctx context.Context) (string, error) {
+	value, err := label(ctx, s.db, SettingCurrency, DefaultCurrency)
+	return strings.TrimSpace(value), err
+}
+
+// DailyTargets returns the time aimed for on each day of the week, indexed
+// by time.Weekday, zero for days without a target.
+func (s *Service) DailyTargets(ctx context.Context) ([7]time.Duration, error) {
+	return dailyTargets(ctx, s.db)
+}
+
+func dailyTargets(ctx context.Context, q *database.Queries) ([7]time.Duration, error) {
+	var targets [7]time.Duration
+	settings, err := q.ListSettings(ctx)
+	if err != nil {
+		return targets, err
+	}
+	for _, setting := range settings {
+		name, ok := strings.CutPrefix(setting.Key, SettingDailyTargetPrefix)
+		if !ok {
+			continue
+		}
+		day, ok := weekdayNames[name]
+		if !ok {
+			return targets, fmt.Errorf("invalid setting %s: unknown weekday %q", setting.Key, name)
+		}
+		hours, err := strconv.ParseFloat(strings.TrimSpace(setting.Value), 64)
+		if err != nil || hours < 0 || hours > 24 {
+			return targets, fmt.Errorf("invalid %s setting %q: expected hours between 0 and 24", setting.Key, setting.Value)
+		}
+		targets[day] = time.Duration(hours * float64(time.Hour))
+	}
+	return targets, nil
+}
+
+// weekdayNames maps lowercase weekday names to their time.Weekday.
+var weekdayNames = func() map[string]time.Weekday {
+	m := make(map[string]time.Weekday, 7)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		m[strings.ToLower(d.String())] = d
+	}
+	return m
+}()
+
+func tagsRequireLetter(ctx context.Context, q *database.Queries) (bool, error) {
+	return boolSetting(ctx, q, SettingTagsRequireLetter)
+}
+
+// boolSetting reads key as a boolean; a missing key is false.
+func boolSetting(ctx context.Context, q *database.Queries, key string) (bool, error) {
+	value, err := q.GetSetting(ctx, key)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s setting %q: %w", key, value, err)
+	}
+	return b, nil
+}
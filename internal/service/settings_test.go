@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSettingsGetSetOverwrite(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	// Missing key
+	_, ok, err := svc.GetSetting(ctx, "week_start")
+	if err != nil {
+		t.Fatalf("GetSetting failed: %v", err)
+	}
+	if ok {
+		t.Errorf("expected missing setting to report ok=false")
+	}
+
+	// Set
+	if err := svc.SetSetting(ctx, "week_start", "monday"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	value, ok, err := svc.GetSetting(ctx, "week_start")
+	if err != nil || !ok || value != "monday" {
+		t.Errorf("expected 'monday', got %q (ok=%v, err=%v)", value, ok, err)
+	}
+
+	// Overwrite
+	if err := svc.SetSetting(ctx, "week_start", "sunday"); err != nil {
+		t.Fatalf("SetSetting overwrite failed: %v", err)
+	}
+	value, _, _ = svc.GetSetting(ctx, "week_start")
+	if value != "sunday" {
+		t.Errorf("expected 'sunday' after overwrite, got %q", value)
+	}
+
+	settings, _ := svc.ListSettings(ctx)
+	if len(settings) != 1 {
+		t.Errorf("expected 1 stored setting, got %d", len(settings))
+	}
+
+	// Delete
+	if err := svc.DeleteSetting(ctx, "week_start"); err != nil {
+		t.Fatalf("DeleteSetting failed: %v", err)
+	}
+	if _, ok, _ := svc.GetSetting(ctx, "week_start"); ok {
+		t.Errorf("expected setting to be deleted")
+	}
+
+	// Empty key is rejected
+	if err := svc.SetSetting(ctx, "  ", "x"); err == nil {
+		t.Errorf("expected error for empty key")
+	}
+}
+
+func TestCustomNoCategoryLabel(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	if err := svc.SetSetting(ctx, SettingNoCategoryLabel, "Sin categoría"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	now := time.Now()
+	seedEntry(t, svc, "Uncategorized", now.Add(-time.Hour), now, nil)
+
+	report, err := svc.GetReport(ctx, ReportFilter{
+		StartDate: now.Add(-24 * time.Hour),
+		EndDate:   now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if len(report.CategoryBreakdown) != 1 {
+		t.Fatalf("expected 1 breakdown row, got %d", len(report.CategoryBreakdown))
+	}
+	b := report.CategoryBreakdown[0]
+	if b.CategoryID != -1 || b.CategoryName != "Sin categoría" {
+		t.Errorf("expected custom label on id -1, got id=%d name=%q", b.CategoryID, b.CategoryName)
+	}
+	if b.TotalSeconds != 3600 {
+		t.Errorf("expected 3600s, got %d", b.TotalSeconds)
+	}
+	if len(report.GroupedEntries) != 1 || report.GroupedEntries[0].CategoryName != "Sin categoría" {
+		t.Errorf("expected custom label on group, got %+v", report.GroupedEntries)
+	}
+}
+
+func TestCustomNoDescriptionLabel(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	entry, _ := svc.StartTimer(ctx, "", nil)
+	if entry.Description != DefaultNoDescriptionLabel {
+		t.Errorf("expected %q, got %q", DefaultNoDescriptionLabel, entry.Description)
+	}
+
+	if err := svc.SetSetting(ctx, SettingNoDescriptionLabel, "Senza descrizione"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	entry, err := svc.StartTimer(ctx, "", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	if entry.Description != "Senza descrizione" {
+		t.Errorf("expected custom label, got %q", entry.Description)
+	}
+}
+
+func TestTagsRequireLetterSetting(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	entry, _ := svc.StartTimer(ctx, "Fix #123 in #api", nil)
+	tags, _ := svc.db.ListTagsForTimeEntry(ctx, entry.ID)
+	if len(tags) != 2 {
+		t.Errorf("expected numeric tag to be kept by default, got %v", tags)
+	}
+
+	if err := svc.SetSetting(ctx, SettingTagsRequireLetter, "true"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	entry, err := svc.StartTimer(ctx, "Fix #456 in #api", nil)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	tags, _ = svc.db.ListTagsForTimeEntry(ctx, entry.ID)
+	if len(tags) != 1 || tags[0].Name != "api" {
+		t.Errorf("expected only 'api', got %v", tags)
+	}
+}
+
+func TestKeepIdenticalTimerSetting(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+	cat, _ := svc.CreateCategory(ctx, "Work", "#ff0000")
+
+	// Default: a new entry replaces the running one
+	first, _ := svc.StartTimer(ctx, "Deep work", &cat.ID)
+	second, _ := svc.StartTimer(ctx, "Deep work", &cat.ID)
+	if first.ID == second.ID {
+		t.Fatalf("expected a new entry without the setting")
+	}
+
+	if err := svc.SetSetting(ctx, SettingKeepIdenticalTimer, "true"); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	again, err := svc.StartTimer(ctx, "Deep work", &cat.ID)
+	if err != nil {
+		t.Fatalf("StartTimer failed: %v", err)
+	}
+	if again.ID != second.ID || again.EndTime.Valid {
+		t.Errorf("expected running entry %d to continue, got %d (ended=%v)", second.ID, again.ID, again.EndTime.Valid)
+	}
+	count, _ := svc.CountTimeEntries(ctx)
+	if count != 2 {
+		t.Errorf("expected no extra entry, got %d entries", count)
+	}
+
+	// A different category still starts a new entry
+	other, _ := svc.StartTimer(ctx, "Deep work", nil)
+	if other.ID == second.ID {
+		t.Errorf("expected a new entry when the category differs")
+	}
+}
+
+func TestCurrencySetting(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	if got, err := svc.Currency(ctx); err != nil || got != DefaultCurrency {
+		t.Errorf("expected %s by default, got %q (%v)", DefaultCurrency, got, err)
+	}
+	if err := svc.SetSetting(ctx, SettingCurrency, " EUR "); err != nil {
+		t.Fatalf("SetSetting failed: %v", err)
+	}
+	if got, err := svc.Currency(ctx); err != nil || got != "EUR" {
+		t.Errorf("expected EUR, got %q (%v)", got, err)
+	}
+}
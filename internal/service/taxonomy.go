@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alessandrocuzzocrea/precious-time-tracker/internal/database"
+)
+
+const defaultTaxonomyColor = "#cccccc"
+
+// Taxonomy is the portable form of all categories and tags, used to copy
+// them between installations.
+type Taxonomy struct {
+	Categories []TaxonomyCategory `json:"categories"`
+	Tags       []TaxonomyTag      `json:"tags"`
+}
+
+type TaxonomyCategory struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type TaxonomyTag struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	// Count is the number of entries using the tag. It is informational
+	// and ignored on import.
+	Count int64 `json:"count"`
+}
+
+// ExportTaxonomy returns every category, in display order, and every tag,
+// ordered by name.
+func (s *Service) ExportTaxonomy(ctx context.Context) (Taxonomy, error) {
+	cats, err := s.db.ListCategories(ctx)
+	if err != nil {
+		return Taxonomy{}, err
+	}
+	tags, err := s.db.ListTagsWithCounts(ctx)
+	if err != nil {
+		return Taxonomy{}, err
+	}
+
+	t := Taxonomy{
+		Categories: make([]TaxonomyCategory, 0, len(cats)),
+		Tags:       make([]TaxonomyTag, 0, len(tags)),
+	}
+	for _, c := range cats {
+		t.Categories = append(t.Categories, TaxonomyCategory{Name: c.Name, Color: c.Color})
+	}
+	for _, tag := range tags {
+		t.Tags = append(t.Tags, TaxonomyTag{Name: tag.Name, Color: tag.Color, Count: tag.EntryCount})
+	}
+	return t, nil
+}
+
+// WriteTaxonomyJSON writes the taxonomy as indented JSON.
+func (s *Service) WriteTaxonomyJSON(ctx context.Context, w io.Writer) error {
+	t, err := s.ExportTaxonomy(ctx)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// ImportTaxonomy upserts categories and tags by name, updating the color of
+// existing ones. Time entries are never touched. Imported tags are marked
+// explicit, so they are kept while no entry uses them until removed with
+// CleanupOrphanedTags. Malformed input yields ErrInvalidJSON, invalid names
+// and colors ErrValidation, and input over the import limits
+// ErrImportTooLarge.
+func (s *Service) ImportTaxonomy(ctx context.Context, r io.Reader) error {
+	lr := &io.LimitedReader{R: r, N: s.importMaxBytes + 1}
+	var t Taxonomy
+	err := json.NewDecoder(lr).Decode(&t)
+	if lr.N <= 0 {
+		return fmt.Errorf("%w: more than %d bytes", ErrImportTooLarge, s.importMaxBytes)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	if len(t.Categories)+len(t.Tags) > s.importMaxRows {
+		return fmt.Errorf("%w: more than %d categories and tags", ErrImportTooLarge, s.importMaxRows)
+	}
+
+	tx, err := s.rawDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	qtx := s.db.WithTx(tx)
+
+	for _, c := range t.Categories {
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			return fmt.Errorf("%w: category name is required", ErrValidation)
+		}
+		if err := checkCategoryName(ctx, qtx, name); err != nil {
+			return err
+		}
+		color, err := taxonomyColor(c.Color)
+		if err != nil {
+			return err
+		}
+		if _, err := qtx.UpsertCategoryByName(ctx, database.UpsertCategoryByNameParams{
+			Name:  name,
+			Color: color,
+		}); err != nil {
+			return fmt.Errorf("failed to save category '%s': %w", name, err)
+		}
+	}
+
+	for _, tag := range t.Tags {
+		// Tags are stored lowercase, as parsed from descriptions
+		name := normalizeTagName(tag.Name)
+		if name == "" {
+			return fmt.Errorf("%w: tag name is required", ErrValidation)
+		}
+		color, err := taxonomyColor(tag.Color)
+		if err != nil {
+			return err
+		}
+		if _, err := qtx.UpsertTagByName(ctx, database.UpsertTagByNameParams{
+			Name:  name,
+			Color: color,
+		}); err != nil {
+			return fmt.Errorf("failed to save tag '%s': %w", name, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func taxonomyColor(color string) (string, error) {
+	color = strings.TrimSpace(color)
+	if color == "" {
+		return defaultTaxonomyColor, nil
+	}
+	if !colorRegex.MatchString(color) {
+		return "", fmt.Errorf("%w: invalid color '%s': expected #RRGGBB", ErrValidation, color)
+	}
+	return color, nil
+}
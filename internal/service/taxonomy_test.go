@@ -0,0 +1,147 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaxonomyRoundTrip(t *testing.T) {
+	src := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := src.CreateCategory(ctx, "Work", "#ff0000"); err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	if _, err := src.CreateCategory(ctx, "Personal", "#00ff00"); err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	now := time.Now()
+	seedEntry(t, src, "Coding #golang", now.Add(-2*time.Hour), now.Add(-time.Hour), nil)
+	seedEntry(t, src, "More #golang #review", now.Add(-time.Hour), now, nil)
+
+	var buf bytes.Buffer
+	if err := src.WriteTaxonomyJSON(ctx, &buf); err != nil {
+		t.Fatalf("WriteTaxonomyJSON failed: %v", err)
+	}
+	exported, _ := src.ExportTaxonomy(ctx)
+	if len(exported.Tags) != 2 || exported.Tags[0].Name != "golang" || exported.Tags[0].Count != 2 {
+		t.Fatalf("unexpected exported tags: %+v", exported.Tags)
+	}
+
+	// Destination already has one of the categories with another color
+	dst := newTestService(t)
+	if _, err := dst.CreateCategory(ctx, "Work", "#123456"); err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	if err := dst.ImportTaxonomy(ctx, &buf); err != nil {
+		t.Fatalf("ImportTaxonomy failed: %v", err)
+	}
+
+	imported, err := dst.ExportTaxonomy(ctx)
+	if err != nil {
+		t.Fatalf("ExportTaxonomy failed: %v", err)
+	}
+	if len(imported.Categories) != len(exported.Categories) {
+		t.Fatalf("expected %d categories, got %d", len(exported.Categories), len(imported.Categories))
+	}
+	for i, c := range exported.Categories {
+		if imported.Categories[i] != c {
+			t.Errorf("category %d: expected %+v, got %+v", i, c, imported.Categories[i])
+		}
+	}
+	if len(imported.Tags) != len(exported.Tags) {
+		t.Fatalf("expected %d tags, got %d", len(exported.Tags), len(imported.Tags))
+	}
+	for i, tag := range exported.Tags {
+		got := imported.Tags[i]
+		if got.Name != tag.Name || got.Color != tag.Color {
+			t.Errorf("tag %d: expected %+v, got %+v", i, tag, got)
+		}
+		if got.Count != 0 {
+			t.Errorf("expected imported tag %q to have no entries, got %d", got.Name, got.Count)
+		}
+	}
+
+	entries, _ := dst.db.ListAllTimeEntries(ctx)
+	if len(entries) != 0 {
+		t.Errorf("expected import to leave entries untouched, got %d", len(entries))
+	}
+}
+
+func TestImportTaxonomyInvalidColor(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	err := svc.ImportTaxonomy(ctx, strings.NewReader(`{"categories":[{"name":"Work","color":"red"}]}`))
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation for invalid color, got %v", err)
+	}
+	cats, _ := svc.ListCategories(ctx)
+	if len(cats) != 0 {
+		t.Errorf("expected failed import to be rolled back, got %d categories", len(cats))
+	}
+}
+
+func TestImportTaxonomyErrors(t *testing.T) {
+	ctx := context.Background()
+	data := `{"categories":[{"name":"Work"}],"tags":[{"name":"design"}]}`
+
+	tests := []struct {
+		name string
+		svc  *Service
+		data string
+		want error
+	}{
+		{"malformed", newTestService(t), `{"categories":`, ErrInvalidJSON},
+		{"unnamed tag", newTestService(t), `{"tags":[{"name":" "}]}`, ErrValidation},
+		{"too many bytes", newTestService(t, WithImportLimits(int64(len(data)-1), 0)), data, ErrImportTooLarge},
+		{"too many records", newTestService(t, WithImportLimits(0, 1)), data, ErrImportTooLarge},
+	}
+	for _, tt := range tests {
+		if err := tt.svc.ImportTaxonomy(ctx, strings.NewReader(tt.data)); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestImportedTagsSurviveEntryChanges(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	if err := svc.ImportTaxonomy(ctx, strings.NewReader(`{"tags":[{"name":"design","color":"#336699"}]}`)); err != nil {
+		t.Fatalf("ImportTaxonomy failed: %v", err)
+	}
+	if _, err := svc.StartTimerAt(ctx, svc.Now().Add(-time.Minute), "plain work", nil); err != nil {
+		t.Fatalf("StartTimerAt failed: %v", err)
+	}
+	if err := svc.StopTimer(ctx, nil); err != nil {
+		t.Fatalf("StopTimer failed: %v", err)
+	}
+
+	exported, err := svc.ExportTaxonomy(ctx)
+	if err != nil {
+		t.Fatalf("ExportTaxonomy failed: %v", err)
+	}
+	if len(exported.Tags) != 1 || exported.Tags[0].Name != "design" || exported.Tags[0].Color != "#336699" {
+		t.Fatalf("expected the imported tag to be kept, got %+v", exported.Tags)
+	}
+
+	// Tags parsed from descriptions still go once unused
+	entry := seedEntry(t, svc, "Sketching #draft", svc.Now().Add(-2*time.Hour), svc.Now().Add(-time.Hour), nil)
+	if _, err := svc.UpdateTimeEntry(ctx, entry.ID, "Sketching", entry.StartTime, entry.EndTime, nil); err != nil {
+		t.Fatalf("UpdateTimeEntry failed: %v", err)
+	}
+	if _, err := svc.GetTagByName(ctx, "draft"); err == nil {
+		t.Error("expected the unused description tag to be removed")
+	}
+
+	// The explicit cleanup removes imported tags as well
+	removed, err := svc.CleanupOrphanedTags(ctx)
+	if err != nil || removed != 1 {
+		t.Errorf("expected the cleanup to remove the imported tag, got %d, %v", removed, err)
+	}
+}
@@ -30,6 +30,13 @@ WHERE te.end_time IS NULL
 ORDER BY te.start_time DESC
 LIMIT 1;
 
+-- name: ListActiveTimeEntries :many
+SELECT te.*, c.name as category_name, c.color as category_color 
+FROM time_entries te
+LEFT JOIN categories c ON te.category_id = c.id
+WHERE te.end_time IS NULL
+ORDER BY te.start_time DESC;
+
 -- name: UpdateTimeEntryFull :one
 UPDATE time_entries
 SET description = ?, start_time = ?, end_time = ?, category_id = ?
@@ -48,6 +55,10 @@ VALUES (?)
 ON CONFLICT(name) DO UPDATE SET name=name
 RETURNING *;
 
+-- name: GetTag :one
+SELECT * FROM tags
+WHERE id = ?;
+
 -- name: GetTagByName :one
 SELECT * FROM tags
 WHERE name = ?;
@@ -61,6 +72,22 @@ SELECT t.* FROM tags t
 JOIN time_entry_tags tet ON t.id = tet.tag_id
 WHERE tet.time_entry_id = ?;
 
+-- name: ListTimeEntriesByTag :many
+SELECT te.*, c.name as category_name, c.color as category_color 
+FROM time_entries te
+JOIN time_entry_tags tet ON te.id = tet.time_entry_id
+JOIN tags t ON t.id = tet.tag_id
+LEFT JOIN categories c ON te.category_id = c.id
+WHERE t.name = ?
+ORDER BY te.start_time DESC;
+
+-- name: ListTagsForTimeEntries :many
+SELECT tet.time_entry_id, t.id, t.name, t.color
+FROM time_entry_tags tet
+JOIN tags t ON t.id = tet.tag_id
+WHERE tet.time_entry_id IN (sqlc.slice('time_entry_ids'))
+ORDER BY t.name;
+
 -- name: DeleteTimeEntryTags :exec
 DELETE FROM time_entry_tags
 WHERE time_entry_id = ?;
@@ -70,7 +97,14 @@ WHERE time_entry_id = ?;
 DELETE FROM time_entries
 WHERE id = ?;
 
--- name: DeleteOrphanedTags :exec
+-- name: DeleteOrphanedTags :execrows
+DELETE FROM tags
+WHERE explicit = 0
+AND NOT EXISTS (
+    SELECT 1 FROM time_entry_tags WHERE tag_id = tags.id
+);
+
+-- name: DeleteAllOrphanedTags :execrows
 DELETE FROM tags
 WHERE NOT EXISTS (
     SELECT 1 FROM time_entry_tags WHERE tag_id = tags.id
@@ -80,25 +114,47 @@ WHERE NOT EXISTS (
 SELECT * FROM tags
 ORDER BY name;
 
+-- name: MoveTimeEntryTags :exec
+INSERT OR IGNORE INTO time_entry_tags (time_entry_id, tag_id)
+SELECT time_entry_id, CAST(sqlc.arg('to_tag_id') AS INTEGER)
+FROM time_entry_tags
+WHERE tag_id = sqlc.arg('from_tag_id');
+
+-- name: DeleteTag :exec
+DELETE FROM tags
+WHERE id = ?;
+
+-- name: DeleteTagLinks :exec
+DELETE FROM time_entry_tags
+WHERE tag_id = ?;
+
+-- name: UpdateTagName :exec
+UPDATE tags
+SET name = ?
+WHERE id = ?;
+
 -- name: ListCategories :many
 SELECT * FROM categories
-ORDER BY name;
+ORDER BY sort_order, name;
 
 -- name: CreateCategory :one
-INSERT INTO categories (name, color)
-VALUES (?, ?)
+INSERT INTO categories (name, color, sort_order)
+VALUES (?, ?, (SELECT COALESCE(MAX(sort_order), -1) + 1 FROM categories))
 RETURNING *;
 
 -- name: UpdateCategory :one
 UPDATE categories
-SET name = ?, color = ?
+SET name = ?, color = ?, parent_id = ?
 WHERE id = ?
 RETURNING *;
 
--- name: DeleteCategory :exec
+-- name: DeleteCategory :execrows
 DELETE FROM categories
 WHERE id = ?;
 
+-- name: DeleteAllCategories :exec
+DELETE FROM categories;
+
 -- name: GetCategory :one
 SELECT * FROM categories
 WHERE id = ?;
@@ -106,15 +162,26 @@ WHERE id = ?;
 SELECT te.*, c.name as category_name, c.color as category_color 
 FROM time_entries te
 LEFT JOIN categories c ON te.category_id = c.id
-WHERE te.end_time IS NOT NULL
-AND te.start_time >= ?
-AND te.start_time <= ?
+WHERE (
+    (
+        (sqlc.arg('has_start') = 0 OR te.start_time >= sqlc.arg('start_date'))
+        AND (sqlc.arg('has_end') = 0 OR te.start_time <= sqlc.arg('end_date'))
+    )
+    OR (
+        sqlc.arg('include_overlapping') = 1
+        AND te.start_time < sqlc.arg('start_date')
+        AND (te.end_time IS NULL OR te.end_time > sqlc.arg('start_date'))
+    )
+)
 AND (
     (sqlc.arg('category_filter') = 0)
     OR (te.category_id = sqlc.arg('category_filter'))
     OR (sqlc.arg('category_filter') = -1 AND te.category_id IS NULL)
 )
-ORDER BY te.start_time DESC;
+AND (te.end_time IS NOT NULL OR sqlc.arg('include_running') = 1)
+ORDER BY
+    CASE WHEN sqlc.arg('oldest_first') = 1 THEN te.start_time END ASC,
+    te.start_time DESC;
 
 -- name: ListAllTimeEntries :many
 SELECT te.*, c.name as category_name, c.color as category_color 
@@ -122,9 +189,23 @@ FROM time_entries te
 LEFT JOIN categories c ON te.category_id = c.id
 ORDER BY te.start_time DESC;
 
+-- name: ListTimeEntriesPage :many
+SELECT te.*, c.name as category_name, c.color as category_color 
+FROM time_entries te
+LEFT JOIN categories c ON te.category_id = c.id
+ORDER BY
+    CASE WHEN sqlc.arg('sort') = 'start_asc' THEN te.start_time END ASC,
+    te.start_time DESC,
+    te.id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListTimeEntryBounds :many
+SELECT start_time, end_time FROM time_entries
+ORDER BY start_time;
+
 -- name: GetCategoryByName :one
 SELECT * FROM categories
-WHERE name = ?;
+WHERE name = ? COLLATE NOCASE;
 
 -- name: UpsertTimeEntry :one
 INSERT INTO time_entries (
@@ -132,15 +213,17 @@ INSERT INTO time_entries (
     description,
     start_time,
     end_time,
-    category_id
+    category_id,
+    color
 ) VALUES (
-    ?, ?, ?, ?, ?
+    ?, ?, ?, ?, ?, ?
 )
 ON CONFLICT(id) DO UPDATE SET
     description = excluded.description,
     start_time = excluded.start_time,
     end_time = excluded.end_time,
-    category_id = excluded.category_id
+    category_id = excluded.category_id,
+    color = COALESCE(excluded.color, time_entries.color)
 RETURNING *;
 
 -- name: CreateTimeEntryFull :one
@@ -148,8 +231,164 @@ INSERT INTO time_entries (
     description,
     start_time,
     end_time,
-    category_id
+    category_id,
+    color
+) VALUES (
+    ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: UpdateTimeEntryColor :exec
+UPDATE time_entries
+SET color = ?
+WHERE id = ?;
+
+-- name: UpdateTimeEntryNotes :exec
+UPDATE time_entries
+SET notes = ?
+WHERE id = ?;
+
+-- name: UpdateTimeEntryBillable :exec
+UPDATE time_entries
+SET billable = ?
+WHERE id = ?;
+
+-- name: GetSetting :one
+SELECT value FROM settings
+WHERE key = ?;
+
+-- name: SetSetting :exec
+INSERT INTO settings (key, value)
+VALUES (?, ?)
+ON CONFLICT(key) DO UPDATE SET value = excluded.value;
+
+-- name: DeleteSetting :exec
+DELETE FROM settings
+WHERE key = ?;
+
+-- name: ListSettings :many
+SELECT * FROM settings
+ORDER BY key;
+
+-- name: GetTimeEntryIDByExternalID :one
+SELECT id FROM time_entries
+WHERE external_id = ?;
+
+-- name: UpsertTimeEntryByExternalID :one
+INSERT INTO time_entries (
+    external_id,
+    description,
+    start_time,
+    end_time,
+    category_id,
+    color
 ) VALUES (
-    ?, ?, ?, ?
+    ?, ?, ?, ?, ?, ?
 )
+ON CONFLICT(external_id) DO UPDATE SET
+    description = excluded.description,
+    start_time = excluded.start_time,
+    end_time = excluded.end_time,
+    category_id = excluded.category_id,
+    color = COALESCE(excluded.color, time_entries.color)
+RETURNING *;
+
+-- name: ListTagsWithCounts :many
+SELECT t.id, t.name, t.color, COUNT(tet.time_entry_id) AS entry_count
+FROM tags t
+LEFT JOIN time_entry_tags tet ON tet.tag_id = t.id
+GROUP BY t.id
+ORDER BY t.name;
+
+-- name: ListTagCooccurrences :many
+SELECT t.id, t.name, t.color, COUNT(*) AS entry_count
+FROM time_entry_tags a
+JOIN time_entry_tags b ON b.time_entry_id = a.time_entry_id AND b.tag_id != a.tag_id
+JOIN tags t ON t.id = b.tag_id
+WHERE a.tag_id = ?
+GROUP BY t.id
+ORDER BY entry_count DESC, t.name;
+
+-- name: UpsertCategoryByName :one
+INSERT INTO categories (name, color, sort_order)
+VALUES (?, ?, (SELECT COALESCE(MAX(sort_order), -1) + 1 FROM categories))
+ON CONFLICT(name) DO UPDATE SET color = excluded.color
 RETURNING *;
+
+-- name: UpsertTagByName :one
+INSERT INTO tags (name, color, explicit)
+VALUES (?, ?, 1)
+ON CONFLICT(name) DO UPDATE SET color = excluded.color, explicit = 1
+RETURNING *;
+
+-- name: CountTimeEntries :one
+SELECT COUNT(*) FROM time_entries;
+
+-- name: GetTimeEntriesVersion :one
+SELECT
+    (SELECT version FROM data_version WHERE id = 1) AS version,
+    CAST(COALESCE((SELECT MAX(updated_at) FROM (
+        SELECT updated_at FROM time_entries
+        UNION ALL SELECT updated_at FROM categories
+        UNION ALL SELECT updated_at FROM tags
+    )), '') AS TEXT) AS last_updated;
+
+-- name: CreateEntryAudit :exec
+INSERT INTO entry_audit (time_entry_id, action, old_value, new_value, changed_at)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: ListEntryAudit :many
+SELECT * FROM entry_audit
+WHERE time_entry_id = ?
+ORDER BY id;
+
+-- name: SetGoal :exec
+INSERT INTO goals (category_id, period, target_seconds)
+VALUES (?, ?, ?)
+ON CONFLICT(category_id, period) DO UPDATE SET target_seconds = excluded.target_seconds;
+
+-- name: DeleteGoal :exec
+DELETE FROM goals
+WHERE category_id = ? AND period = ?;
+
+-- name: ListGoalsForPeriod :many
+SELECT g.category_id, g.period, g.target_seconds, c.name AS category_name, c.color AS category_color
+FROM goals g
+JOIN categories c ON c.id = g.category_id
+WHERE g.period = ?
+ORDER BY c.name;
+
+-- name: UpdateCategorySortOrder :execrows
+UPDATE categories
+SET sort_order = ?
+WHERE id = ?;
+
+-- name: ListCompletedEntrySpans :many
+SELECT start_time, end_time FROM time_entries
+WHERE end_time IS NOT NULL;
+
+-- name: ListUncategorizedTimeEntries :many
+SELECT te.*, c.name as category_name, c.color as category_color 
+FROM time_entries te
+LEFT JOIN categories c ON te.category_id = c.id
+WHERE te.end_time IS NOT NULL
+AND te.category_id IS NULL
+ORDER BY te.start_time DESC;
+
+-- name: CountUncategorizedTimeEntries :one
+SELECT COUNT(*) FROM time_entries
+WHERE end_time IS NOT NULL
+AND category_id IS NULL;
+
+-- name: CreateFocusSession :one
+INSERT INTO focus_sessions (time_entry_id, start_time, planned_seconds)
+VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: GetActiveFocusSession :one
+SELECT fs.*, te.description
+FROM focus_sessions fs
+JOIN time_entries te ON te.id = fs.time_entry_id
+WHERE te.end_time IS NULL
+ORDER BY fs.start_time DESC, fs.id DESC
+LIMIT 1;
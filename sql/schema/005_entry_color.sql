@@ -0,0 +1,6 @@
+-- +goose Up
+-- NULL means the entry inherits its category's color.
+ALTER TABLE time_entries ADD COLUMN color TEXT;
+
+-- +goose Down
+ALTER TABLE time_entries DROP COLUMN color;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Stable identifier from an external source system, used to make repeated
+-- imports idempotent. SQLite allows multiple NULLs in a unique index.
+ALTER TABLE time_entries ADD COLUMN external_id TEXT;
+
+CREATE UNIQUE INDEX idx_time_entries_external_id ON time_entries(external_id);
+
+-- +goose Down
+DROP INDEX idx_time_entries_external_id;
+ALTER TABLE time_entries DROP COLUMN external_id;
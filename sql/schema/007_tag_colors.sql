@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE tags ADD COLUMN color TEXT NOT NULL DEFAULT '#cccccc';
+
+-- +goose Down
+ALTER TABLE tags DROP COLUMN color;
@@ -0,0 +1,32 @@
+-- +goose Up
+-- Append-only history of time entry changes. There is deliberately no
+-- foreign key so the history of deleted entries is kept.
+CREATE TABLE entry_audit (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    time_entry_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    changed_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_entry_audit_time_entry_id ON entry_audit(time_entry_id);
+
+-- +goose StatementBegin
+CREATE TRIGGER entry_audit_no_update BEFORE UPDATE ON entry_audit
+BEGIN
+    SELECT RAISE(ABORT, 'entry_audit is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER entry_audit_no_delete BEFORE DELETE ON entry_audit
+BEGIN
+    SELECT RAISE(ABORT, 'entry_audit is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER entry_audit_no_delete;
+DROP TRIGGER entry_audit_no_update;
+DROP TABLE entry_audit;
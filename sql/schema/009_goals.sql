@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE goals (
+    category_id INTEGER NOT NULL,
+    period TEXT NOT NULL,
+    target_seconds INTEGER NOT NULL,
+    PRIMARY KEY (category_id, period),
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE goals;
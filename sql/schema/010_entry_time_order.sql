@@ -0,0 +1,31 @@
+-- +goose Up
+-- SQLite cannot add a CHECK constraint to an existing table, so
+-- end_time >= start_time is enforced with triggers instead. The driver
+-- stores times as text like "2006-01-02 15:04:05.999999999 -0700 MST",
+-- which julianday() cannot read directly, so the offset is rewritten as
+-- "-07:00" first. Values julianday() cannot read at all are let through.
+-- The service checks the order itself before writing; these triggers only
+-- back it up for other writers.
+-- +goose StatementBegin
+CREATE TRIGGER time_entries_end_after_start_insert BEFORE INSERT ON time_entries
+WHEN NEW.end_time IS NOT NULL
+    AND COALESCE(julianday(NEW.end_time), julianday(substr(NEW.end_time, 1, 19) || substr(substr(NEW.end_time, 20), instr(substr(NEW.end_time, 20), ' ') + 1, 3) || ':' || substr(substr(NEW.end_time, 20), instr(substr(NEW.end_time, 20), ' ') + 4, 2)))
+        < COALESCE(julianday(NEW.start_time), julianday(substr(NEW.start_time, 1, 19) || substr(substr(NEW.start_time, 20), instr(substr(NEW.start_time, 20), ' ') + 1, 3) || ':' || substr(substr(NEW.start_time, 20), instr(substr(NEW.start_time, 20), ' ') + 4, 2)))
+BEGIN
+    SELECT RAISE(ABORT, 'end_time before start_time');
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER time_entries_end_after_start_update BEFORE UPDATE OF start_time, end_time ON time_entries
+WHEN NEW.end_time IS NOT NULL
+    AND COALESCE(julianday(NEW.end_time), julianday(substr(NEW.end_time, 1, 19) || substr(substr(NEW.end_time, 20), instr(substr(NEW.end_time, 20), ' ') + 1, 3) || ':' || substr(substr(NEW.end_time, 20), instr(substr(NEW.end_time, 20), ' ') + 4, 2)))
+        < COALESCE(julianday(NEW.start_time), julianday(substr(NEW.start_time, 1, 19) || substr(substr(NEW.start_time, 20), instr(substr(NEW.start_time, 20), ' ') + 1, 3) || ':' || substr(substr(NEW.start_time, 20), instr(substr(NEW.start_time, 20), ' ') + 4, 2)))
+BEGIN
+    SELECT RAISE(ABORT, 'end_time before start_time');
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER time_entries_end_after_start_update;
+DROP TRIGGER time_entries_end_after_start_insert;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE categories ADD COLUMN sort_order INTEGER NOT NULL DEFAULT 0;
+
+-- Keep the previous alphabetical order for existing categories
+UPDATE categories
+SET sort_order = (SELECT COUNT(*) FROM categories c2 WHERE c2.name < categories.name);
+
+-- +goose Down
+ALTER TABLE categories DROP COLUMN sort_order;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Free-form notes kept alongside the one-line description.
+ALTER TABLE time_entries ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE time_entries DROP COLUMN notes;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Deleting a category turns its subcategories into top-level ones
+ALTER TABLE categories ADD COLUMN parent_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE categories DROP COLUMN parent_id;
@@ -0,0 +1,38 @@
+-- +goose Up
+-- Categories whose names differ only by case are merged into the oldest
+-- one, so that names can be made unique regardless of case.
+CREATE TEMP TABLE category_merge AS
+SELECT c.id AS old_id,
+       (SELECT MIN(k.id) FROM categories k WHERE k.name = c.name COLLATE NOCASE) AS new_id
+FROM categories c;
+
+DELETE FROM category_merge WHERE old_id = new_id;
+
+UPDATE time_entries
+SET category_id = (SELECT new_id FROM category_merge WHERE old_id = time_entries.category_id)
+WHERE category_id IN (SELECT old_id FROM category_merge);
+
+-- A goal the kept category already has for the period wins
+UPDATE OR IGNORE goals
+SET category_id = (SELECT new_id FROM category_merge WHERE old_id = goals.category_id)
+WHERE category_id IN (SELECT old_id FROM category_merge);
+
+UPDATE categories
+SET parent_id = (SELECT new_id FROM category_merge WHERE old_id = categories.parent_id)
+WHERE parent_id IN (SELECT old_id FROM category_merge);
+
+UPDATE categories SET parent_id = NULL WHERE parent_id = id;
+
+UPDATE settings
+SET value = (SELECT CAST(new_id AS TEXT) FROM category_merge WHERE CAST(old_id AS TEXT) = settings.value)
+WHERE key = 'default_category_id'
+AND value IN (SELECT CAST(old_id AS TEXT) FROM category_merge);
+
+DELETE FROM categories WHERE id IN (SELECT old_id FROM category_merge);
+
+DROP TABLE category_merge;
+
+CREATE UNIQUE INDEX idx_categories_name_nocase ON categories(name COLLATE NOCASE);
+
+-- +goose Down
+DROP INDEX idx_categories_name_nocase;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Whether the time of an entry can be billed, independent of its category.
+ALTER TABLE time_entries ADD COLUMN billable BOOLEAN NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE time_entries DROP COLUMN billable;
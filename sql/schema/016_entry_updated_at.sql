@@ -0,0 +1,28 @@
+-- +goose Up
+-- When an entry last changed, with millisecond precision, so that clients
+-- can tell whether the entry list changed. SQLite cannot add a column with a
+-- non-constant default, so triggers keep it current.
+ALTER TABLE time_entries ADD COLUMN updated_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00.000';
+
+UPDATE time_entries SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now');
+
+-- +goose StatementBegin
+CREATE TRIGGER time_entries_touch_insert AFTER INSERT ON time_entries
+BEGIN
+    UPDATE time_entries SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+END;
+-- +goose StatementEnd
+
+-- Recursive triggers are off, so the update below does not fire this again
+-- +goose StatementBegin
+CREATE TRIGGER time_entries_touch_update AFTER UPDATE ON time_entries
+WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE time_entries SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER time_entries_touch_update;
+DROP TRIGGER time_entries_touch_insert;
+ALTER TABLE time_entries DROP COLUMN updated_at;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- A focus session is a timer started with the intent of not switching, with
+-- an optional planned length. It lasts as long as its entry runs.
+CREATE TABLE focus_sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    time_entry_id INTEGER NOT NULL,
+    start_time DATETIME NOT NULL,
+    planned_seconds INTEGER,
+    FOREIGN KEY (time_entry_id) REFERENCES time_entries(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_focus_sessions_entry ON focus_sessions(time_entry_id);
+
+-- +goose Down
+DROP TABLE focus_sessions;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Tags created on their own, such as by a taxonomy import, rather than
+-- parsed from a description. They survive the cleanup that follows entry
+-- changes even while no entry uses them.
+ALTER TABLE tags ADD COLUMN explicit BOOLEAN NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE tags DROP COLUMN explicit;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- Entries are listed with their category and tags, so changes to those count
+-- towards whether the entry list changed, like time_entries.updated_at.
+ALTER TABLE categories ADD COLUMN updated_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00.000';
+ALTER TABLE tags ADD COLUMN updated_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00.000';
+
+UPDATE categories SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now');
+UPDATE tags SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now');
+
+-- +goose StatementBegin
+CREATE TRIGGER categories_touch_insert AFTER INSERT ON categories
+BEGIN
+    UPDATE categories SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER categories_touch_update AFTER UPDATE ON categories
+WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE categories SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tags_touch_insert AFTER INSERT ON tags
+BEGIN
+    UPDATE tags SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tags_touch_update AFTER UPDATE ON tags
+WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE tags SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = NEW.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER tags_touch_update;
+DROP TRIGGER tags_touch_insert;
+DROP TRIGGER categories_touch_update;
+DROP TRIGGER categories_touch_insert;
+ALTER TABLE tags DROP COLUMN updated_at;
+ALTER TABLE categories DROP COLUMN updated_at;
@@ -0,0 +1,109 @@
+-- +goose Up
+-- updated_at only has millisecond precision, so two changes within the same
+-- millisecond leave it as it was. data_version is a single counter that every
+-- change to the listed entries bumps, deletions included.
+CREATE TABLE data_version (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    version INTEGER NOT NULL
+);
+
+INSERT INTO data_version (id, version) VALUES (1, 0);
+
+-- +goose StatementBegin
+CREATE TRIGGER time_entries_version_insert AFTER INSERT ON time_entries
+BEGIN
+    UPDATE data_version SET version = version + 1;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER time_entries_version_update AFTER UPDATE ON time_entries
+BEGIN
+    UPDATE data_version SET version = version + 1;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER time_entries_version_delete AFTER DELETE ON time_entries
+BEGIN
+    UPDATE data_version SET version = version + 1;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER categories_version_insert AFTER INSERT ON categories
+BEGIN
+    UPDATE data_version SET version = version + 1;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER categories_version_update AFTER UPDATE ON categories
+BEGIN
+    UPDATE data_version SET version = version + 1;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER categories_version_delete AFTER DELETE ON categories
+BEGIN
+    UPDATE data_version SET version = version + 1;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tags_version_insert AFTER INSERT ON tags
+BEGIN
+    UPDATE data_version SET version = version + 1;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tags_version_update AFTER UPDATE ON tags
+BEGIN
+    UPDATE data_version SET version = version + 1;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER tags_version_delete AFTER DELETE ON tags
+BEGIN
+    UPDATE data_version SET version = version + 1;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER time_entry_tags_version_insert AFTER INSERT ON time_entry_tags
+BEGIN
+    UPDATE data_version SET version = version + 1;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER time_entry_tags_version_update AFTER UPDATE ON time_entry_tags
+BEGIN
+    UPDATE data_version SET version = version + 1;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER time_entry_tags_version_delete AFTER DELETE ON time_entry_tags
+BEGIN
+    UPDATE data_version SET version = version + 1;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER time_entry_tags_version_delete;
+DROP TRIGGER time_entry_tags_version_update;
+DROP TRIGGER time_entry_tags_version_insert;
+DROP TRIGGER tags_version_delete;
+DROP TRIGGER tags_version_update;
+DROP TRIGGER tags_version_insert;
+DROP TRIGGER categories_version_delete;
+DROP TRIGGER categories_version_update;
+DROP TRIGGER categories_version_insert;
+DROP TRIGGER time_entries_version_delete;
+DROP TRIGGER time_entries_version_update;
+DROP TRIGGER time_entries_version_insert;
+DROP TABLE data_version;
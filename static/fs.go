@@ -0,0 +1,8 @@
+// Package static holds the stylesheets and icons the server sends to
+// browsers, embedded so they ship inside the binary.
+package static
+
+import "embed"
+
+//go:embed css favicon.ico
+var FS embed.FS